@@ -0,0 +1,20 @@
+// Package ast defines the syntax tree produced by the parser.
+//
+// The node types in expr.go and stmt.go are generated from nodes.spec by
+// cmd/generate_ast; edit the spec and run `go generate ./ast` rather than
+// editing them by hand.
+//
+// Tools walk the tree with visitors. A visitor implements ExprVisitor[R] or
+// StmtVisitor[R] for the result type R it computes, and AcceptExpr and
+// AcceptStmt dispatch a node to the matching Visit method:
+//
+//	s := ast.AcceptExpr[string](expr, printer)
+//
+// Go methods cannot have type parameters, so dispatch is a function rather
+// than an Accept method on each node.
+//
+// Passes that only care about a few kinds of node can use Inspect to walk a
+// tree and Rewrite to build a modified copy of one, in the style of go/ast.
+package ast
+
+//go:generate go run ../cmd/generate_ast -spec nodes.spec -out .
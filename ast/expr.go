@@ -1,53 +1,542 @@
+// Code generated by generate_ast from nodes.spec. DO NOT EDIT.
+
 package ast
 
-import "github.com/nicholasq/glox/token"
+import (
+	"fmt"
+
+	"github.com/nicholasq/glox/token"
+)
 
 // Expr is an interface representing an expression in the Lox language.
-// It defines a single method Accept that implements the Visitor pattern.
+// Expressions are visited with AcceptExpr and an ExprVisitor.
+// Every expression records the source line it starts on in its Line field.
 type Expr interface {
-	Accept(v ExpressionVisitor) interface{}
+	Node
+	exprNode()
 }
 
-// ExpressionVisitor is an interface that defines methods for visiting different types of expressions.
-// It is used to implement the Visitor pattern for traversing and operating on the abstract syntax tree.
-type ExpressionVisitor interface {
-	VisitBinaryExpr(expr *Binary) interface{}
-	VisitGroupingExpr(expr *Grouping) interface{}
-	VisitLiteralExpr(expr *Literal) interface{}
-	VisitUnaryExpr(expr *Unary) interface{}
-	VisitVariableExpr(expr *Variable) interface{}
+// ExprVisitor has a method for each kind of Expr, returning a result of type R.
+type ExprVisitor[R any] interface {
+	VisitAssignExpr(expr *Assign) R
+	VisitBinaryExpr(expr *Binary) R
+	VisitCallExpr(expr *Call) R
+	VisitConditionalExpr(expr *Conditional) R
+	VisitGetExpr(expr *Get) R
+	VisitGroupingExpr(expr *Grouping) R
+	VisitIndexExpr(expr *Index) R
+	VisitIndexSetExpr(expr *IndexSet) R
+	VisitLambdaExpr(expr *Lambda) R
+	VisitListLiteralExpr(expr *ListLiteral) R
+	VisitLiteralExpr(expr *Literal) R
+	VisitMapLiteralExpr(expr *MapLiteral) R
+	VisitSliceExpr(expr *Slice) R
+	VisitUnaryExpr(expr *Unary) R
+	VisitVariableExpr(expr *Variable) R
+}
+
+// AcceptExpr calls the method of v that matches the type of expr and returns its result.
+func AcceptExpr[R any](expr Expr, v ExprVisitor[R]) R {
+	switch n := expr.(type) {
+	case *Assign:
+		return v.VisitAssignExpr(n)
+	case *Binary:
+		return v.VisitBinaryExpr(n)
+	case *Call:
+		return v.VisitCallExpr(n)
+	case *Conditional:
+		return v.VisitConditionalExpr(n)
+	case *Get:
+		return v.VisitGetExpr(n)
+	case *Grouping:
+		return v.VisitGroupingExpr(n)
+	case *Index:
+		return v.VisitIndexExpr(n)
+	case *IndexSet:
+		return v.VisitIndexSetExpr(n)
+	case *Lambda:
+		return v.VisitLambdaExpr(n)
+	case *ListLiteral:
+		return v.VisitListLiteralExpr(n)
+	case *Literal:
+		return v.VisitLiteralExpr(n)
+	case *MapLiteral:
+		return v.VisitMapLiteralExpr(n)
+	case *Slice:
+		return v.VisitSliceExpr(n)
+	case *Unary:
+		return v.VisitUnaryExpr(n)
+	case *Variable:
+		return v.VisitVariableExpr(n)
+	}
+	panic(fmt.Sprintf("ast: unexpected Expr type %T", expr))
+}
+
+// Assign represents assigning a new value to the variable Name.
+type Assign struct {
+	Name  token.Token
+	Value Expr
+	Line  uint
+}
+
+func (*Assign) exprNode() {}
+
+func (n *Assign) Pos() uint { return n.Line }
+
+func (n *Assign) End() uint {
+	end := n.Line
+	end = max(end, n.Name.Line)
+	end = max(end, endOf(n.Value))
+	return end
+}
+
+func (n *Assign) eachChild(f func(Node)) {
+	if n.Value != nil {
+		f(n.Value)
+	}
+}
+
+func (n *Assign) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Value = rewriteExpr(n.Value, f)
+	return &rewritten
 }
 
 // Binary represents a binary expression in the Lox language.
 // It consists of a left operand, an operator, and a right operand.
+// The comma operator is a Binary whose value is its right operand.
 type Binary struct {
 	Left     Expr
 	Operator token.Token
 	Right    Expr
+	Line     uint
+}
+
+func (*Binary) exprNode() {}
+
+func (n *Binary) Pos() uint { return n.Line }
+
+func (n *Binary) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Left))
+	end = max(end, n.Operator.Line)
+	end = max(end, endOf(n.Right))
+	return end
+}
+
+func (n *Binary) eachChild(f func(Node)) {
+	if n.Left != nil {
+		f(n.Left)
+	}
+	if n.Right != nil {
+		f(n.Right)
+	}
+}
+
+func (n *Binary) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Left = rewriteExpr(n.Left, f)
+	rewritten.Right = rewriteExpr(n.Right, f)
+	return &rewritten
+}
+
+// Call represents a call of a function with a list of arguments.
+// Paren is the closing parenthesis, whose line is used to report errors.
+type Call struct {
+	Callee    Expr
+	Paren     token.Token
+	Arguments []Expr
+	Line      uint
+}
+
+func (*Call) exprNode() {}
+
+func (n *Call) Pos() uint { return n.Line }
+
+func (n *Call) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Callee))
+	end = max(end, n.Paren.Line)
+	for _, child := range n.Arguments {
+		end = max(end, endOf(child))
+	}
+	return end
+}
+
+func (n *Call) eachChild(f func(Node)) {
+	if n.Callee != nil {
+		f(n.Callee)
+	}
+	for _, child := range n.Arguments {
+		f(child)
+	}
+}
+
+func (n *Call) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Callee = rewriteExpr(n.Callee, f)
+	rewritten.Arguments = rewriteExprs(n.Arguments, f)
+	return &rewritten
+}
+
+// Conditional represents the ternary operator, as in cond ? a : b,
+// which evaluates only the branch the condition selects.
+type Conditional struct {
+	Condition  Expr
+	ThenBranch Expr
+	ElseBranch Expr
+	Line       uint
 }
 
-func (expr *Binary) Accept(v ExpressionVisitor) interface{} {
-	return v.VisitBinaryExpr(expr)
+func (*Conditional) exprNode() {}
+
+func (n *Conditional) Pos() uint { return n.Line }
+
+func (n *Conditional) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Condition))
+	end = max(end, endOf(n.ThenBranch))
+	end = max(end, endOf(n.ElseBranch))
+	return end
+}
+
+func (n *Conditional) eachChild(f func(Node)) {
+	if n.Condition != nil {
+		f(n.Condition)
+	}
+	if n.ThenBranch != nil {
+		f(n.ThenBranch)
+	}
+	if n.ElseBranch != nil {
+		f(n.ElseBranch)
+	}
+}
+
+func (n *Conditional) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Condition = rewriteExpr(n.Condition, f)
+	rewritten.ThenBranch = rewriteExpr(n.ThenBranch, f)
+	rewritten.ElseBranch = rewriteExpr(n.ElseBranch, f)
+	return &rewritten
+}
+
+// Get represents reading the property Name of an object, such as a
+// member of the math namespace.
+type Get struct {
+	Object Expr
+	Name   token.Token
+	Line   uint
+}
+
+func (*Get) exprNode() {}
+
+func (n *Get) Pos() uint { return n.Line }
+
+func (n *Get) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Object))
+	end = max(end, n.Name.Line)
+	return end
+}
+
+func (n *Get) eachChild(f func(Node)) {
+	if n.Object != nil {
+		f(n.Object)
+	}
+}
+
+func (n *Get) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Object = rewriteExpr(n.Object, f)
+	return &rewritten
 }
 
 // Grouping represents a grouping expression in the Lox language.
 // It contains a single expression that is enclosed in parentheses.
 type Grouping struct {
 	Expression Expr
+	Line       uint
+}
+
+func (*Grouping) exprNode() {}
+
+func (n *Grouping) Pos() uint { return n.Line }
+
+func (n *Grouping) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Expression))
+	return end
+}
+
+func (n *Grouping) eachChild(f func(Node)) {
+	if n.Expression != nil {
+		f(n.Expression)
+	}
+}
+
+func (n *Grouping) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Expression = rewriteExpr(n.Expression, f)
+	return &rewritten
+}
+
+// Index represents reading one element of a string or list by its position.
+// Bracket is the closing bracket, whose line is used to report errors.
+type Index struct {
+	Object  Expr
+	Bracket token.Token
+	Index   Expr
+	Line    uint
+}
+
+func (*Index) exprNode() {}
+
+func (n *Index) Pos() uint { return n.Line }
+
+func (n *Index) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Object))
+	end = max(end, n.Bracket.Line)
+	end = max(end, endOf(n.Index))
+	return end
+}
+
+func (n *Index) eachChild(f func(Node)) {
+	if n.Object != nil {
+		f(n.Object)
+	}
+	if n.Index != nil {
+		f(n.Index)
+	}
+}
+
+func (n *Index) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Object = rewriteExpr(n.Object, f)
+	rewritten.Index = rewriteExpr(n.Index, f)
+	return &rewritten
 }
 
-func (expr *Grouping) Accept(v ExpressionVisitor) interface{} {
-	return v.VisitGroupingExpr(expr)
+// IndexSet represents assigning to one element of a list, as in xs[i] = v.
+// Bracket is the closing bracket, whose line is used to report errors.
+type IndexSet struct {
+	Object  Expr
+	Bracket token.Token
+	Index   Expr
+	Value   Expr
+	Line    uint
+}
+
+func (*IndexSet) exprNode() {}
+
+func (n *IndexSet) Pos() uint { return n.Line }
+
+func (n *IndexSet) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Object))
+	end = max(end, n.Bracket.Line)
+	end = max(end, endOf(n.Index))
+	end = max(end, endOf(n.Value))
+	return end
+}
+
+func (n *IndexSet) eachChild(f func(Node)) {
+	if n.Object != nil {
+		f(n.Object)
+	}
+	if n.Index != nil {
+		f(n.Index)
+	}
+	if n.Value != nil {
+		f(n.Value)
+	}
+}
+
+func (n *IndexSet) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Object = rewriteExpr(n.Object, f)
+	rewritten.Index = rewriteExpr(n.Index, f)
+	rewritten.Value = rewriteExpr(n.Value, f)
+	return &rewritten
+}
+
+// Lambda represents an anonymous function, as in fun (a, b) { return a + b; }
+// or (a) => a * 2. The body of the arrow form is a single return
+// statement. Keyword is the fun keyword or the arrow, whose line is
+// used to report errors.
+type Lambda struct {
+	Keyword token.Token
+	Params  []token.Token
+	Body    []Stmt
+	Line    uint
+}
+
+func (*Lambda) exprNode() {}
+
+func (n *Lambda) Pos() uint { return n.Line }
+
+func (n *Lambda) End() uint {
+	end := n.Line
+	end = max(end, n.Keyword.Line)
+	for _, tok := range n.Params {
+		end = max(end, tok.Line)
+	}
+	for _, child := range n.Body {
+		end = max(end, endOf(child))
+	}
+	return end
+}
+
+func (n *Lambda) eachChild(f func(Node)) {
+	for _, child := range n.Body {
+		f(child)
+	}
+}
+
+func (n *Lambda) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Body = rewriteStmts(n.Body, f)
+	return &rewritten
+}
+
+// ListLiteral represents a list written out element by element, as in [1, 2, 3].
+type ListLiteral struct {
+	Elements []Expr
+	Line     uint
+}
+
+func (*ListLiteral) exprNode() {}
+
+func (n *ListLiteral) Pos() uint { return n.Line }
+
+func (n *ListLiteral) End() uint {
+	end := n.Line
+	for _, child := range n.Elements {
+		end = max(end, endOf(child))
+	}
+	return end
+}
+
+func (n *ListLiteral) eachChild(f func(Node)) {
+	for _, child := range n.Elements {
+		f(child)
+	}
+}
+
+func (n *ListLiteral) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Elements = rewriteExprs(n.Elements, f)
+	return &rewritten
 }
 
 // Literal represents a literal value in the Lox language.
 // It can hold various types of values such as numbers, strings, or booleans.
 type Literal struct {
 	Value interface{}
+	Line  uint
+}
+
+func (*Literal) exprNode() {}
+
+func (n *Literal) Pos() uint { return n.Line }
+
+func (n *Literal) End() uint {
+	end := n.Line
+	return end
+}
+
+func (n *Literal) eachChild(f func(Node)) {
 }
 
-func (expr *Literal) Accept(v ExpressionVisitor) interface{} {
-	return v.VisitLiteralExpr(expr)
+func (n *Literal) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	return &rewritten
+}
+
+// MapLiteral represents a map written out entry by entry, as in {"a": 1}.
+// Keys[i] is the key of the entry whose value is Values[i]. Brace is the
+// closing brace, whose line is used to report errors.
+type MapLiteral struct {
+	Keys   []Expr
+	Values []Expr
+	Brace  token.Token
+	Line   uint
+}
+
+func (*MapLiteral) exprNode() {}
+
+func (n *MapLiteral) Pos() uint { return n.Line }
+
+func (n *MapLiteral) End() uint {
+	end := n.Line
+	for _, child := range n.Keys {
+		end = max(end, endOf(child))
+	}
+	for _, child := range n.Values {
+		end = max(end, endOf(child))
+	}
+	end = max(end, n.Brace.Line)
+	return end
+}
+
+func (n *MapLiteral) eachChild(f func(Node)) {
+	for _, child := range n.Keys {
+		f(child)
+	}
+	for _, child := range n.Values {
+		f(child)
+	}
+}
+
+func (n *MapLiteral) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Keys = rewriteExprs(n.Keys, f)
+	rewritten.Values = rewriteExprs(n.Values, f)
+	return &rewritten
+}
+
+// Slice represents the part of a list between two positions, as in xs[1:3].
+// A nil Low or High bound stands for the start or the end of the list.
+// Bracket is the closing bracket, whose line is used to report errors.
+type Slice struct {
+	Object  Expr
+	Bracket token.Token
+	Low     Expr
+	High    Expr
+	Line    uint
+}
+
+func (*Slice) exprNode() {}
+
+func (n *Slice) Pos() uint { return n.Line }
+
+func (n *Slice) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Object))
+	end = max(end, n.Bracket.Line)
+	end = max(end, endOf(n.Low))
+	end = max(end, endOf(n.High))
+	return end
+}
+
+func (n *Slice) eachChild(f func(Node)) {
+	if n.Object != nil {
+		f(n.Object)
+	}
+	if n.Low != nil {
+		f(n.Low)
+	}
+	if n.High != nil {
+		f(n.High)
+	}
+}
+
+func (n *Slice) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Object = rewriteExpr(n.Object, f)
+	rewritten.Low = rewriteExpr(n.Low, f)
+	rewritten.High = rewriteExpr(n.High, f)
+	return &rewritten
 }
 
 // Unary represents a unary expression in the Lox language.
@@ -55,18 +544,90 @@ func (expr *Literal) Accept(v ExpressionVisitor) interface{} {
 type Unary struct {
 	Operator token.Token
 	Right    Expr
+	Line     uint
+}
+
+func (*Unary) exprNode() {}
+
+func (n *Unary) Pos() uint { return n.Line }
+
+func (n *Unary) End() uint {
+	end := n.Line
+	end = max(end, n.Operator.Line)
+	end = max(end, endOf(n.Right))
+	return end
+}
+
+func (n *Unary) eachChild(f func(Node)) {
+	if n.Right != nil {
+		f(n.Right)
+	}
 }
 
-func (expr *Unary) Accept(v ExpressionVisitor) interface{} {
-	return v.VisitUnaryExpr(expr)
+func (n *Unary) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Right = rewriteExpr(n.Right, f)
+	return &rewritten
 }
 
 // Variable represents a variable expression in the Lox language.
 // It contains a token that holds the name of the variable.
 type Variable struct {
 	Name token.Token
+	Line uint
+}
+
+func (*Variable) exprNode() {}
+
+func (n *Variable) Pos() uint { return n.Line }
+
+func (n *Variable) End() uint {
+	end := n.Line
+	end = max(end, n.Name.Line)
+	return end
+}
+
+func (n *Variable) eachChild(f func(Node)) {
+}
+
+func (n *Variable) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	return &rewritten
 }
 
-func (expr *Variable) Accept(v ExpressionVisitor) interface{} {
-	return v.VisitVariableExpr(expr)
+// newExpr returns an empty Expr of the named kind, or nil if there is no such kind.
+func newExpr(kind string) Expr {
+	switch kind {
+	case "Assign":
+		return &Assign{}
+	case "Binary":
+		return &Binary{}
+	case "Call":
+		return &Call{}
+	case "Conditional":
+		return &Conditional{}
+	case "Get":
+		return &Get{}
+	case "Grouping":
+		return &Grouping{}
+	case "Index":
+		return &Index{}
+	case "IndexSet":
+		return &IndexSet{}
+	case "Lambda":
+		return &Lambda{}
+	case "ListLiteral":
+		return &ListLiteral{}
+	case "Literal":
+		return &Literal{}
+	case "MapLiteral":
+		return &MapLiteral{}
+	case "Slice":
+		return &Slice{}
+	case "Unary":
+		return &Unary{}
+	case "Variable":
+		return &Variable{}
+	}
+	return nil
 }
@@ -0,0 +1,161 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A program is encoded as a JSON array of statements. Each node is an object
+// whose "kind" member names its Go type and whose "line" member holds its
+// source line, followed by one member per field, named after the field with
+// a lower-case first letter:
+//
+//	{"kind":"PrintStmt","line":1,"expression":{"kind":"Literal","line":1,"value":1}}
+//
+// A missing expression, such as a variable declaration without an
+// initializer, is null. Tokens are encoded by token.Token.MarshalJSON.
+
+var (
+	exprType = reflect.TypeOf((*Expr)(nil)).Elem()
+	stmtType = reflect.TypeOf((*Stmt)(nil)).Elem()
+)
+
+// MarshalJSON encodes a program as JSON.
+func MarshalJSON(stmts []Stmt) ([]byte, error) {
+	return encodeValue(reflect.ValueOf(stmts))
+}
+
+// UnmarshalProgram decodes a program encoded by MarshalJSON.
+func UnmarshalProgram(data []byte) ([]Stmt, error) {
+	var stmts []Stmt
+	if decodeErr := decodeValue(reflect.ValueOf(&stmts).Elem(), data); decodeErr != nil {
+		return nil, decodeErr
+	}
+	return stmts, nil
+}
+
+func encodeValue(v reflect.Value) (json.RawMessage, error) {
+	switch {
+	case v.Type() == exprType || v.Type() == stmtType:
+		if v.IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		return encodeNode(v.Elem().Elem())
+	case v.Kind() == reflect.Slice:
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for idx := 0; idx < v.Len(); idx++ {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			elem, encodeErr := encodeValue(v.Index(idx))
+			if encodeErr != nil {
+				return nil, encodeErr
+			}
+			buf.Write(elem)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	}
+	return json.Marshal(v.Interface())
+}
+
+// encodeNode writes the members of a node in a fixed order: kind, line, then
+// the node's fields in declaration order.
+func encodeNode(node reflect.Value) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{"kind":%q,"line":%d`, node.Type().Name(), node.FieldByName("Line").Uint())
+	for idx := 0; idx < node.NumField(); idx++ {
+		field := node.Type().Field(idx)
+		if field.Name == "Line" {
+			continue
+		}
+		value, encodeErr := encodeValue(node.Field(idx))
+		if encodeErr != nil {
+			return nil, fmt.Errorf("%s.%s: %w", node.Type().Name(), field.Name, encodeErr)
+		}
+		fmt.Fprintf(&buf, ",%q:", jsonName(field.Name))
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func decodeValue(v reflect.Value, data json.RawMessage) error {
+	switch {
+	case v.Type() == exprType || v.Type() == stmtType:
+		if string(bytes.TrimSpace(data)) == "null" {
+			return nil
+		}
+		node, decodeErr := decodeNode(v.Type(), data)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		v.Set(node)
+		return nil
+	case v.Kind() == reflect.Slice:
+		var elems []json.RawMessage
+		if decodeErr := json.Unmarshal(data, &elems); decodeErr != nil {
+			return decodeErr
+		}
+		// The parser leaves empty lists nil, so decode them that way too.
+		if len(elems) == 0 {
+			return nil
+		}
+		slice := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for idx, elem := range elems {
+			if decodeErr := decodeValue(slice.Index(idx), elem); decodeErr != nil {
+				return decodeErr
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return json.Unmarshal(data, v.Addr().Interface())
+}
+
+// decodeNode decodes an object encoded by encodeNode into a new node of the
+// kind it names, which must belong to base (Expr or Stmt).
+func decodeNode(base reflect.Type, data json.RawMessage) (reflect.Value, error) {
+	var members map[string]json.RawMessage
+	if decodeErr := json.Unmarshal(data, &members); decodeErr != nil {
+		return reflect.Value{}, decodeErr
+	}
+	var kind string
+	if decodeErr := json.Unmarshal(members["kind"], &kind); decodeErr != nil {
+		return reflect.Value{}, fmt.Errorf("node without a kind: %s", data)
+	}
+
+	var node reflect.Value
+	if base == exprType {
+		node = reflect.ValueOf(newExpr(kind))
+	} else {
+		node = reflect.ValueOf(newStmt(kind))
+	}
+	if !node.IsValid() {
+		return reflect.Value{}, fmt.Errorf("unknown %s kind %q", base.Name(), kind)
+	}
+
+	fields := node.Elem()
+	for idx := 0; idx < fields.NumField(); idx++ {
+		name := fields.Type().Field(idx).Name
+		member, ok := members[jsonName(name)]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%s without a %q member", kind, jsonName(name))
+		}
+		if decodeErr := decodeValue(fields.Field(idx), member); decodeErr != nil {
+			return reflect.Value{}, fmt.Errorf("%s.%s: %w", kind, name, decodeErr)
+		}
+	}
+	return node, nil
+}
+
+// jsonName converts a Go field name to its JSON member name.
+func jsonName(field string) string {
+	first, size := utf8.DecodeRuneInString(field)
+	return string(unicode.ToLower(first)) + field[size:]
+}
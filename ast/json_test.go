@@ -0,0 +1,90 @@
+package ast_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+)
+
+func parse(t *testing.T, source string) []ast.Stmt {
+	t.Helper()
+	scanner := scanner.New(source)
+	stmts, parseErr := parser.New(scanner.ScanTokens()).Parse()
+	if parseErr != nil {
+		t.Fatalf("parse %q: %v", source, parseErr)
+	}
+	return stmts
+}
+
+func TestMarshalJSON(t *testing.T) {
+	stmts := parse(t, "var a;\nprint -a + 1;")
+	data, marshalErr := ast.MarshalJSON(stmts)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	expected := `[{"kind":"VarStmt","line":1,"name":{"type":"IDENTIFIER","lexeme":"a","literal":"a","line":1},"initializer":null},` +
+		`{"kind":"PrintStmt","line":2,"expression":{"kind":"Binary","line":2,` +
+		`"left":{"kind":"Unary","line":2,"operator":{"type":"MINUS","lexeme":"-","literal":"-","line":2},` +
+		`"right":{"kind":"Variable","line":2,"name":{"type":"IDENTIFIER","lexeme":"a","literal":"a","line":2}}},` +
+		`"operator":{"type":"PLUS","lexeme":"+","literal":"+","line":2},` +
+		`"right":{"kind":"Literal","line":2,"value":1}}}]`
+	if string(data) != expected {
+		t.Errorf("got\n%s\nexpected\n%s", data, expected)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	scripts, _ := filepath.Glob("../test/*.lox")
+	if len(scripts) == 0 {
+		t.Fatal("no test scripts found")
+	}
+	for _, script := range scripts {
+		t.Run(filepath.Base(script), func(t *testing.T) {
+			source, readErr := os.ReadFile(script)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			stmts := parse(t, string(source))
+			data, marshalErr := ast.MarshalJSON(stmts)
+			if marshalErr != nil {
+				t.Fatal(marshalErr)
+			}
+			decoded, unmarshalErr := ast.UnmarshalProgram(data)
+			if unmarshalErr != nil {
+				t.Fatal(unmarshalErr)
+			}
+			if !reflect.DeepEqual(decoded, stmts) {
+				t.Errorf("round trip changed the program:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestUnmarshalProgramErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"not an array", `{}`, "cannot unmarshal"},
+		{"missing kind", `[{"line":1}]`, "node without a kind"},
+		{"unknown kind", `[{"kind":"Nope","line":1}]`, `unknown Stmt kind "Nope"`},
+		{"expression as statement", `[{"kind":"Literal","line":1,"value":1}]`, `unknown Stmt kind "Literal"`},
+		{"missing member", `[{"kind":"PrintStmt","line":1}]`, `PrintStmt without a "expression" member`},
+		{"unknown token type", `[{"kind":"VarStmt","line":1,"name":{"type":"NOPE","lexeme":"a","line":1},"initializer":null}]`, `unknown token type "NOPE"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, unmarshalErr := ast.UnmarshalProgram([]byte(tt.input))
+			if unmarshalErr == nil || !strings.Contains(unmarshalErr.Error(), tt.expected) {
+				t.Errorf("got error %v, expected one containing %q", unmarshalErr, tt.expected)
+			}
+		})
+	}
+}
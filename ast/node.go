@@ -0,0 +1,104 @@
+package ast
+
+import "fmt"
+
+// Node is implemented by every expression and statement.
+type Node interface {
+	// Pos returns the line on which the node starts.
+	Pos() uint
+	// End returns the last line of the node's tokens and children. Closing
+	// punctuation such as ')' and ';' is not recorded, so a node that ends
+	// with one may report an earlier line.
+	End() uint
+
+	eachChild(f func(Node))
+	rewrite(f func(Node) Node) Node
+}
+
+// Inspect traverses the tree rooted at node in depth-first order. It calls
+// f(node); if f returns true, Inspect visits each non-nil child of node in
+// field order, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	if !f(node) {
+		return
+	}
+	node.eachChild(func(child Node) {
+		Inspect(child, f)
+	})
+	f(nil)
+}
+
+// Rewrite returns a copy of the tree rooted at node in which every node has
+// been replaced by f's result. Children are rewritten before their parent,
+// so f sees a node whose children have already been replaced. The nodes
+// passed to f are copies, which f may modify or return as they are.
+//
+// f must return an Expr in place of an Expr and a Stmt in place of a Stmt.
+// Returning nil removes an optional child, such as a variable initializer,
+// or removes the node from a list of statements or expressions.
+func Rewrite(node Node, f func(Node) Node) Node {
+	return f(node.rewrite(f))
+}
+
+// RewriteProgram applies Rewrite to each statement of a program, dropping
+// the statements f removes.
+func RewriteProgram(stmts []Stmt, f func(Node) Node) []Stmt {
+	return rewriteStmts(stmts, f)
+}
+
+func endOf(node Node) uint {
+	if node == nil {
+		return 0
+	}
+	return node.End()
+}
+
+func rewriteExpr(expr Expr, f func(Node) Node) Expr {
+	if expr == nil {
+		return nil
+	}
+	result := Rewrite(expr, f)
+	if result == nil {
+		return nil
+	}
+	rewritten, ok := result.(Expr)
+	if !ok {
+		panic(fmt.Sprintf("ast: Rewrite replaced an expression with %T", result))
+	}
+	return rewritten
+}
+
+func rewriteStmt(stmt Stmt, f func(Node) Node) Stmt {
+	if stmt == nil {
+		return nil
+	}
+	result := Rewrite(stmt, f)
+	if result == nil {
+		return nil
+	}
+	rewritten, ok := result.(Stmt)
+	if !ok {
+		panic(fmt.Sprintf("ast: Rewrite replaced a statement with %T", result))
+	}
+	return rewritten
+}
+
+func rewriteExprs(exprs []Expr, f func(Node) Node) []Expr {
+	var result []Expr
+	for _, expr := range exprs {
+		if rewritten := rewriteExpr(expr, f); rewritten != nil {
+			result = append(result, rewritten)
+		}
+	}
+	return result
+}
+
+func rewriteStmts(stmts []Stmt, f func(Node) Node) []Stmt {
+	result := make([]Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		if rewritten := rewriteStmt(stmt, f); rewritten != nil {
+			result = append(result, rewritten)
+		}
+	}
+	return result
+}
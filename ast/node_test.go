@@ -0,0 +1,101 @@
+package ast_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nicholasq/glox/ast"
+)
+
+func TestInspect(t *testing.T) {
+	stmts := parse(t, "var a = -(1 + b);\nprint a;")
+	var visited []string
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			if node == nil {
+				visited = append(visited, "end")
+				return false
+			}
+			visited = append(visited, fmt.Sprintf("%T", node))
+			// Skip the operands of binary expressions.
+			_, isBinary := node.(*ast.Binary)
+			return !isBinary
+		})
+	}
+	expected := "*ast.VarStmt *ast.Unary *ast.Grouping *ast.Binary end end end *ast.PrintStmt *ast.Variable end end"
+	if actual := strings.Join(visited, " "); actual != expected {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+func TestPosEnd(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   uint
+		end   uint
+	}{
+		{"Single line", "print 1 + 2;", 1, 1},
+		{"Operands on later lines", "print 1\n+\n(2\n*\nx);", 1, 5},
+		{"Declaration", "\nvar a =\n\n  nil;", 2, 4},
+		{"No initializer", "var a;", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := parse(t, tt.input)[0]
+			if stmt.Pos() != tt.pos || stmt.End() != tt.end {
+				t.Errorf("Expected lines %d-%d, got %d-%d", tt.pos, tt.end, stmt.Pos(), stmt.End())
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	stmts := parse(t, "var a = x + 1; x; print -x;")
+	// Replace every variable named x with the literal 2 and drop
+	// expression statements.
+	rewritten := ast.RewriteProgram(stmts, func(node ast.Node) ast.Node {
+		switch n := node.(type) {
+		case *ast.Variable:
+			if n.Name.Lexeme == "x" {
+				return &ast.Literal{Value: float64(2), Line: n.Line}
+			}
+		case *ast.ExpressionStmt:
+			return nil
+		}
+		return node
+	})
+
+	if len(rewritten) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(rewritten))
+	}
+	binary := rewritten[0].(*ast.VarStmt).Initializer.(*ast.Binary)
+	if literal, ok := binary.Left.(*ast.Literal); !ok || literal.Value != float64(2) {
+		t.Errorf("Expected x to be replaced, got %#v", binary.Left)
+	}
+	unary := rewritten[1].(*ast.PrintStmt).Expression.(*ast.Unary)
+	if _, ok := unary.Right.(*ast.Literal); !ok {
+		t.Errorf("Expected x to be replaced, got %#v", unary.Right)
+	}
+
+	// The original program is left untouched.
+	original := stmts[0].(*ast.VarStmt).Initializer.(*ast.Binary)
+	if _, ok := original.Left.(*ast.Variable); !ok || len(stmts) != 3 {
+		t.Errorf("Rewrite modified the original program")
+	}
+}
+
+func TestRewriteWrongFamily(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "replaced an expression with *ast.PrintStmt") {
+			t.Errorf("Expected a panic about the replacement, got %v", r)
+		}
+	}()
+	ast.Rewrite(parse(t, "print 1;")[0], func(node ast.Node) ast.Node {
+		if literal, ok := node.(*ast.Literal); ok {
+			return &ast.PrintStmt{Expression: literal}
+		}
+		return node
+	})
+}
@@ -0,0 +1,197 @@
+# Declarative description of the Lox syntax tree. cmd/generate_ast reads
+# this file and writes expr.go and stmt.go; run `go generate ./ast` after
+# editing it.
+#
+# A node starts with a line "<Base> <Name>", where Base is Expr or Stmt,
+# followed by indented lines that are either
+#
+#     doc <text>        a line of the node's doc comment, or
+#     <Field> <GoType>  one of the node's fields.
+#
+# Every node also gets a generated Line field holding the source line on
+# which it starts. Fields of type Expr, Stmt, []Expr and []Stmt are the
+# node's children, which Inspect and Rewrite visit in field order.
+
+Expr Assign
+    doc Assign represents assigning a new value to the variable Name.
+    Name  token.Token
+    Value Expr
+
+Expr Binary
+    doc Binary represents a binary expression in the Lox language.
+    doc It consists of a left operand, an operator, and a right operand.
+    doc The comma operator is a Binary whose value is its right operand.
+    Left     Expr
+    Operator token.Token
+    Right    Expr
+
+Expr Call
+    doc Call represents a call of a function with a list of arguments.
+    doc Paren is the closing parenthesis, whose line is used to report errors.
+    Callee    Expr
+    Paren     token.Token
+    Arguments []Expr
+
+Expr Conditional
+    doc Conditional represents the ternary operator, as in cond ? a : b,
+    doc which evaluates only the branch the condition selects.
+    Condition  Expr
+    ThenBranch Expr
+    ElseBranch Expr
+
+Expr Get
+    doc Get represents reading the property Name of an object, such as a
+    doc member of the math namespace.
+    Object Expr
+    Name   token.Token
+
+Expr Grouping
+    doc Grouping represents a grouping expression in the Lox language.
+    doc It contains a single expression that is enclosed in parentheses.
+    Expression Expr
+
+Expr Index
+    doc Index represents reading one element of a string or list by its position.
+    doc Bracket is the closing bracket, whose line is used to report errors.
+    Object  Expr
+    Bracket token.Token
+    Index   Expr
+
+Expr IndexSet
+    doc IndexSet represents assigning to one element of a list, as in xs[i] = v.
+    doc Bracket is the closing bracket, whose line is used to report errors.
+    Object  Expr
+    Bracket token.Token
+    Index   Expr
+    Value   Expr
+
+Expr Lambda
+    doc Lambda represents an anonymous function, as in fun (a, b) { return a + b; }
+    doc or (a) => a * 2. The body of the arrow form is a single return
+    doc statement. Keyword is the fun keyword or the arrow, whose line is
+    doc used to report errors.
+    Keyword token.Token
+    Params  []token.Token
+    Body    []Stmt
+
+Expr ListLiteral
+    doc ListLiteral represents a list written out element by element, as in [1, 2, 3].
+    Elements []Expr
+
+Expr Literal
+    doc Literal represents a literal value in the Lox language.
+    doc It can hold various types of values such as numbers, strings, or booleans.
+    Value interface{}
+
+Expr MapLiteral
+    doc MapLiteral represents a map written out entry by entry, as in {"a": 1}.
+    doc Keys[i] is the key of the entry whose value is Values[i]. Brace is the
+    doc closing brace, whose line is used to report errors.
+    Keys   []Expr
+    Values []Expr
+    Brace  token.Token
+
+Expr Slice
+    doc Slice represents the part of a list between two positions, as in xs[1:3].
+    doc A nil Low or High bound stands for the start or the end of the list.
+    doc Bracket is the closing bracket, whose line is used to report errors.
+    Object  Expr
+    Bracket token.Token
+    Low     Expr
+    High    Expr
+
+Expr Unary
+    doc Unary represents a unary expression in the Lox language.
+    doc It consists of an operator token and a right operand expression.
+    Operator token.Token
+    Right    Expr
+
+Expr Variable
+    doc Variable represents a variable expression in the Lox language.
+    doc It contains a token that holds the name of the variable.
+    Name token.Token
+
+Stmt BlockStmt
+    doc BlockStmt represents a braced list of statements, which run in a scope of their own.
+    Statements []Stmt
+
+Stmt BreakStmt
+    doc BreakStmt represents a break statement, which ends the innermost loop.
+    Keyword token.Token
+
+Stmt ContinueStmt
+    doc ContinueStmt represents a continue statement, which skips the rest of
+    doc the innermost loop's body.
+    Keyword token.Token
+
+Stmt ExpressionStmt
+    doc ExpressionStmt represents a statement that consists of a single expression.
+    Expression Expr
+
+Stmt ForInStmt
+    doc ForInStmt represents a loop that runs Body once for each element of
+    doc Iterable, with the element bound to the variable Name, as in
+    doc for (var x in xs) print x;. In is the in keyword, whose line is used
+    doc to report errors.
+    Name     token.Token
+    In       token.Token
+    Iterable Expr
+    Body     Stmt
+
+Stmt IfStmt
+    doc IfStmt represents an if statement. ElseBranch is nil if there is no
+    doc else clause.
+    Condition  Expr
+    ThenBranch Stmt
+    ElseBranch Stmt
+
+Stmt ImportStmt
+    doc ImportStmt represents an import statement, which runs the module at Path
+    doc once and binds it, as in import "util.lox" as u;, or binds the members
+    doc listed in Names, as in import { a, b } from "util.lox";. Alias is the
+    doc zero token when Names is used. Keyword is the import keyword, whose
+    doc line is used to report errors.
+    Keyword token.Token
+    Path    token.Token
+    Alias   token.Token
+    Names   []token.Token
+
+Stmt PrintStmt
+    doc PrintStmt represents a print statement in the AST.
+    Expression Expr
+
+Stmt ReturnStmt
+    doc ReturnStmt represents a return statement. Value is nil if the
+    doc statement returns nothing, which makes the call evaluate to nil.
+    Keyword token.Token
+    Value   Expr
+
+Stmt ThrowStmt
+    doc ThrowStmt represents a throw statement, which raises Value as an
+    doc exception. Keyword is the throw keyword, whose line is used to report
+    doc the exception if nothing catches it.
+    Keyword token.Token
+    Value   Expr
+
+Stmt TryStmt
+    doc TryStmt represents a try statement. Body, Catch and Finally are blocks;
+    doc Catch, which binds the caught exception to the variable CatchName, or
+    doc Finally is nil if the statement has no such clause.
+    Body      Stmt
+    CatchName token.Token
+    Catch     Stmt
+    Finally   Stmt
+
+Stmt VarStmt
+    doc VarStmt represents a variable declaration statement in the AST.
+    Name        token.Token
+    Initializer Expr
+
+Stmt WhileStmt
+    doc WhileStmt represents a loop that runs Body while Condition is truthy.
+    doc A for loop becomes a WhileStmt whose Increment, if it has one, is
+    doc evaluated after the body on every iteration, including those cut short
+    doc by continue.
+    Condition Expr
+    Body      Stmt
+    Increment Expr
@@ -1,45 +1,533 @@
+// Code generated by generate_ast from nodes.spec. DO NOT EDIT.
+
 package ast
 
-import "github.com/nicholasq/glox/token"
+import (
+	"fmt"
+
+	"github.com/nicholasq/glox/token"
+)
 
 // Stmt is the interface for all statement types in the AST.
-// It defines the Accept method for the visitor pattern.
+// Statements are visited with AcceptStmt and a StmtVisitor.
+// Every statement records the source line it starts on in its Line field.
 type Stmt interface {
-	Accept(v ExpressionStmtVisitor)
+	Node
+	stmtNode()
+}
+
+// StmtVisitor has a method for each kind of Stmt, returning a result of type R.
+type StmtVisitor[R any] interface {
+	VisitBlockStmt(stmt *BlockStmt) R
+	VisitBreakStmt(stmt *BreakStmt) R
+	VisitContinueStmt(stmt *ContinueStmt) R
+	VisitExpressionStmt(stmt *ExpressionStmt) R
+	VisitForInStmt(stmt *ForInStmt) R
+	VisitIfStmt(stmt *IfStmt) R
+	VisitImportStmt(stmt *ImportStmt) R
+	VisitPrintStmt(stmt *PrintStmt) R
+	VisitReturnStmt(stmt *ReturnStmt) R
+	VisitThrowStmt(stmt *ThrowStmt) R
+	VisitTryStmt(stmt *TryStmt) R
+	VisitVarStmt(stmt *VarStmt) R
+	VisitWhileStmt(stmt *WhileStmt) R
+}
+
+// AcceptStmt calls the method of v that matches the type of stmt and returns its result.
+func AcceptStmt[R any](stmt Stmt, v StmtVisitor[R]) R {
+	switch n := stmt.(type) {
+	case *BlockStmt:
+		return v.VisitBlockStmt(n)
+	case *BreakStmt:
+		return v.VisitBreakStmt(n)
+	case *ContinueStmt:
+		return v.VisitContinueStmt(n)
+	case *ExpressionStmt:
+		return v.VisitExpressionStmt(n)
+	case *ForInStmt:
+		return v.VisitForInStmt(n)
+	case *IfStmt:
+		return v.VisitIfStmt(n)
+	case *ImportStmt:
+		return v.VisitImportStmt(n)
+	case *PrintStmt:
+		return v.VisitPrintStmt(n)
+	case *ReturnStmt:
+		return v.VisitReturnStmt(n)
+	case *ThrowStmt:
+		return v.VisitThrowStmt(n)
+	case *TryStmt:
+		return v.VisitTryStmt(n)
+	case *VarStmt:
+		return v.VisitVarStmt(n)
+	case *WhileStmt:
+		return v.VisitWhileStmt(n)
+	}
+	panic(fmt.Sprintf("ast: unexpected Stmt type %T", stmt))
+}
+
+// BlockStmt represents a braced list of statements, which run in a scope of their own.
+type BlockStmt struct {
+	Statements []Stmt
+	Line       uint
+}
+
+func (*BlockStmt) stmtNode() {}
+
+func (n *BlockStmt) Pos() uint { return n.Line }
+
+func (n *BlockStmt) End() uint {
+	end := n.Line
+	for _, child := range n.Statements {
+		end = max(end, endOf(child))
+	}
+	return end
+}
+
+func (n *BlockStmt) eachChild(f func(Node)) {
+	for _, child := range n.Statements {
+		f(child)
+	}
+}
+
+func (n *BlockStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Statements = rewriteStmts(n.Statements, f)
+	return &rewritten
+}
+
+// BreakStmt represents a break statement, which ends the innermost loop.
+type BreakStmt struct {
+	Keyword token.Token
+	Line    uint
+}
+
+func (*BreakStmt) stmtNode() {}
+
+func (n *BreakStmt) Pos() uint { return n.Line }
+
+func (n *BreakStmt) End() uint {
+	end := n.Line
+	end = max(end, n.Keyword.Line)
+	return end
+}
+
+func (n *BreakStmt) eachChild(f func(Node)) {
+}
+
+func (n *BreakStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	return &rewritten
+}
+
+// ContinueStmt represents a continue statement, which skips the rest of
+// the innermost loop's body.
+type ContinueStmt struct {
+	Keyword token.Token
+	Line    uint
+}
+
+func (*ContinueStmt) stmtNode() {}
+
+func (n *ContinueStmt) Pos() uint { return n.Line }
+
+func (n *ContinueStmt) End() uint {
+	end := n.Line
+	end = max(end, n.Keyword.Line)
+	return end
+}
+
+func (n *ContinueStmt) eachChild(f func(Node)) {
 }
 
-// ExpressionStmtVisitor defines the interface for visiting different types of statements.
-// Each method corresponds to a specific statement type.
-type ExpressionStmtVisitor interface {
-	VisitExpressionStmt(stmt *ExpressionStmt)
-	VisitPrintStmt(stmt *PrintStmt)
-	VisitVarStmt(stmt *VarStmt)
+func (n *ContinueStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	return &rewritten
 }
 
 // ExpressionStmt represents a statement that consists of a single expression.
 type ExpressionStmt struct {
 	Expression Expr
+	Line       uint
+}
+
+func (*ExpressionStmt) stmtNode() {}
+
+func (n *ExpressionStmt) Pos() uint { return n.Line }
+
+func (n *ExpressionStmt) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Expression))
+	return end
+}
+
+func (n *ExpressionStmt) eachChild(f func(Node)) {
+	if n.Expression != nil {
+		f(n.Expression)
+	}
+}
+
+func (n *ExpressionStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Expression = rewriteExpr(n.Expression, f)
+	return &rewritten
+}
+
+// ForInStmt represents a loop that runs Body once for each element of
+// Iterable, with the element bound to the variable Name, as in
+// for (var x in xs) print x;. In is the in keyword, whose line is used
+// to report errors.
+type ForInStmt struct {
+	Name     token.Token
+	In       token.Token
+	Iterable Expr
+	Body     Stmt
+	Line     uint
+}
+
+func (*ForInStmt) stmtNode() {}
+
+func (n *ForInStmt) Pos() uint { return n.Line }
+
+func (n *ForInStmt) End() uint {
+	end := n.Line
+	end = max(end, n.Name.Line)
+	end = max(end, n.In.Line)
+	end = max(end, endOf(n.Iterable))
+	end = max(end, endOf(n.Body))
+	return end
+}
+
+func (n *ForInStmt) eachChild(f func(Node)) {
+	if n.Iterable != nil {
+		f(n.Iterable)
+	}
+	if n.Body != nil {
+		f(n.Body)
+	}
+}
+
+func (n *ForInStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Iterable = rewriteExpr(n.Iterable, f)
+	rewritten.Body = rewriteStmt(n.Body, f)
+	return &rewritten
+}
+
+// IfStmt represents an if statement. ElseBranch is nil if there is no
+// else clause.
+type IfStmt struct {
+	Condition  Expr
+	ThenBranch Stmt
+	ElseBranch Stmt
+	Line       uint
+}
+
+func (*IfStmt) stmtNode() {}
+
+func (n *IfStmt) Pos() uint { return n.Line }
+
+func (n *IfStmt) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Condition))
+	end = max(end, endOf(n.ThenBranch))
+	end = max(end, endOf(n.ElseBranch))
+	return end
+}
+
+func (n *IfStmt) eachChild(f func(Node)) {
+	if n.Condition != nil {
+		f(n.Condition)
+	}
+	if n.ThenBranch != nil {
+		f(n.ThenBranch)
+	}
+	if n.ElseBranch != nil {
+		f(n.ElseBranch)
+	}
+}
+
+func (n *IfStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Condition = rewriteExpr(n.Condition, f)
+	rewritten.ThenBranch = rewriteStmt(n.ThenBranch, f)
+	rewritten.ElseBranch = rewriteStmt(n.ElseBranch, f)
+	return &rewritten
 }
 
-func (expr *ExpressionStmt) Accept(v ExpressionStmtVisitor) {
-	v.VisitExpressionStmt(expr)
+// ImportStmt represents an import statement, which runs the module at Path
+// once and binds it, as in import "util.lox" as u;, or binds the members
+// listed in Names, as in import { a, b } from "util.lox";. Alias is the
+// zero token when Names is used. Keyword is the import keyword, whose
+// line is used to report errors.
+type ImportStmt struct {
+	Keyword token.Token
+	Path    token.Token
+	Alias   token.Token
+	Names   []token.Token
+	Line    uint
+}
+
+func (*ImportStmt) stmtNode() {}
+
+func (n *ImportStmt) Pos() uint { return n.Line }
+
+func (n *ImportStmt) End() uint {
+	end := n.Line
+	end = max(end, n.Keyword.Line)
+	end = max(end, n.Path.Line)
+	end = max(end, n.Alias.Line)
+	for _, tok := range n.Names {
+		end = max(end, tok.Line)
+	}
+	return end
+}
+
+func (n *ImportStmt) eachChild(f func(Node)) {
+}
+
+func (n *ImportStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	return &rewritten
 }
 
 // PrintStmt represents a print statement in the AST.
 type PrintStmt struct {
 	Expression Expr
+	Line       uint
+}
+
+func (*PrintStmt) stmtNode() {}
+
+func (n *PrintStmt) Pos() uint { return n.Line }
+
+func (n *PrintStmt) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Expression))
+	return end
+}
+
+func (n *PrintStmt) eachChild(f func(Node)) {
+	if n.Expression != nil {
+		f(n.Expression)
+	}
+}
+
+func (n *PrintStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Expression = rewriteExpr(n.Expression, f)
+	return &rewritten
+}
+
+// ReturnStmt represents a return statement. Value is nil if the
+// statement returns nothing, which makes the call evaluate to nil.
+type ReturnStmt struct {
+	Keyword token.Token
+	Value   Expr
+	Line    uint
+}
+
+func (*ReturnStmt) stmtNode() {}
+
+func (n *ReturnStmt) Pos() uint { return n.Line }
+
+func (n *ReturnStmt) End() uint {
+	end := n.Line
+	end = max(end, n.Keyword.Line)
+	end = max(end, endOf(n.Value))
+	return end
+}
+
+func (n *ReturnStmt) eachChild(f func(Node)) {
+	if n.Value != nil {
+		f(n.Value)
+	}
+}
+
+func (n *ReturnStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Value = rewriteExpr(n.Value, f)
+	return &rewritten
+}
+
+// ThrowStmt represents a throw statement, which raises Value as an
+// exception. Keyword is the throw keyword, whose line is used to report
+// the exception if nothing catches it.
+type ThrowStmt struct {
+	Keyword token.Token
+	Value   Expr
+	Line    uint
+}
+
+func (*ThrowStmt) stmtNode() {}
+
+func (n *ThrowStmt) Pos() uint { return n.Line }
+
+func (n *ThrowStmt) End() uint {
+	end := n.Line
+	end = max(end, n.Keyword.Line)
+	end = max(end, endOf(n.Value))
+	return end
+}
+
+func (n *ThrowStmt) eachChild(f func(Node)) {
+	if n.Value != nil {
+		f(n.Value)
+	}
+}
+
+func (n *ThrowStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Value = rewriteExpr(n.Value, f)
+	return &rewritten
+}
+
+// TryStmt represents a try statement. Body, Catch and Finally are blocks;
+// Catch, which binds the caught exception to the variable CatchName, or
+// Finally is nil if the statement has no such clause.
+type TryStmt struct {
+	Body      Stmt
+	CatchName token.Token
+	Catch     Stmt
+	Finally   Stmt
+	Line      uint
+}
+
+func (*TryStmt) stmtNode() {}
+
+func (n *TryStmt) Pos() uint { return n.Line }
+
+func (n *TryStmt) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Body))
+	end = max(end, n.CatchName.Line)
+	end = max(end, endOf(n.Catch))
+	end = max(end, endOf(n.Finally))
+	return end
+}
+
+func (n *TryStmt) eachChild(f func(Node)) {
+	if n.Body != nil {
+		f(n.Body)
+	}
+	if n.Catch != nil {
+		f(n.Catch)
+	}
+	if n.Finally != nil {
+		f(n.Finally)
+	}
 }
 
-func (expr *PrintStmt) Accept(v ExpressionStmtVisitor) {
-	v.VisitPrintStmt(expr)
+func (n *TryStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Body = rewriteStmt(n.Body, f)
+	rewritten.Catch = rewriteStmt(n.Catch, f)
+	rewritten.Finally = rewriteStmt(n.Finally, f)
+	return &rewritten
 }
 
 // VarStmt represents a variable declaration statement in the AST.
 type VarStmt struct {
 	Name        token.Token
 	Initializer Expr
+	Line        uint
+}
+
+func (*VarStmt) stmtNode() {}
+
+func (n *VarStmt) Pos() uint { return n.Line }
+
+func (n *VarStmt) End() uint {
+	end := n.Line
+	end = max(end, n.Name.Line)
+	end = max(end, endOf(n.Initializer))
+	return end
+}
+
+func (n *VarStmt) eachChild(f func(Node)) {
+	if n.Initializer != nil {
+		f(n.Initializer)
+	}
+}
+
+func (n *VarStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Initializer = rewriteExpr(n.Initializer, f)
+	return &rewritten
+}
+
+// WhileStmt represents a loop that runs Body while Condition is truthy.
+// A for loop becomes a WhileStmt whose Increment, if it has one, is
+// evaluated after the body on every iteration, including those cut short
+// by continue.
+type WhileStmt struct {
+	Condition Expr
+	Body      Stmt
+	Increment Expr
+	Line      uint
+}
+
+func (*WhileStmt) stmtNode() {}
+
+func (n *WhileStmt) Pos() uint { return n.Line }
+
+func (n *WhileStmt) End() uint {
+	end := n.Line
+	end = max(end, endOf(n.Condition))
+	end = max(end, endOf(n.Body))
+	end = max(end, endOf(n.Increment))
+	return end
+}
+
+func (n *WhileStmt) eachChild(f func(Node)) {
+	if n.Condition != nil {
+		f(n.Condition)
+	}
+	if n.Body != nil {
+		f(n.Body)
+	}
+	if n.Increment != nil {
+		f(n.Increment)
+	}
+}
+
+func (n *WhileStmt) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+	rewritten.Condition = rewriteExpr(n.Condition, f)
+	rewritten.Body = rewriteStmt(n.Body, f)
+	rewritten.Increment = rewriteExpr(n.Increment, f)
+	return &rewritten
 }
 
-func (expr *VarStmt) Accept(v ExpressionStmtVisitor) {
-	v.VisitVarStmt(expr)
+// newStmt returns an empty Stmt of the named kind, or nil if there is no such kind.
+func newStmt(kind string) Stmt {
+	switch kind {
+	case "BlockStmt":
+		return &BlockStmt{}
+	case "BreakStmt":
+		return &BreakStmt{}
+	case "ContinueStmt":
+		return &ContinueStmt{}
+	case "ExpressionStmt":
+		return &ExpressionStmt{}
+	case "ForInStmt":
+		return &ForInStmt{}
+	case "IfStmt":
+		return &IfStmt{}
+	case "ImportStmt":
+		return &ImportStmt{}
+	case "PrintStmt":
+		return &PrintStmt{}
+	case "ReturnStmt":
+		return &ReturnStmt{}
+	case "ThrowStmt":
+		return &ThrowStmt{}
+	case "TryStmt":
+		return &TryStmt{}
+	case "VarStmt":
+		return &VarStmt{}
+	case "WhileStmt":
+		return &WhileStmt{}
+	}
+	return nil
 }
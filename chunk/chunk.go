@@ -0,0 +1,283 @@
+// Package chunk defines the bytecode produced by the compiler and executed
+// by the vm: instructions, their constant pool and a table mapping
+// instructions back to source lines.
+package chunk
+
+import (
+	"math"
+	"sort"
+)
+
+// OpCode is a single bytecode instruction. Adding, removing or reordering
+// opcodes changes the meaning of compiled files, so FormatVersion must be
+// bumped along with them.
+type OpCode byte
+
+const (
+	// OpConstant pushes the constant at its two-byte operand index.
+	OpConstant OpCode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+
+	// Globals take the two-byte constant index of their name as operand.
+	OpDefineGlobal
+	OpGetGlobal
+	OpSetGlobal
+	// Locals take a one-byte operand, the variable's stack slot counted
+	// from the start of the current frame.
+	OpGetLocal
+	OpSetLocal
+	// Upvalues take a one-byte operand, the variable's index among the
+	// running closure's upvalues. OpCloseUpvalue pops the local on top of
+	// the stack, first moving it off the stack for the closures that
+	// captured it.
+	OpGetUpvalue
+	OpSetUpvalue
+	OpCloseUpvalue
+
+	// OpGetProperty replaces the object on top of the stack with its
+	// property named by the two-byte constant index operand.
+	OpGetProperty
+	// OpIndex replaces an object and the index above it with the object's
+	// element at that index.
+	OpIndex
+	// OpSetIndex stores the value on top of the stack at the index below it
+	// in the object below that, leaving just the value.
+	OpSetIndex
+	// OpSlice replaces an object and the start and end above it, either of
+	// which may be nil, with the slice of the object between them.
+	OpSlice
+	// OpList replaces the number of values given by its two-byte operand
+	// with a list of them.
+	OpList
+	// OpMap replaces the number of key/value pairs given by its two-byte
+	// operand, each key pushed before its value, with a map of them.
+	OpMap
+
+	OpEqual
+	OpGreater
+	OpGreaterEqual
+	OpLess
+	OpLessEqual
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpModulo
+	OpFloorDivide
+	OpPower
+	// The bitwise instructions raise a runtime error unless their operands
+	// are integers.
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpBitNot
+	OpNot
+	OpNegate
+
+	// OpIterate replaces the value on top of the stack with an iterator
+	// over its elements.
+	OpIterate
+	// OpForIter pushes the next element of the iterator on top of the
+	// stack or, once there are none left, jumps forward by its two-byte
+	// operand.
+	OpForIter
+	// OpLoop jumps backward by its two-byte operand.
+	OpLoop
+	// OpJump jumps forward by its two-byte operand, and OpJumpIfFalse does
+	// so if the value on top of the stack is falsey, leaving it there.
+	OpJump
+	OpJumpIfFalse
+
+	// OpTry starts a try statement whose handler is its two-byte forward
+	// jump offset. If a runtime error is raised before the matching
+	// OpEndTry, the stack is unwound to where it was at OpTry and execution
+	// continues at the handler with the error pushed. OpCatch replaces that
+	// error with the value a catch clause binds, and OpThrow raises the
+	// value on top of the stack, re-raising an error pushed by a handler.
+	OpTry
+	OpEndTry
+	OpCatch
+	OpThrow
+
+	// OpImport pushes the namespace of the module whose path is the
+	// constant at its two-byte operand index, running the module first if
+	// it has not been imported yet. OpImportFrom replaces the namespace on
+	// top of the stack with its member named by its constant operand.
+	OpImport
+	OpImportFrom
+
+	// OpClosure pushes a closure of the function at its two-byte constant
+	// operand index. The operand is followed by two bytes for each of the
+	// function's upvalues: 1 if it captures a local of the enclosing
+	// function and 0 if it shares one of its upvalues, then the local's
+	// slot or the upvalue's index.
+	OpClosure
+	// OpCall calls the value below its arguments; its one-byte operand is
+	// the argument count.
+	OpCall
+
+	OpPrint
+	OpReturn
+)
+
+var OpNames = map[OpCode]string{
+	OpConstant:     "OP_CONSTANT",
+	OpNil:          "OP_NIL",
+	OpTrue:         "OP_TRUE",
+	OpFalse:        "OP_FALSE",
+	OpPop:          "OP_POP",
+	OpDefineGlobal: "OP_DEFINE_GLOBAL",
+	OpGetGlobal:    "OP_GET_GLOBAL",
+	OpSetGlobal:    "OP_SET_GLOBAL",
+	OpGetLocal:     "OP_GET_LOCAL",
+	OpSetLocal:     "OP_SET_LOCAL",
+	OpGetUpvalue:   "OP_GET_UPVALUE",
+	OpSetUpvalue:   "OP_SET_UPVALUE",
+	OpCloseUpvalue: "OP_CLOSE_UPVALUE",
+	OpGetProperty:  "OP_GET_PROPERTY",
+	OpIndex:        "OP_INDEX",
+	OpSetIndex:     "OP_SET_INDEX",
+	OpSlice:        "OP_SLICE",
+	OpList:         "OP_LIST",
+	OpMap:          "OP_MAP",
+	OpEqual:        "OP_EQUAL",
+	OpGreater:      "OP_GREATER",
+	OpGreaterEqual: "OP_GREATER_EQUAL",
+	OpLess:         "OP_LESS",
+	OpLessEqual:    "OP_LESS_EQUAL",
+	OpAdd:          "OP_ADD",
+	OpSubtract:     "OP_SUBTRACT",
+	OpMultiply:     "OP_MULTIPLY",
+	OpDivide:       "OP_DIVIDE",
+	OpModulo:       "OP_MODULO",
+	OpFloorDivide:  "OP_FLOOR_DIVIDE",
+	OpPower:        "OP_POWER",
+	OpBitAnd:       "OP_BIT_AND",
+	OpBitOr:        "OP_BIT_OR",
+	OpBitXor:       "OP_BIT_XOR",
+	OpShiftLeft:    "OP_SHIFT_LEFT",
+	OpShiftRight:   "OP_SHIFT_RIGHT",
+	OpBitNot:       "OP_BIT_NOT",
+	OpNot:          "OP_NOT",
+	OpNegate:       "OP_NEGATE",
+	OpIterate:      "OP_ITERATE",
+	OpForIter:      "OP_FOR_ITER",
+	OpLoop:         "OP_LOOP",
+	OpJump:         "OP_JUMP",
+	OpJumpIfFalse:  "OP_JUMP_IF_FALSE",
+	OpTry:          "OP_TRY",
+	OpEndTry:       "OP_END_TRY",
+	OpCatch:        "OP_CATCH",
+	OpThrow:        "OP_THROW",
+	OpImport:       "OP_IMPORT",
+	OpImportFrom:   "OP_IMPORT_FROM",
+	OpClosure:      "OP_CLOSURE",
+	OpCall:         "OP_CALL",
+	OpPrint:        "OP_PRINT",
+	OpReturn:       "OP_RETURN",
+}
+
+func (op OpCode) String() string {
+	if name, ok := OpNames[op]; ok {
+		return name
+	}
+	return "OP_UNKNOWN"
+}
+
+// Value is a Lox value in the constant pool or on the vm's stack: nil, bool,
+// float64, string or *Function, or at run time a native function or namespace.
+type Value = interface{}
+
+// LineStart records that the instructions from Offset onwards, up to the
+// next LineStart, were compiled from source line Line.
+type LineStart struct {
+	Offset int
+	Line   uint
+}
+
+// Chunk is a sequence of bytecode instructions with its constants and line table.
+type Chunk struct {
+	Code      []byte
+	Constants []Value
+	Lines     []LineStart
+}
+
+// Write appends a byte compiled from the given source line.
+func (c *Chunk) Write(b byte, line uint) {
+	if len(c.Lines) == 0 || c.Lines[len(c.Lines)-1].Line != line {
+		c.Lines = append(c.Lines, LineStart{Offset: len(c.Code), Line: line})
+	}
+	c.Code = append(c.Code, b)
+}
+
+// WriteOp appends an instruction compiled from the given source line.
+func (c *Chunk) WriteOp(op OpCode, line uint) {
+	c.Write(byte(op), line)
+}
+
+// WriteShort appends a two-byte big-endian operand.
+func (c *Chunk) WriteShort(operand uint16, line uint) {
+	c.Write(byte(operand>>8), line)
+	c.Write(byte(operand), line)
+}
+
+// ReadShort decodes the two-byte operand stored at offset.
+func (c *Chunk) ReadShort(offset int) uint16 {
+	return uint16(c.Code[offset])<<8 | uint16(c.Code[offset+1])
+}
+
+// AddConstant adds value to the constant pool, reusing an existing entry
+// for an identical number or an equal string, and returns its index.
+// Numbers are compared by their bits, so that 0 and -0 get entries of
+// their own.
+func (c *Chunk) AddConstant(value Value) int {
+	switch value := value.(type) {
+	case float64:
+		for idx, constant := range c.Constants {
+			if number, ok := constant.(float64); ok && math.Float64bits(number) == math.Float64bits(value) {
+				return idx
+			}
+		}
+	case string:
+		for idx, constant := range c.Constants {
+			if constant == value {
+				return idx
+			}
+		}
+	}
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
+
+// Line returns the source line of the instruction at offset.
+func (c *Chunk) Line(offset int) uint {
+	idx := sort.Search(len(c.Lines), func(i int) bool {
+		return c.Lines[i].Offset > offset
+	})
+	if idx == 0 {
+		return 0
+	}
+	return c.Lines[idx-1].Line
+}
+
+// Function is a compiled unit of code. The top-level script is compiled
+// into a Function with an empty Name. UpvalueCount is the number of
+// variables of enclosing functions that the function uses.
+type Function struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	Chunk        *Chunk
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+	return "<fn " + f.Name + ">"
+}
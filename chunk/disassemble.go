@@ -0,0 +1,102 @@
+package chunk
+
+import (
+	"fmt"
+	"io"
+)
+
+// Disassemble writes a listing of every instruction in fn's chunk, followed
+// by the listings of any functions in its constant pool.
+func Disassemble(w io.Writer, fn *Function) {
+	fmt.Fprintf(w, "== %s ==\n", fn)
+	c := fn.Chunk
+	for offset := 0; offset < len(c.Code); {
+		offset = DisassembleInstruction(w, c, offset)
+	}
+	for _, constant := range c.Constants {
+		if nested, ok := constant.(*Function); ok {
+			fmt.Fprintln(w)
+			Disassemble(w, nested)
+		}
+	}
+}
+
+// DisassembleInstruction writes the instruction at offset with its source
+// line and operands, and returns the offset of the next instruction.
+func DisassembleInstruction(w io.Writer, c *Chunk, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
+	if line := c.Line(offset); offset > 0 && line == c.Line(offset-1) {
+		fmt.Fprint(w, "   | ")
+	} else {
+		fmt.Fprintf(w, "%4d ", line)
+	}
+
+	op := OpCode(c.Code[offset])
+	switch op {
+	case OpConstant, OpDefineGlobal, OpGetGlobal, OpSetGlobal, OpGetProperty, OpImport, OpImportFrom:
+		return constantInstruction(w, op, c, offset)
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall:
+		return byteInstruction(w, op, c, offset)
+	case OpList, OpMap:
+		return shortInstruction(w, op, c, offset)
+	case OpForIter, OpJump, OpJumpIfFalse, OpTry:
+		return jumpInstruction(w, op, 1, c, offset)
+	case OpLoop:
+		return jumpInstruction(w, op, -1, c, offset)
+	case OpClosure:
+		return closureInstruction(w, c, offset)
+	default:
+		fmt.Fprintln(w, op)
+		return offset + 1
+	}
+}
+
+func constantInstruction(w io.Writer, op OpCode, c *Chunk, offset int) int {
+	idx := c.ReadShort(offset + 1)
+	fmt.Fprintf(w, "%-16s %4d '%s'\n", op, idx, FormatValue(c.Constants[idx]))
+	return offset + 3
+}
+
+func byteInstruction(w io.Writer, op OpCode, c *Chunk, offset int) int {
+	fmt.Fprintf(w, "%-16s %4d\n", op, c.Code[offset+1])
+	return offset + 2
+}
+
+func shortInstruction(w io.Writer, op OpCode, c *Chunk, offset int) int {
+	fmt.Fprintf(w, "%-16s %4d\n", op, c.ReadShort(offset+1))
+	return offset + 3
+}
+
+// closureInstruction writes a closure's function followed by a line for
+// each variable it captures.
+func closureInstruction(w io.Writer, c *Chunk, offset int) int {
+	idx := c.ReadShort(offset + 1)
+	fmt.Fprintf(w, "%-16s %4d '%s'\n", OpClosure, idx, FormatValue(c.Constants[idx]))
+	offset += 3
+	fn, _ := c.Constants[idx].(*Function)
+	for count := 0; fn != nil && count < fn.UpvalueCount; count++ {
+		kind := "upvalue"
+		if c.Code[offset] == 1 {
+			kind = "local"
+		}
+		fmt.Fprintf(w, "%04d    |                     %s %d\n", offset, kind, c.Code[offset+1])
+		offset += 2
+	}
+	return offset
+}
+
+// jumpInstruction writes a jump with the offset it jumps to, sign being 1
+// for a forward jump and -1 for a backward one.
+func jumpInstruction(w io.Writer, op OpCode, sign int, c *Chunk, offset int) int {
+	target := offset + 3 + sign*int(c.ReadShort(offset+1))
+	fmt.Fprintf(w, "%-16s %4d -> %d\n", op, offset, target)
+	return offset + 3
+}
+
+// FormatValue formats a value the way Lox prints it.
+func FormatValue(value Value) string {
+	if value == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%v", value)
+}
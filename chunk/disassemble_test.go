@@ -0,0 +1,111 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	c := &Chunk{}
+	c.WriteOp(OpConstant, 1)
+	c.WriteShort(uint16(c.AddConstant(1.5)), 1)
+	c.WriteOp(OpDefineGlobal, 1)
+	c.WriteShort(uint16(c.AddConstant("x")), 1)
+	c.WriteOp(OpGetGlobal, 2)
+	c.WriteShort(uint16(c.AddConstant("x")), 2)
+	c.WriteOp(OpNegate, 2)
+	c.WriteOp(OpPrint, 2)
+	c.WriteOp(OpNil, 4)
+	c.WriteOp(OpReturn, 4)
+
+	var out strings.Builder
+	Disassemble(&out, &Function{Chunk: c})
+
+	expected := `== <script> ==
+0000    1 OP_CONSTANT         0 '1.5'
+0003    | OP_DEFINE_GLOBAL    1 'x'
+0006    2 OP_GET_GLOBAL       1 'x'
+0009    | OP_NEGATE
+0010    | OP_PRINT
+0011    4 OP_NIL
+0012    | OP_RETURN
+`
+	if out.String() != expected {
+		t.Fatalf("\nExpected:\n%s\n     Got:\n%s", expected, out.String())
+	}
+}
+
+func TestDisassembleJumps(t *testing.T) {
+	c := &Chunk{}
+	c.WriteOp(OpIterate, 1)
+	c.WriteOp(OpForIter, 1)
+	c.WriteShort(4, 1)
+	c.WriteOp(OpGetLocal, 2)
+	c.Write(2, 2)
+	c.WriteOp(OpPop, 2)
+	c.WriteOp(OpLoop, 2)
+	c.WriteShort(9, 2)
+
+	var out strings.Builder
+	Disassemble(&out, &Function{Chunk: c})
+
+	expected := `== <script> ==
+0000    1 OP_ITERATE
+0001    | OP_FOR_ITER         1 -> 8
+0004    2 OP_GET_LOCAL        2
+0006    | OP_POP
+0007    | OP_LOOP             7 -> 1
+`
+	if out.String() != expected {
+		t.Fatalf("\nExpected:\n%s\n     Got:\n%s", expected, out.String())
+	}
+}
+
+func TestDisassembleClosure(t *testing.T) {
+	inner := &Chunk{}
+	inner.WriteOp(OpGetUpvalue, 3)
+	inner.Write(1, 3)
+	inner.WriteOp(OpReturn, 3)
+	fn := &Function{Name: "lambda", UpvalueCount: 2, Chunk: inner}
+	c := &Chunk{}
+	c.WriteOp(OpClosure, 2)
+	c.WriteShort(uint16(c.AddConstant(fn)), 2)
+	c.Write(1, 2)
+	c.Write(3, 2)
+	c.Write(0, 2)
+	c.Write(0, 2)
+	c.WriteOp(OpCloseUpvalue, 4)
+
+	var out strings.Builder
+	Disassemble(&out, &Function{Chunk: c})
+
+	expected := `== <script> ==
+0000    2 OP_CLOSURE          0 '<fn lambda>'
+0003    |                     local 3
+0005    |                     upvalue 0
+0007    4 OP_CLOSE_UPVALUE
+
+== <fn lambda> ==
+0000    3 OP_GET_UPVALUE      1
+0002    | OP_RETURN
+`
+	if out.String() != expected {
+		t.Fatalf("\nExpected:\n%s\n     Got:\n%s", expected, out.String())
+	}
+}
+
+func TestLine(t *testing.T) {
+	c := &Chunk{}
+	lines := []uint{1, 1, 1, 3, 3, 7}
+	for _, line := range lines {
+		c.Write(0, line)
+	}
+	if len(c.Lines) != 3 {
+		t.Fatalf("Expected 3 line table entries, got %d", len(c.Lines))
+	}
+	for offset, line := range lines {
+		if got := c.Line(offset); got != line {
+			t.Fatalf("Offset %d: expected line %d, got %d", offset, line, got)
+		}
+	}
+}
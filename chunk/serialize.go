@@ -0,0 +1,249 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// FormatVersion is the version of the .loxc file format written by Encode.
+// It must be bumped whenever the encoding or the instruction set changes,
+// since files compiled for one instruction set cannot run on another.
+const FormatVersion uint16 = 12
+
+// magic identifies a .loxc file.
+var magic = [4]byte{'L', 'O', 'X', 'C'}
+
+// ErrNotBytecode is returned by Decode for input that is not a .loxc file.
+var ErrNotBytecode = errors.New("not a glox bytecode file")
+
+// ErrChecksum is returned by Decode when the payload has been corrupted.
+var ErrChecksum = errors.New("bytecode checksum mismatch")
+
+// Value tags in the encoded constant pool.
+const (
+	tagNil byte = iota
+	tagFalse
+	tagTrue
+	tagNumber
+	tagString
+	tagFunction
+)
+
+// Encode writes fn, including its constants, nested functions and line
+// tables, in the .loxc format:
+//
+//	magic    [4]byte "LOXC"
+//	version  uint16
+//	length   uint32  payload length
+//	checksum uint32  CRC-32 (IEEE) of the payload
+//	payload  the encoded function
+//
+// Integers in the header are big-endian; those in the payload are uvarints.
+func Encode(w io.Writer, fn *Function) error {
+	var payload bytes.Buffer
+	if encodeErr := encodeFunction(&payload, fn); encodeErr != nil {
+		return encodeErr
+	}
+
+	var header [14]byte
+	copy(header[0:4], magic[:])
+	binary.BigEndian.PutUint16(header[4:6], FormatVersion)
+	binary.BigEndian.PutUint32(header[6:10], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(header[10:14], crc32.ChecksumIEEE(payload.Bytes()))
+	if _, writeErr := w.Write(header[:]); writeErr != nil {
+		return writeErr
+	}
+	_, writeErr := w.Write(payload.Bytes())
+	return writeErr
+}
+
+// Decode reads a function written by Encode. It rejects files written for
+// a different FormatVersion and files whose checksum does not match.
+func Decode(r io.Reader) (*Function, error) {
+	var header [14]byte
+	if _, readErr := io.ReadFull(r, header[:]); readErr != nil {
+		return nil, ErrNotBytecode
+	}
+	if !bytes.Equal(header[0:4], magic[:]) {
+		return nil, ErrNotBytecode
+	}
+	if version := binary.BigEndian.Uint16(header[4:6]); version != FormatVersion {
+		return nil, fmt.Errorf("unsupported bytecode format version %d (expected %d); recompile the script", version, FormatVersion)
+	}
+	length := int64(binary.BigEndian.Uint32(header[6:10]))
+	payload, readErr := io.ReadAll(io.LimitReader(r, length))
+	if readErr != nil {
+		return nil, readErr
+	}
+	if int64(len(payload)) != length {
+		return nil, errors.New("truncated bytecode file")
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[10:14]) {
+		return nil, ErrChecksum
+	}
+
+	fn, decodeErr := decodeFunction(bytes.NewReader(payload))
+	if decodeErr != nil {
+		return nil, fmt.Errorf("malformed bytecode: %w", decodeErr)
+	}
+	return fn, nil
+}
+
+func encodeFunction(w *bytes.Buffer, fn *Function) error {
+	writeString(w, fn.Name)
+	writeUvarint(w, uint64(fn.Arity))
+	writeUvarint(w, uint64(fn.UpvalueCount))
+
+	c := fn.Chunk
+	writeUvarint(w, uint64(len(c.Code)))
+	w.Write(c.Code)
+
+	writeUvarint(w, uint64(len(c.Constants)))
+	for _, constant := range c.Constants {
+		switch value := constant.(type) {
+		case nil:
+			w.WriteByte(tagNil)
+		case bool:
+			if value {
+				w.WriteByte(tagTrue)
+			} else {
+				w.WriteByte(tagFalse)
+			}
+		case float64:
+			w.WriteByte(tagNumber)
+			var buf [8]byte
+			binary.BigEndian.PutUint64(buf[:], math.Float64bits(value))
+			w.Write(buf[:])
+		case string:
+			w.WriteByte(tagString)
+			writeString(w, value)
+		case *Function:
+			w.WriteByte(tagFunction)
+			if encodeErr := encodeFunction(w, value); encodeErr != nil {
+				return encodeErr
+			}
+		default:
+			return fmt.Errorf("cannot encode constant of type %T", constant)
+		}
+	}
+
+	writeUvarint(w, uint64(len(c.Lines)))
+	for _, start := range c.Lines {
+		writeUvarint(w, uint64(start.Offset))
+		writeUvarint(w, uint64(start.Line))
+	}
+	return nil
+}
+
+func decodeFunction(r *bytes.Reader) (*Function, error) {
+	name, readErr := readString(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+	arity, readErr := binary.ReadUvarint(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+	upvalueCount, readErr := binary.ReadUvarint(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	c := &Chunk{}
+	codeLen, readErr := binary.ReadUvarint(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+	if codeLen > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	c.Code = make([]byte, codeLen)
+	if _, readErr := io.ReadFull(r, c.Code); readErr != nil {
+		return nil, readErr
+	}
+
+	constantCount, readErr := binary.ReadUvarint(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+	for idx := uint64(0); idx < constantCount; idx++ {
+		constant, readErr := decodeConstant(r)
+		if readErr != nil {
+			return nil, readErr
+		}
+		c.Constants = append(c.Constants, constant)
+	}
+
+	lineCount, readErr := binary.ReadUvarint(r)
+	if readErr != nil {
+		return nil, readErr
+	}
+	for idx := uint64(0); idx < lineCount; idx++ {
+		offset, readErr := binary.ReadUvarint(r)
+		if readErr != nil {
+			return nil, readErr
+		}
+		line, readErr := binary.ReadUvarint(r)
+		if readErr != nil {
+			return nil, readErr
+		}
+		c.Lines = append(c.Lines, LineStart{Offset: int(offset), Line: uint(line)})
+	}
+	return &Function{Name: name, Arity: int(arity), UpvalueCount: int(upvalueCount), Chunk: c}, nil
+}
+
+func decodeConstant(r *bytes.Reader) (Value, error) {
+	tag, readErr := r.ReadByte()
+	if readErr != nil {
+		return nil, readErr
+	}
+	switch tag {
+	case tagNil:
+		return nil, nil
+	case tagFalse:
+		return false, nil
+	case tagTrue:
+		return true, nil
+	case tagNumber:
+		var buf [8]byte
+		if _, readErr := io.ReadFull(r, buf[:]); readErr != nil {
+			return nil, readErr
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(buf[:])), nil
+	case tagString:
+		return readString(r)
+	case tagFunction:
+		return decodeFunction(r)
+	}
+	return nil, fmt.Errorf("unknown constant tag %d", tag)
+}
+
+func writeUvarint(w *bytes.Buffer, n uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], n)])
+}
+
+func writeString(w *bytes.Buffer, s string) {
+	writeUvarint(w, uint64(len(s)))
+	w.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	length, readErr := binary.ReadUvarint(r)
+	if readErr != nil {
+		return "", readErr
+	}
+	if length > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, length)
+	if _, readErr := io.ReadFull(r, buf); readErr != nil {
+		return "", readErr
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,74 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testFunction() *Function {
+	c := &Chunk{}
+	c.WriteOp(OpConstant, 1)
+	c.WriteShort(uint16(c.AddConstant(-2.25)), 1)
+	c.WriteOp(OpConstant, 2)
+	c.WriteShort(uint16(c.AddConstant("héllo")), 2)
+	c.WriteOp(OpNil, 3)
+	c.WriteOp(OpReturn, 3)
+	inner := &Chunk{}
+	inner.WriteOp(OpNil, 5)
+	inner.WriteOp(OpReturn, 5)
+	c.Constants = append(c.Constants, nil, true, false, &Function{Name: "inner", Arity: 2, UpvalueCount: 1, Chunk: inner})
+	return &Function{Chunk: c}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	fn := testFunction()
+	var buf bytes.Buffer
+	if encodeErr := Encode(&buf, fn); encodeErr != nil {
+		t.Fatalf("Error encoding: %s", encodeErr)
+	}
+	decoded, decodeErr := Decode(&buf)
+	if decodeErr != nil {
+		t.Fatalf("Error decoding: %s", decodeErr)
+	}
+	if !reflect.DeepEqual(fn, decoded) {
+		t.Fatalf("\nExpected: %+v\n     Got: %+v", fn, decoded)
+	}
+}
+
+func TestDecodeRejectsBadInput(t *testing.T) {
+	var buf bytes.Buffer
+	Encode(&buf, testFunction())
+	valid := buf.Bytes()
+
+	corrupt := func(mutate func(b []byte) []byte) []byte {
+		b := append([]byte(nil), valid...)
+		return mutate(b)
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		err   string
+	}{
+		{"Empty", nil, "not a glox bytecode file"},
+		{"Bad magic", corrupt(func(b []byte) []byte { b[0] = 'X'; return b }), "not a glox bytecode file"},
+		{"Other version", corrupt(func(b []byte) []byte {
+			binary.BigEndian.PutUint16(b[4:6], FormatVersion+1)
+			return b
+		}), "unsupported bytecode format version"},
+		{"Corrupt payload", corrupt(func(b []byte) []byte { b[len(b)-1] ^= 0xff; return b }), "checksum mismatch"},
+		{"Truncated", corrupt(func(b []byte) []byte { return b[:len(b)-3] }), "truncated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, decodeErr := Decode(bytes.NewReader(tt.input))
+			if decodeErr == nil || !strings.Contains(decodeErr.Error(), tt.err) {
+				t.Fatalf("Expected error containing %q, got %v", tt.err, decodeErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+// Command generate_ast writes the syntax tree types of package ast from the
+// declarative node spec in ast/nodes.spec. It is run by `go generate ./ast`.
+//
+// Usage:
+//
+//	generate_ast [-spec nodes.spec] [-out dir]
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+type field struct {
+	Name string
+	Type string
+}
+
+type node struct {
+	Base   string
+	Name   string
+	Doc    []string
+	Fields []field
+}
+
+// base describes one family of nodes and the file they are written to.
+type base struct {
+	Name     string
+	File     string
+	Doc      []string
+	Receiver string
+	// Suffix is appended to a node's name to form its visit method, so that
+	// Binary is visited by VisitBinaryExpr.
+	Suffix string
+	Nodes  []node
+}
+
+var bases = []*base{
+	{
+		Name: "Expr",
+		File: "expr.go",
+		Doc: []string{
+			"Expr is an interface representing an expression in the Lox language.",
+			"Expressions are visited with AcceptExpr and an ExprVisitor.",
+			"Every expression records the source line it starts on in its Line field.",
+		},
+		Receiver: "expr",
+		Suffix:   "Expr",
+	},
+	{
+		Name: "Stmt",
+		File: "stmt.go",
+		Doc: []string{
+			"Stmt is the interface for all statement types in the AST.",
+			"Statements are visited with AcceptStmt and a StmtVisitor.",
+			"Every statement records the source line it starts on in its Line field.",
+		},
+		Receiver: "stmt",
+		Suffix:   "",
+	},
+}
+
+const fileTemplate = `// Code generated by generate_ast from nodes.spec. DO NOT EDIT.
+
+package ast
+
+import (
+	"fmt"
+{{if usesToken .}}
+	"github.com/nicholasq/glox/token"
+{{- end}}
+)
+
+{{range .Doc}}// {{.}}
+{{end}}type {{.Name}} interface {
+	Node
+	{{lower .Name}}Node()
+}
+
+// {{.Name}}Visitor has a method for each kind of {{.Name}}, returning a result of type R.
+type {{.Name}}Visitor[R any] interface {
+{{- $b := .}}
+{{range .Nodes}}	Visit{{.Name}}{{$b.Suffix}}({{$b.Receiver}} *{{.Name}}) R
+{{end -}}
+}
+
+// Accept{{.Name}} calls the method of v that matches the type of {{.Receiver}} and returns its result.
+func Accept{{.Name}}[R any]({{.Receiver}} {{.Name}}, v {{.Name}}Visitor[R]) R {
+	switch n := {{.Receiver}}.(type) {
+{{range .Nodes}}	case *{{.Name}}:
+		return v.Visit{{.Name}}{{$b.Suffix}}(n)
+{{end -}}
+	}
+	panic(fmt.Sprintf("ast: unexpected {{.Name}} type %T", {{.Receiver}}))
+}
+{{range .Nodes}}
+{{range .Doc}}// {{.}}
+{{end}}type {{.Name}} struct {
+{{range .Fields}}	{{.Name}} {{.Type}}
+{{end}}	Line uint
+}
+
+func (*{{.Name}}) {{lower $b.Name}}Node() {}
+
+func (n *{{.Name}}) Pos() uint { return n.Line }
+
+func (n *{{.Name}}) End() uint {
+	end := n.Line
+{{- range .Fields}}
+{{- if eq (fieldKind .Type) "node"}}
+	end = max(end, endOf(n.{{.Name}}))
+{{- else if eq (fieldKind .Type) "token"}}
+	end = max(end, n.{{.Name}}.Line)
+{{- else if eq (fieldKind .Type) "nodes"}}
+	for _, child := range n.{{.Name}} {
+		end = max(end, endOf(child))
+	}
+{{- else if eq (fieldKind .Type) "tokens"}}
+	for _, tok := range n.{{.Name}} {
+		end = max(end, tok.Line)
+	}
+{{- end}}
+{{- end}}
+	return end
+}
+
+func (n *{{.Name}}) eachChild(f func(Node)) {
+{{- range .Fields}}
+{{- if eq (fieldKind .Type) "node"}}
+	if n.{{.Name}} != nil {
+		f(n.{{.Name}})
+	}
+{{- else if eq (fieldKind .Type) "nodes"}}
+	for _, child := range n.{{.Name}} {
+		f(child)
+	}
+{{- end}}
+{{- end}}
+}
+
+func (n *{{.Name}}) rewrite(f func(Node) Node) Node {
+	rewritten := *n
+{{- range .Fields}}
+{{- if eq (fieldKind .Type) "node" "nodes"}}
+	rewritten.{{.Name}} = rewrite{{title .Type}}(n.{{.Name}}, f)
+{{- end}}
+{{- end}}
+	return &rewritten
+}
+{{end}}
+// new{{.Name}} returns an empty {{.Name}} of the named kind, or nil if there is no such kind.
+func new{{.Name}}(kind string) {{.Name}} {
+	switch kind {
+{{range .Nodes}}	case "{{.Name}}":
+		return &{{.Name}}{}
+{{end -}}
+	}
+	return nil
+}
+
+`
+
+var tmpl = template.Must(template.New("file").Funcs(template.FuncMap{
+	"usesToken": func(b *base) bool {
+		for _, n := range b.Nodes {
+			for _, f := range n.Fields {
+				if strings.Contains(f.Type, "token.") {
+					return true
+				}
+			}
+		}
+		return false
+	},
+	"lower":     strings.ToLower,
+	"fieldKind": fieldKind,
+	// title turns a child field type into the suffix of its rewrite
+	// helper: Expr, Stmt, Exprs or Stmts.
+	"title": func(typ string) string {
+		if elem, ok := strings.CutPrefix(typ, "[]"); ok {
+			return elem + "s"
+		}
+		return typ
+	},
+}).Parse(fileTemplate))
+
+func main() {
+	specPath := flag.String("spec", "nodes.spec", "node spec to read")
+	outDir := flag.String("out", ".", "directory to write the generated files to")
+	flag.Parse()
+
+	specFile, openErr := os.Open(*specPath)
+	if openErr != nil {
+		log.Fatal(openErr)
+	}
+	defer specFile.Close()
+
+	files, genErr := generate(specFile)
+	if genErr != nil {
+		log.Fatalf("%s: %s", *specPath, genErr)
+	}
+	for name, src := range files {
+		if writeErr := os.WriteFile(filepath.Join(*outDir, name), src, 0o644); writeErr != nil {
+			log.Fatal(writeErr)
+		}
+	}
+}
+
+// generate parses a spec and returns the contents of each generated file,
+// keyed by file name.
+func generate(spec io.Reader) (map[string][]byte, error) {
+	nodes, parseErr := parseSpec(spec)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	files := make(map[string][]byte)
+	for _, b := range bases {
+		fileBase := *b
+		for _, n := range nodes {
+			if n.Base == b.Name {
+				fileBase.Nodes = append(fileBase.Nodes, n)
+			}
+		}
+		var buf bytes.Buffer
+		if execErr := tmpl.Execute(&buf, &fileBase); execErr != nil {
+			return nil, execErr
+		}
+		src, fmtErr := format.Source(buf.Bytes())
+		if fmtErr != nil {
+			return nil, fmt.Errorf("formatting %s: %w", b.File, fmtErr)
+		}
+		files[b.File] = src
+	}
+	return files, nil
+}
+
+func parseSpec(r io.Reader) ([]node, error) {
+	var nodes []node
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if trimmed == line {
+			words := strings.Fields(line)
+			if len(words) != 2 || !isBase(words[0]) {
+				return nil, fmt.Errorf("line %d: expected \"Expr <Name>\" or \"Stmt <Name>\"", lineNo)
+			}
+			nodes = append(nodes, node{Base: words[0], Name: words[1]})
+			continue
+		}
+
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("line %d: indented line outside of a node", lineNo)
+		}
+		current := &nodes[len(nodes)-1]
+		if doc, ok := strings.CutPrefix(trimmed, "doc "); ok {
+			current.Doc = append(current.Doc, doc)
+			continue
+		}
+		name, typ, found := strings.Cut(trimmed, " ")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected \"<Field> <Type>\"", lineNo)
+		}
+		current.Fields = append(current.Fields, field{Name: name, Type: strings.TrimSpace(typ)})
+	}
+	return nodes, scanner.Err()
+}
+
+// fieldKind classifies a field type for the walking code: "node" for a
+// child Expr or Stmt, "nodes" for a slice of them, "token" and "tokens" for
+// tokens, and "" for anything else.
+func fieldKind(typ string) string {
+	elem, slice := strings.CutPrefix(typ, "[]")
+	kind := ""
+	switch {
+	case isBase(elem):
+		kind = "node"
+	case elem == "token.Token":
+		kind = "token"
+	default:
+		return ""
+	}
+	if slice {
+		kind += "s"
+	}
+	return kind
+}
+
+func isBase(name string) bool {
+	for _, b := range bases {
+		if b.Name == name {
+			return true
+		}
+	}
+	return false
+}
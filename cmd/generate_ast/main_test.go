@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGeneratedFilesUpToDate fails when ast/nodes.spec or the generator has
+// changed without the generated files being regenerated.
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	astDir := filepath.Join("..", "..", "ast")
+	spec, openErr := os.Open(filepath.Join(astDir, "nodes.spec"))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer spec.Close()
+
+	files, genErr := generate(spec)
+	if genErr != nil {
+		t.Fatalf("Error generating: %s", genErr)
+	}
+	for name, expected := range files {
+		actual, readErr := os.ReadFile(filepath.Join(astDir, name))
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+		if !bytes.Equal(expected, actual) {
+			t.Errorf("ast/%s is stale; run `go generate ./ast`", name)
+		}
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		err  string
+	}{
+		{"Unknown base", "Decl Fun\n", "line 1: expected"},
+		{"Field outside node", "    Name token.Token\n", "line 1: indented line outside of a node"},
+		{"Field without type", "Expr Call\n    Callee\n", "line 2: expected \"<Field> <Type>\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, parseErr := parseSpec(strings.NewReader(tt.spec))
+			if parseErr == nil || !strings.Contains(parseErr.Error(), tt.err) {
+				t.Fatalf("Expected error containing %q, got %v", tt.err, parseErr)
+			}
+		})
+	}
+}
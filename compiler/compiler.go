@@ -0,0 +1,824 @@
+// Package compiler lowers the AST into bytecode for the vm backend.
+package compiler
+
+import (
+	"errors"
+	"math"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/chunk"
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/token"
+)
+
+// ErrCompile is returned when the program could not be compiled. The
+// errors themselves are reported through the error package.
+var ErrCompile = errors.New("compile error")
+
+type Compiler struct {
+	function *chunk.Function
+	// enclosing is the compiler of the function the one being compiled
+	// appears in, or nil for the top-level script.
+	enclosing *Compiler
+	// line is the source line of the statement being compiled, used for
+	// expressions that carry no token of their own.
+	line uint
+	// locals are the local variables in scope, in the order of their stack
+	// slots. Slot 0 holds the function being run and has no name.
+	locals []local
+	// scopeDepth is the number of blocks enclosing the code being compiled;
+	// variables declared at depth 0 are globals.
+	scopeDepth int
+	// loops are the loops enclosing the code being compiled, innermost last.
+	loops []*loop
+	// tries are the try statements whose handlers are active around the
+	// code being compiled, innermost last.
+	tries []*try
+	// upvalues are the variables of enclosing functions that the function
+	// uses, in the order of their indexes.
+	upvalues []upvalue
+}
+
+// try is a try statement whose body, or catch clause if it has a finally
+// clause, is being compiled. break and continue statements that leave it
+// remove its handler and run its finally clause, if any. loops is the
+// number of loops enclosing the statement.
+type try struct {
+	finally ast.Stmt
+	loops   int
+}
+
+// loop is a loop being compiled. Its break and continue statements pop the
+// locals declared deeper than breakDepth or continueDepth and jump forward
+// to the end of the loop or of its body.
+type loop struct {
+	breakDepth    int
+	continueDepth int
+	breaks        []int
+	continues     []int
+}
+
+// local is a local variable. depth is the scopeDepth of the block that
+// declared it, or -1 while its initializer is being compiled. captured is
+// set once a function nested in the scope uses it, so that the variable
+// outlives the scope.
+type local struct {
+	name     string
+	depth    int
+	captured bool
+}
+
+// upvalue is a variable of an enclosing function: the slot of a local of
+// the directly enclosing function if isLocal is set, and otherwise the
+// index of one of its upvalues.
+type upvalue struct {
+	index   byte
+	isLocal bool
+}
+
+// maxLocals is the number of stack slots an OpGetLocal operand can address,
+// and maxUpvalues the number of upvalues an OpGetUpvalue operand can.
+const (
+	maxLocals   = math.MaxUint8 + 1
+	maxUpvalues = math.MaxUint8 + 1
+)
+
+// Compiler emits code as it visits nodes, so its visitors produce no
+// result of their own.
+var (
+	_ ast.ExprVisitor[struct{}] = (*Compiler)(nil)
+	_ ast.StmtVisitor[struct{}] = (*Compiler)(nil)
+)
+
+// Compile compiles a program into the function that runs its top-level code.
+func Compile(stmts []ast.Stmt) (fn *chunk.Function, result error) {
+	c := &Compiler{function: &chunk.Function{Chunk: &chunk.Chunk{}}, locals: []local{{}}}
+	defer func() {
+		if r := recover(); r != nil {
+			if r != ErrCompile {
+				panic(r)
+			}
+			fn, result = nil, ErrCompile
+		}
+	}()
+	for _, stmt := range stmts {
+		c.compileStmt(stmt)
+	}
+	c.emitOp(chunk.OpNil)
+	c.emitOp(chunk.OpReturn)
+	return c.function, nil
+}
+
+func (c *Compiler) VisitAssignExpr(expr *ast.Assign) struct{} {
+	c.compileExpr(expr.Value)
+	c.line = expr.Name.Line
+	if slot, ok := c.resolveLocal(expr.Name.Lexeme); ok {
+		c.emitOp(chunk.OpSetLocal)
+		c.emitByte(byte(slot))
+		return struct{}{}
+	}
+	if idx, ok := c.resolveUpvalue(expr.Name.Lexeme); ok {
+		c.emitOp(chunk.OpSetUpvalue)
+		c.emitByte(byte(idx))
+		return struct{}{}
+	}
+	c.emitOp(chunk.OpSetGlobal)
+	c.emitShort(c.makeConstant(expr.Name.Lexeme))
+	return struct{}{}
+}
+
+func (c *Compiler) VisitBinaryExpr(expr *ast.Binary) struct{} {
+	if expr.Operator.TokenType == token.COMMA {
+		c.compileExpr(expr.Left)
+		c.line = expr.Operator.Line
+		c.emitOp(chunk.OpPop)
+		c.compileExpr(expr.Right)
+		return struct{}{}
+	}
+	c.compileExpr(expr.Left)
+	c.compileExpr(expr.Right)
+	c.line = expr.Operator.Line
+
+	switch expr.Operator.TokenType {
+	case token.MINUS:
+		c.emitOp(chunk.OpSubtract)
+	case token.SLASH:
+		c.emitOp(chunk.OpDivide)
+	case token.STAR:
+		c.emitOp(chunk.OpMultiply)
+	case token.PERCENT:
+		c.emitOp(chunk.OpModulo)
+	case token.TILDE_SLASH:
+		c.emitOp(chunk.OpFloorDivide)
+	case token.STAR_STAR:
+		c.emitOp(chunk.OpPower)
+	case token.AMPERSAND:
+		c.emitOp(chunk.OpBitAnd)
+	case token.PIPE:
+		c.emitOp(chunk.OpBitOr)
+	case token.CARET:
+		c.emitOp(chunk.OpBitXor)
+	case token.LESS_LESS:
+		c.emitOp(chunk.OpShiftLeft)
+	case token.GREATER_GREATER:
+		c.emitOp(chunk.OpShiftRight)
+	case token.PLUS:
+		c.emitOp(chunk.OpAdd)
+	case token.GREATER:
+		c.emitOp(chunk.OpGreater)
+	case token.GREATER_EQUAL:
+		c.emitOp(chunk.OpGreaterEqual)
+	case token.LESS:
+		c.emitOp(chunk.OpLess)
+	case token.LESS_EQUAL:
+		c.emitOp(chunk.OpLessEqual)
+	case token.BANG_EQUAL:
+		c.emitOp(chunk.OpEqual)
+		c.emitOp(chunk.OpNot)
+	case token.EQUAL_EQUAL:
+		c.emitOp(chunk.OpEqual)
+	}
+	return struct{}{}
+}
+
+func (c *Compiler) VisitCallExpr(expr *ast.Call) struct{} {
+	c.compileExpr(expr.Callee)
+	for _, arg := range expr.Arguments {
+		c.compileExpr(arg)
+	}
+	c.line = expr.Paren.Line
+	if len(expr.Arguments) > math.MaxUint8 {
+		c.error("Can't have more than 255 arguments.")
+	}
+	c.emitOp(chunk.OpCall)
+	c.emitByte(byte(len(expr.Arguments)))
+	return struct{}{}
+}
+
+func (c *Compiler) VisitConditionalExpr(expr *ast.Conditional) struct{} {
+	c.compileExpr(expr.Condition)
+	c.line = expr.Line
+	elseJump := c.emitJump(chunk.OpJumpIfFalse)
+	c.emitOp(chunk.OpPop)
+	c.compileExpr(expr.ThenBranch)
+	endJump := c.emitJump(chunk.OpJump)
+	c.patchJump(elseJump)
+	c.emitOp(chunk.OpPop)
+	c.compileExpr(expr.ElseBranch)
+	c.patchJump(endJump)
+	return struct{}{}
+}
+
+func (c *Compiler) VisitGetExpr(expr *ast.Get) struct{} {
+	c.compileExpr(expr.Object)
+	c.line = expr.Name.Line
+	c.emitOp(chunk.OpGetProperty)
+	c.emitShort(c.makeConstant(expr.Name.Lexeme))
+	return struct{}{}
+}
+
+func (c *Compiler) VisitGroupingExpr(expr *ast.Grouping) struct{} {
+	c.compileExpr(expr.Expression)
+	return struct{}{}
+}
+
+func (c *Compiler) VisitIndexExpr(expr *ast.Index) struct{} {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.line = expr.Bracket.Line
+	c.emitOp(chunk.OpIndex)
+	return struct{}{}
+}
+
+func (c *Compiler) VisitIndexSetExpr(expr *ast.IndexSet) struct{} {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.compileExpr(expr.Value)
+	c.line = expr.Bracket.Line
+	c.emitOp(chunk.OpSetIndex)
+	return struct{}{}
+}
+
+// VisitLambdaExpr compiles the function with a compiler of its own, in
+// which slot 0 holds the closure being run and the parameters come next,
+// and emits an OpClosure that captures the variables it uses.
+func (c *Compiler) VisitLambdaExpr(expr *ast.Lambda) struct{} {
+	fn := &chunk.Function{Name: "lambda", Arity: len(expr.Params), Chunk: &chunk.Chunk{}}
+	inner := &Compiler{function: fn, enclosing: c, line: expr.Line, locals: []local{{}}, scopeDepth: 1}
+	for _, param := range expr.Params {
+		inner.line = param.Line
+		inner.declareLocal(param.Lexeme)
+		inner.defineLocal()
+	}
+	for _, stmt := range expr.Body {
+		inner.compileStmt(stmt)
+	}
+	inner.emitOp(chunk.OpNil)
+	inner.emitOp(chunk.OpReturn)
+	fn.UpvalueCount = len(inner.upvalues)
+
+	c.line = expr.Line
+	c.emitOp(chunk.OpClosure)
+	c.emitShort(c.makeConstant(fn))
+	for _, up := range inner.upvalues {
+		isLocal := byte(0)
+		if up.isLocal {
+			isLocal = 1
+		}
+		c.emitByte(isLocal)
+		c.emitByte(up.index)
+	}
+	return struct{}{}
+}
+
+func (c *Compiler) VisitListLiteralExpr(expr *ast.ListLiteral) struct{} {
+	for _, element := range expr.Elements {
+		c.compileExpr(element)
+	}
+	c.line = expr.Line
+	if len(expr.Elements) > math.MaxUint16 {
+		c.error("Too many elements in list literal.")
+	}
+	c.emitOp(chunk.OpList)
+	c.emitShort(uint16(len(expr.Elements)))
+	return struct{}{}
+}
+
+func (c *Compiler) VisitLiteralExpr(expr *ast.Literal) struct{} {
+	switch expr.Value {
+	case nil:
+		c.emitOp(chunk.OpNil)
+	case true:
+		c.emitOp(chunk.OpTrue)
+	case false:
+		c.emitOp(chunk.OpFalse)
+	default:
+		c.emitConstant(expr.Value)
+	}
+	return struct{}{}
+}
+
+func (c *Compiler) VisitMapLiteralExpr(expr *ast.MapLiteral) struct{} {
+	for idx, key := range expr.Keys {
+		c.compileExpr(key)
+		c.compileExpr(expr.Values[idx])
+	}
+	c.line = expr.Brace.Line
+	if len(expr.Keys) > math.MaxUint16 {
+		c.error("Too many entries in map literal.")
+	}
+	c.emitOp(chunk.OpMap)
+	c.emitShort(uint16(len(expr.Keys)))
+	return struct{}{}
+}
+
+func (c *Compiler) VisitSliceExpr(expr *ast.Slice) struct{} {
+	c.compileExpr(expr.Object)
+	for _, bound := range []ast.Expr{expr.Low, expr.High} {
+		if bound != nil {
+			c.compileExpr(bound)
+		} else {
+			c.emitOp(chunk.OpNil)
+		}
+	}
+	c.line = expr.Bracket.Line
+	c.emitOp(chunk.OpSlice)
+	return struct{}{}
+}
+
+func (c *Compiler) VisitUnaryExpr(expr *ast.Unary) struct{} {
+	c.compileExpr(expr.Right)
+	c.line = expr.Operator.Line
+
+	switch expr.Operator.TokenType {
+	case token.MINUS:
+		c.emitOp(chunk.OpNegate)
+	case token.TILDE:
+		c.emitOp(chunk.OpBitNot)
+	case token.BANG:
+		c.emitOp(chunk.OpNot)
+	}
+	return struct{}{}
+}
+
+func (c *Compiler) VisitVariableExpr(expr *ast.Variable) struct{} {
+	c.line = expr.Name.Line
+	if slot, ok := c.resolveLocal(expr.Name.Lexeme); ok {
+		c.emitOp(chunk.OpGetLocal)
+		c.emitByte(byte(slot))
+		return struct{}{}
+	}
+	if idx, ok := c.resolveUpvalue(expr.Name.Lexeme); ok {
+		c.emitOp(chunk.OpGetUpvalue)
+		c.emitByte(byte(idx))
+		return struct{}{}
+	}
+	c.emitOp(chunk.OpGetGlobal)
+	c.emitShort(c.makeConstant(expr.Name.Lexeme))
+	return struct{}{}
+}
+
+func (c *Compiler) VisitBlockStmt(stmt *ast.BlockStmt) struct{} {
+	c.beginScope()
+	for _, inner := range stmt.Statements {
+		c.compileStmt(inner)
+	}
+	c.endScope()
+	return struct{}{}
+}
+
+// VisitForInStmt keeps the loop's iterator in a local slot with no name,
+// below a scope holding the loop variable that is renewed each iteration.
+func (c *Compiler) VisitForInStmt(stmt *ast.ForInStmt) struct{} {
+	c.beginScope()
+	c.compileExpr(stmt.Iterable)
+	c.line = stmt.In.Line
+	c.emitOp(chunk.OpIterate)
+	c.hiddenLocal()
+
+	loopStart := len(c.chunk().Code)
+	exitJump := c.emitJump(chunk.OpForIter)
+	c.beginScope()
+	c.line = stmt.Name.Line
+	c.declareLocal(stmt.Name.Lexeme)
+	c.defineLocal()
+	l := c.beginLoop(c.scopeDepth-1, c.scopeDepth)
+	c.compileStmt(stmt.Body)
+	c.patchJumps(l.continues)
+	c.endScope()
+	c.emitLoop(loopStart)
+	c.patchJump(exitJump)
+	c.endLoop()
+	c.endScope()
+	return struct{}{}
+}
+
+func (c *Compiler) VisitWhileStmt(stmt *ast.WhileStmt) struct{} {
+	loopStart := len(c.chunk().Code)
+	c.compileExpr(stmt.Condition)
+	c.line = stmt.Line
+	exitJump := c.emitJump(chunk.OpJumpIfFalse)
+	c.emitOp(chunk.OpPop)
+	l := c.beginLoop(c.scopeDepth, c.scopeDepth)
+	c.compileStmt(stmt.Body)
+	c.patchJumps(l.continues)
+	if stmt.Increment != nil {
+		c.compileExpr(stmt.Increment)
+		c.emitOp(chunk.OpPop)
+	}
+	c.emitLoop(loopStart)
+	c.patchJump(exitJump)
+	c.emitOp(chunk.OpPop)
+	c.endLoop()
+	return struct{}{}
+}
+
+func (c *Compiler) VisitIfStmt(stmt *ast.IfStmt) struct{} {
+	c.compileExpr(stmt.Condition)
+	c.line = stmt.Line
+	elseJump := c.emitJump(chunk.OpJumpIfFalse)
+	c.emitOp(chunk.OpPop)
+	c.compileStmt(stmt.ThenBranch)
+	endJump := c.emitJump(chunk.OpJump)
+	c.patchJump(elseJump)
+	c.emitOp(chunk.OpPop)
+	if stmt.ElseBranch != nil {
+		c.compileStmt(stmt.ElseBranch)
+	}
+	c.patchJump(endJump)
+	return struct{}{}
+}
+
+// VisitTryStmt compiles the finally clause, if there is one, once for each
+// way out of the statement: after the body or catch clause completes, and
+// before an exception they raise is raised again.
+func (c *Compiler) VisitTryStmt(stmt *ast.TryStmt) struct{} {
+	t := &try{finally: stmt.Finally, loops: len(c.loops)}
+	c.line = stmt.Line
+	handlerJump := c.emitJump(chunk.OpTry)
+	c.tries = append(c.tries, t)
+	c.compileStmt(stmt.Body)
+	c.tries = c.tries[:len(c.tries)-1]
+	c.emitOp(chunk.OpEndTry)
+	c.compileFinally(stmt.Finally)
+	endJumps := []int{c.emitJump(chunk.OpJump)}
+
+	// The handler starts with the exception on top of the stack.
+	c.patchJump(handlerJump)
+	c.beginScope()
+	if stmt.Catch != nil {
+		c.line = stmt.CatchName.Line
+		c.emitOp(chunk.OpCatch)
+		c.declareLocal(stmt.CatchName.Lexeme)
+		c.defineLocal()
+		if stmt.Finally == nil {
+			c.compileStmt(stmt.Catch)
+			c.endScope()
+			c.patchJumps(endJumps)
+			return struct{}{}
+		}
+		rethrowJump := c.emitJump(chunk.OpTry)
+		c.tries = append(c.tries, t)
+		c.compileStmt(stmt.Catch)
+		c.tries = c.tries[:len(c.tries)-1]
+		c.emitOp(chunk.OpEndTry)
+		c.endScope()
+		c.compileFinally(stmt.Finally)
+		endJumps = append(endJumps, c.emitJump(chunk.OpJump))
+		// An exception raised in the catch clause lands above the catch
+		// variable, which the finally clause must not see.
+		c.patchJump(rethrowJump)
+		c.beginScope()
+		c.hiddenLocal()
+	}
+	// Keep the exception in a slot of its own while the finally clause
+	// runs, then raise it again.
+	c.hiddenLocal()
+	c.compileFinally(stmt.Finally)
+	c.emitOp(chunk.OpThrow)
+	// Execution does not continue past OpThrow, so drop the scope's slots
+	// without emitting pops.
+	c.scopeDepth--
+	for len(c.locals) > 1 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+	c.patchJumps(endJumps)
+	return struct{}{}
+}
+
+func (c *Compiler) VisitThrowStmt(stmt *ast.ThrowStmt) struct{} {
+	c.compileExpr(stmt.Value)
+	c.line = stmt.Keyword.Line
+	c.emitOp(chunk.OpThrow)
+	return struct{}{}
+}
+
+// compileFinally compiles a finally clause, if there is one.
+func (c *Compiler) compileFinally(finally ast.Stmt) {
+	if finally != nil {
+		c.compileStmt(finally)
+	}
+}
+
+// exitTries emits code to leave the try statements enclosed by at least
+// loops loops, innermost first, before a break, continue or return jumps
+// out of them.
+func (c *Compiler) exitTries(loops int) {
+	tries := c.tries
+	defer func() {
+		c.tries = tries
+	}()
+	for len(c.tries) > 0 && c.tries[len(c.tries)-1].loops >= loops {
+		t := c.tries[len(c.tries)-1]
+		// Compile the finally clause outside of the statement, so that a
+		// break or continue in it does not run it again.
+		c.tries = c.tries[:len(c.tries)-1]
+		c.emitOp(chunk.OpEndTry)
+		c.compileFinally(t.finally)
+	}
+}
+
+func (c *Compiler) VisitBreakStmt(stmt *ast.BreakStmt) struct{} {
+	l := c.loops[len(c.loops)-1]
+	c.exitTries(len(c.loops))
+	c.popLocals(l.breakDepth)
+	l.breaks = append(l.breaks, c.emitJump(chunk.OpJump))
+	return struct{}{}
+}
+
+func (c *Compiler) VisitContinueStmt(stmt *ast.ContinueStmt) struct{} {
+	l := c.loops[len(c.loops)-1]
+	c.exitTries(len(c.loops))
+	c.popLocals(l.continueDepth)
+	l.continues = append(l.continues, c.emitJump(chunk.OpJump))
+	return struct{}{}
+}
+
+// VisitReturnStmt leaves every try statement in the function before
+// returning, keeping the value to return in a slot of its own while their
+// finally clauses run.
+func (c *Compiler) VisitReturnStmt(stmt *ast.ReturnStmt) struct{} {
+	if stmt.Value != nil {
+		c.compileExpr(stmt.Value)
+	} else {
+		c.emitOp(chunk.OpNil)
+	}
+	c.line = stmt.Keyword.Line
+	if len(c.tries) > 0 {
+		c.hiddenLocal()
+		c.exitTries(0)
+		c.locals = c.locals[:len(c.locals)-1]
+		c.line = stmt.Keyword.Line
+	}
+	c.emitOp(chunk.OpReturn)
+	return struct{}{}
+}
+
+func (c *Compiler) VisitExpressionStmt(stmt *ast.ExpressionStmt) struct{} {
+	c.compileExpr(stmt.Expression)
+	c.emitOp(chunk.OpPop)
+	return struct{}{}
+}
+
+// VisitImportStmt imports the module again for each member it binds, which
+// is cheap since the module only runs the first time.
+func (c *Compiler) VisitImportStmt(stmt *ast.ImportStmt) struct{} {
+	path := c.makeConstant(stmt.Path.Literal.(string))
+	names := stmt.Names
+	if names == nil {
+		names = []token.Token{stmt.Alias}
+	}
+	for _, name := range names {
+		if c.scopeDepth > 0 {
+			c.line = name.Line
+			c.declareLocal(name.Lexeme)
+		}
+		c.line = stmt.Keyword.Line
+		c.emitOp(chunk.OpImport)
+		c.emitShort(path)
+		c.line = name.Line
+		if stmt.Names != nil {
+			c.emitOp(chunk.OpImportFrom)
+			c.emitShort(c.makeConstant(name.Lexeme))
+		}
+		if c.scopeDepth > 0 {
+			c.defineLocal()
+			continue
+		}
+		c.emitOp(chunk.OpDefineGlobal)
+		c.emitShort(c.makeConstant(name.Lexeme))
+	}
+	return struct{}{}
+}
+
+func (c *Compiler) VisitPrintStmt(stmt *ast.PrintStmt) struct{} {
+	c.compileExpr(stmt.Expression)
+	c.line = stmt.Line
+	c.emitOp(chunk.OpPrint)
+	return struct{}{}
+}
+
+func (c *Compiler) VisitVarStmt(stmt *ast.VarStmt) struct{} {
+	if c.scopeDepth > 0 {
+		c.line = stmt.Name.Line
+		c.declareLocal(stmt.Name.Lexeme)
+		// A function can refer to the variable it initializes, so that
+		// it can call itself.
+		if _, ok := stmt.Initializer.(*ast.Lambda); ok {
+			c.defineLocal()
+		}
+	}
+	if stmt.Initializer != nil {
+		c.compileExpr(stmt.Initializer)
+	} else {
+		c.emitOp(chunk.OpNil)
+	}
+	if c.scopeDepth > 0 {
+		// The initializer's value stays on the stack as the local's slot.
+		c.defineLocal()
+		return struct{}{}
+	}
+	c.line = stmt.Name.Line
+	c.emitOp(chunk.OpDefineGlobal)
+	c.emitShort(c.makeConstant(stmt.Name.Lexeme))
+	return struct{}{}
+}
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+// endScope closes the innermost scope, popping its locals off the stack.
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+	count := c.popLocals(c.scopeDepth)
+	c.locals = c.locals[:len(c.locals)-count]
+}
+
+// popLocals emits code to pop the locals declared deeper than depth off
+// the stack, closing those that functions captured, leaving them in scope
+// for the compiler, and returns how many there are.
+func (c *Compiler) popLocals(depth int) int {
+	count := 0
+	for idx := len(c.locals) - 1; idx > 0 && c.locals[idx].depth > depth; idx-- {
+		if c.locals[idx].captured {
+			c.emitOp(chunk.OpCloseUpvalue)
+		} else {
+			c.emitOp(chunk.OpPop)
+		}
+		count++
+	}
+	return count
+}
+
+// beginLoop starts compiling the body of a loop whose break and continue
+// statements pop the locals deeper than the given depths.
+func (c *Compiler) beginLoop(breakDepth, continueDepth int) *loop {
+	l := &loop{breakDepth: breakDepth, continueDepth: continueDepth}
+	c.loops = append(c.loops, l)
+	return l
+}
+
+// endLoop ends the innermost loop, making its break statements jump to the
+// next instruction to be emitted.
+func (c *Compiler) endLoop() {
+	l := c.loops[len(c.loops)-1]
+	c.loops = c.loops[:len(c.loops)-1]
+	c.patchJumps(l.breaks)
+}
+
+// declareLocal adds a local variable to the innermost scope, marked as not
+// yet initialized.
+func (c *Compiler) declareLocal(name string) {
+	for idx := len(c.locals) - 1; idx > 0; idx-- {
+		if c.locals[idx].depth != -1 && c.locals[idx].depth < c.scopeDepth {
+			break
+		}
+		if c.locals[idx].name == name {
+			c.error("Already a variable with this name in this scope.")
+		}
+	}
+	if len(c.locals) == maxLocals {
+		c.error("Too many local variables in function.")
+	}
+	c.locals = append(c.locals, local{name: name, depth: -1})
+}
+
+// hiddenLocal adds a local with no name to the innermost scope, for a value
+// the compiled code keeps on the stack.
+func (c *Compiler) hiddenLocal() {
+	if len(c.locals) == maxLocals {
+		c.error("Too many local variables in function.")
+	}
+	c.locals = append(c.locals, local{depth: c.scopeDepth})
+}
+
+// defineLocal marks the most recently declared local as initialized.
+func (c *Compiler) defineLocal() {
+	c.locals[len(c.locals)-1].depth = c.scopeDepth
+}
+
+// resolveLocal returns the stack slot of the innermost local variable
+// called name, reporting false if name is not a local.
+func (c *Compiler) resolveLocal(name string) (int, bool) {
+	for idx := len(c.locals) - 1; idx > 0; idx-- {
+		if c.locals[idx].name == name {
+			if c.locals[idx].depth == -1 {
+				c.error("Can't read local variable in its own initializer.")
+			}
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// resolveUpvalue returns the index of the upvalue through which the
+// function refers to the variable called name of an enclosing function,
+// adding one if needed, and reports false if name is not a local of any
+// enclosing function.
+func (c *Compiler) resolveUpvalue(name string) (int, bool) {
+	if c.enclosing == nil {
+		return 0, false
+	}
+	if slot, ok := c.enclosing.resolveLocal(name); ok {
+		c.enclosing.locals[slot].captured = true
+		return c.addUpvalue(byte(slot), true), true
+	}
+	if idx, ok := c.enclosing.resolveUpvalue(name); ok {
+		return c.addUpvalue(byte(idx), false), true
+	}
+	return 0, false
+}
+
+// addUpvalue returns the index of the given upvalue, adding it if the
+// function does not use it yet.
+func (c *Compiler) addUpvalue(index byte, isLocal bool) int {
+	for idx, up := range c.upvalues {
+		if up.index == index && up.isLocal == isLocal {
+			return idx
+		}
+	}
+	if len(c.upvalues) == maxUpvalues {
+		c.error("Too many closure variables in function.")
+	}
+	c.upvalues = append(c.upvalues, upvalue{index: index, isLocal: isLocal})
+	return len(c.upvalues) - 1
+}
+
+func (c *Compiler) compileStmt(stmt ast.Stmt) {
+	c.line = stmt.Pos()
+	ast.AcceptStmt[struct{}](stmt, c)
+}
+
+func (c *Compiler) compileExpr(expr ast.Expr) {
+	ast.AcceptExpr[struct{}](expr, c)
+}
+
+func (c *Compiler) chunk() *chunk.Chunk {
+	return c.function.Chunk
+}
+
+func (c *Compiler) emitOp(op chunk.OpCode) {
+	c.chunk().WriteOp(op, c.line)
+}
+
+func (c *Compiler) emitByte(b byte) {
+	c.chunk().Write(b, c.line)
+}
+
+func (c *Compiler) emitShort(operand uint16) {
+	c.chunk().WriteShort(operand, c.line)
+}
+
+func (c *Compiler) emitConstant(value chunk.Value) {
+	c.emitOp(chunk.OpConstant)
+	c.emitShort(c.makeConstant(value))
+}
+
+// emitJump emits a forward jump with a placeholder offset, returning the
+// position of the offset for patchJump.
+func (c *Compiler) emitJump(op chunk.OpCode) int {
+	c.emitOp(op)
+	c.emitShort(math.MaxUint16)
+	return len(c.chunk().Code) - 2
+}
+
+// patchJump makes the jump whose offset is at position land on the next
+// instruction to be emitted.
+func (c *Compiler) patchJump(position int) {
+	jump := len(c.chunk().Code) - position - 2
+	if jump > math.MaxUint16 {
+		c.error("Too much code to jump over.")
+	}
+	c.chunk().Code[position] = byte(jump >> 8)
+	c.chunk().Code[position+1] = byte(jump)
+}
+
+func (c *Compiler) patchJumps(positions []int) {
+	for _, position := range positions {
+		c.patchJump(position)
+	}
+}
+
+// emitLoop emits a backward jump to the instruction at loopStart.
+func (c *Compiler) emitLoop(loopStart int) {
+	c.emitOp(chunk.OpLoop)
+	offset := len(c.chunk().Code) - loopStart + 2
+	if offset > math.MaxUint16 {
+		c.error("Loop body too large.")
+	}
+	c.emitShort(uint16(offset))
+}
+
+func (c *Compiler) makeConstant(value chunk.Value) uint16 {
+	idx := c.chunk().AddConstant(value)
+	if idx > math.MaxUint16 {
+		c.error("Too many constants in one chunk.")
+	}
+	return uint16(idx)
+}
+
+func (c *Compiler) error(message string) {
+	err.Report(c.line, "", message)
+	panic(ErrCompile)
+}
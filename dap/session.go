@@ -0,0 +1,480 @@
+// Package dap implements a Debug Adapter Protocol server for glox, allowing
+// editors to set breakpoints in, step through and inspect running Lox scripts.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/debug"
+	"github.com/nicholasq/glox/environment"
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/interpreter"
+	"github.com/nicholasq/glox/module"
+	"github.com/nicholasq/glox/native"
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+)
+
+// threadID is the id of the only thread a Lox program has.
+const threadID = 1
+
+// Session serves a single debugging session over a pair of streams.
+type Session struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	writeMu sync.Mutex
+	seq     int
+
+	program     string
+	stmts       []ast.Stmt
+	stopOnEntry bool
+	noDebug     bool
+	breakpoints map[string][]debug.Breakpoint
+	launched    bool
+	configured  bool
+	started     bool
+	done        chan struct{}
+
+	interp   *interpreter.Interpreter
+	debugger *debug.Debugger
+	// reporter forwards the errors the program's scanner, parser and
+	// interpreter report to the client as output events.
+	reporter *err.Reporter
+
+	// handles maps variablesReference values to the scopes they name. They
+	// are only valid while the program stays paused.
+	handles map[int]*environment.Environment
+}
+
+// NewSession creates a session reading requests from in and writing
+// responses and events to out.
+func NewSession(in io.Reader, out io.Writer) *Session {
+	s := &Session{
+		in:          bufio.NewReader(in),
+		out:         out,
+		breakpoints: make(map[string][]debug.Breakpoint),
+		done:        make(chan struct{}),
+		handles:     make(map[int]*environment.Environment),
+	}
+	s.reporter = &err.Reporter{Writer: &outputWriter{session: s, category: "stderr"}}
+	return s
+}
+
+// Serve handles requests until the client disconnects or in is closed.
+// Errors in the program are forwarded to the client as output events.
+func (s *Session) Serve() error {
+	for {
+		body, readErr := readMessage(s.in)
+		if readErr == io.EOF {
+			s.shutdown()
+			return nil
+		}
+		if readErr != nil {
+			s.shutdown()
+			return readErr
+		}
+		var req request
+		if jsonErr := json.Unmarshal(body, &req); jsonErr != nil {
+			s.shutdown()
+			return fmt.Errorf("malformed message: %w", jsonErr)
+		}
+		if req.Type != "request" {
+			continue
+		}
+		if s.dispatch(&req) {
+			return nil
+		}
+	}
+}
+
+// dispatch handles req and reports whether the session has ended.
+func (s *Session) dispatch(req *request) bool {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsConditionalBreakpoints:   true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		})
+		s.sendEvent("initialized", nil)
+	case "launch":
+		s.onLaunch(req)
+	case "setBreakpoints":
+		s.onSetBreakpoints(req)
+	case "configurationDone":
+		s.configured = true
+		s.respond(req, nil)
+		s.maybeStart()
+	case "threads":
+		s.respond(req, map[string]interface{}{"threads": []thread{{ID: threadID, Name: "main"}}})
+	case "stackTrace":
+		s.onStackTrace(req)
+	case "scopes":
+		s.onScopes(req)
+	case "variables":
+		s.onVariables(req)
+	case "evaluate":
+		s.onEvaluate(req)
+	case "continue":
+		s.onResume(req, s.debugger.Continue)
+	case "next":
+		s.onResume(req, s.debugger.StepOver)
+	case "stepIn":
+		s.onResume(req, s.debugger.StepIn)
+	case "stepOut":
+		s.onResume(req, s.debugger.StepOut)
+	case "pause":
+		if s.debugger != nil {
+			s.debugger.Pause()
+		}
+		s.respond(req, nil)
+	case "terminate":
+		s.shutdown()
+		s.respond(req, nil)
+	case "disconnect":
+		s.shutdown()
+		s.respond(req, nil)
+		return true
+	default:
+		s.respondError(req, fmt.Sprintf("unsupported command: %s", req.Command))
+	}
+	return false
+}
+
+func (s *Session) onLaunch(req *request) {
+	var args launchArguments
+	if jsonErr := json.Unmarshal(req.Arguments, &args); jsonErr != nil {
+		s.respondError(req, jsonErr.Error())
+		return
+	}
+	program, absErr := filepath.Abs(args.Program)
+	if absErr != nil {
+		s.respondError(req, absErr.Error())
+		return
+	}
+	contents, readErr := os.ReadFile(program)
+	if readErr != nil {
+		s.respondError(req, readErr.Error())
+		return
+	}
+	sc := scanner.New(string(contents))
+	sc.Reporter = s.reporter
+	p := parser.New(sc.ScanTokens())
+	p.SetReporter(s.reporter)
+	stmts, parseErr := p.Parse()
+	if parseErr != nil {
+		s.respondError(req, fmt.Sprintf("could not parse %s", args.Program))
+		return
+	}
+
+	s.program = program
+	s.stmts = stmts
+	s.stopOnEntry = args.StopOnEntry && !args.NoDebug
+	s.noDebug = args.NoDebug
+	loader := module.New()
+	loader.Reporter = s.reporter
+	s.interp = interpreter.New()
+	s.interp.SetOutput(&outputWriter{session: s, category: "stdout"})
+	s.interp.SetReporter(s.reporter)
+	s.interp.SetLoader(loader)
+	s.interp.SetFile(program)
+	s.debugger = debug.New(s.interp, s.onStop)
+	s.launched = true
+	s.respond(req, nil)
+	s.maybeStart()
+}
+
+func (s *Session) onSetBreakpoints(req *request) {
+	var args setBreakpointsArguments
+	if jsonErr := json.Unmarshal(req.Arguments, &args); jsonErr != nil {
+		s.respondError(req, jsonErr.Error())
+		return
+	}
+	path, absErr := filepath.Abs(args.Source.Path)
+	if absErr != nil {
+		s.respondError(req, absErr.Error())
+		return
+	}
+	lines, linesErr := statementLines(path)
+	breakpoints := make([]debug.Breakpoint, 0, len(args.Breakpoints))
+	result := make([]breakpoint, 0, len(args.Breakpoints))
+	for _, bp := range args.Breakpoints {
+		if linesErr != nil {
+			result = append(result, breakpoint{Line: bp.Line, Message: linesErr.Error()})
+			continue
+		}
+		// A breakpoint on a line without a statement moves to the next
+		// line that has one, which is where it would stop.
+		idx := sort.Search(len(lines), func(i int) bool { return lines[i] >= bp.Line })
+		if idx == len(lines) {
+			result = append(result, breakpoint{Line: bp.Line, Message: "No statement on or after this line."})
+			continue
+		}
+		breakpoints = append(breakpoints, debug.Breakpoint{Line: lines[idx], Condition: bp.Condition})
+		result = append(result, breakpoint{Verified: true, Line: lines[idx]})
+	}
+	s.breakpoints[path] = breakpoints
+	if s.debugger != nil && path == s.program && !s.noDebug {
+		s.debugger.SetBreakpoints(breakpoints)
+	}
+	s.respond(req, map[string]interface{}{"breakpoints": result})
+}
+
+// statementLines returns, in increasing order, the lines of the Lox file at
+// path on which statements start, the only lines a breakpoint can stop on.
+func statementLines(path string) ([]uint, error) {
+	contents, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+	// Syntax errors are reported when the program is launched, so they
+	// aren't reported here as well.
+	quiet := &err.Reporter{Writer: io.Discard}
+	sc := scanner.New(string(contents))
+	sc.Reporter = quiet
+	p := parser.New(sc.ScanTokens())
+	p.SetReporter(quiet)
+	stmts, parseErr := p.Parse()
+	if parseErr != nil {
+		return nil, fmt.Errorf("could not parse %s", path)
+	}
+	seen := make(map[uint]bool)
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			if stmt, ok := node.(ast.Stmt); ok {
+				seen[stmt.Pos()] = true
+			}
+			return true
+		})
+	}
+	lines := make([]uint, 0, len(seen))
+	for line := range seen {
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i] < lines[j] })
+	return lines, nil
+}
+
+// maybeStart runs the program once it has been launched and the client has
+// finished sending its initial configuration.
+func (s *Session) maybeStart() {
+	if !s.launched || !s.configured || s.started {
+		return
+	}
+	s.started = true
+	if !s.noDebug {
+		s.debugger.SetBreakpoints(s.breakpoints[s.program])
+	}
+	go func() {
+		defer close(s.done)
+		exitCode := 0
+		runErr := s.debugger.Run(s.stmts, s.stopOnEntry)
+		if exit, ok := runErr.(*native.Exit); ok {
+			exitCode = exit.Code
+		} else if rtErr, ok := runErr.(*err.RuntimeError); ok {
+			s.reporter.RuntimeErrorReport(rtErr)
+			exitCode = 70
+		} else if runErr == interpreter.ErrResolve {
+			// The errors have been reported as they were found.
+			exitCode = 65
+		} else if runErr != nil && runErr != debug.ErrTerminated {
+			fmt.Fprintln(s.reporter.Writer, "Error:", runErr)
+			exitCode = 70
+		}
+		s.sendEvent("exited", map[string]interface{}{"exitCode": exitCode})
+		s.sendEvent("terminated", nil)
+	}()
+}
+
+// shutdown terminates a running program and waits for it to finish.
+func (s *Session) shutdown() {
+	if !s.started {
+		return
+	}
+	s.debugger.Terminate()
+	<-s.done
+}
+
+func (s *Session) onStop(stop debug.Stop) {
+	s.sendEvent("stopped", map[string]interface{}{
+		"reason":            string(stop.Reason),
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	})
+}
+
+func (s *Session) onResume(req *request, resume func() error) {
+	if s.debugger == nil {
+		s.respondError(req, debug.ErrNotPaused.Error())
+		return
+	}
+	s.handles = make(map[int]*environment.Environment)
+	if resumeErr := resume(); resumeErr != nil {
+		s.respondError(req, resumeErr.Error())
+		return
+	}
+	if req.Command == "continue" {
+		s.respond(req, map[string]interface{}{"allThreadsContinued": true})
+	} else {
+		s.respond(req, nil)
+	}
+}
+
+func (s *Session) onStackTrace(req *request) {
+	frames, stackErr := s.callStack()
+	if stackErr != nil {
+		s.respondError(req, stackErr.Error())
+		return
+	}
+	result := make([]stackFrame, 0, len(frames))
+	for idx, frame := range frames {
+		result = append(result, stackFrame{
+			ID:     idx,
+			Name:   frame.Name,
+			Source: source{Name: filepath.Base(s.program), Path: s.program},
+			Line:   frame.Line,
+			Column: 1,
+		})
+	}
+	s.respond(req, map[string]interface{}{"stackFrames": result, "totalFrames": len(result)})
+}
+
+func (s *Session) onScopes(req *request) {
+	var args scopesArguments
+	if jsonErr := json.Unmarshal(req.Arguments, &args); jsonErr != nil {
+		s.respondError(req, jsonErr.Error())
+		return
+	}
+	frames, stackErr := s.callStack()
+	if stackErr != nil {
+		s.respondError(req, stackErr.Error())
+		return
+	}
+	if args.FrameID < 0 || args.FrameID >= len(frames) {
+		s.respondError(req, "no such frame")
+		return
+	}
+	var result []scope
+	for env := frames[args.FrameID].Env; env != nil; env = env.Enclosing {
+		name := "Enclosing"
+		if env.Enclosing == nil {
+			name = "Globals"
+		} else if len(result) == 0 {
+			name = "Locals"
+		}
+		handle := len(s.handles) + 1
+		s.handles[handle] = env
+		result = append(result, scope{Name: name, VariablesReference: handle, Expensive: env.Enclosing == nil})
+	}
+	s.respond(req, map[string]interface{}{"scopes": result})
+}
+
+func (s *Session) onVariables(req *request) {
+	var args variablesArguments
+	if jsonErr := json.Unmarshal(req.Arguments, &args); jsonErr != nil {
+		s.respondError(req, jsonErr.Error())
+		return
+	}
+	env, ok := s.handles[args.VariablesReference]
+	if !ok {
+		s.respondError(req, "unknown variables reference")
+		return
+	}
+	var values map[string]interface{}
+	if doErr := s.debugger.Do(func() { values = env.Values() }); doErr != nil {
+		s.respondError(req, doErr.Error())
+		return
+	}
+	names := make([]string, 0, len(values))
+	for name, value := range values {
+		// Leave out the built-in functions the program has not redefined.
+		if builtin, ok := native.Globals[name]; ok && builtin == value {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]variable, 0, len(names))
+	for _, name := range names {
+		result = append(result, variable{Name: name, Value: interpreter.Stringify(values[name])})
+	}
+	s.respond(req, map[string]interface{}{"variables": result})
+}
+
+func (s *Session) onEvaluate(req *request) {
+	var args evaluateArguments
+	if jsonErr := json.Unmarshal(req.Arguments, &args); jsonErr != nil {
+		s.respondError(req, jsonErr.Error())
+		return
+	}
+	if s.debugger == nil {
+		s.respondError(req, debug.ErrNotPaused.Error())
+		return
+	}
+	frame := 0
+	if args.FrameID != nil {
+		frame = *args.FrameID
+	}
+	value, evalErr := s.debugger.Evaluate(args.Expression, frame)
+	if evalErr != nil {
+		s.respondError(req, evalErr.Error())
+		return
+	}
+	s.respond(req, map[string]interface{}{"result": interpreter.Stringify(value), "variablesReference": 0})
+}
+
+func (s *Session) callStack() ([]interpreter.Frame, error) {
+	if s.debugger == nil {
+		return nil, debug.ErrNotPaused
+	}
+	return s.debugger.CallStack()
+}
+
+func (s *Session) respond(req *request, body interface{}) {
+	s.write(func(seq int) interface{} {
+		return response{Seq: seq, Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body}
+	})
+}
+
+func (s *Session) respondError(req *request, message string) {
+	s.write(func(seq int) interface{} {
+		return response{Seq: seq, Type: "response", RequestSeq: req.Seq, Success: false, Command: req.Command, Message: message}
+	})
+}
+
+func (s *Session) sendEvent(name string, body interface{}) {
+	s.write(func(seq int) interface{} {
+		return event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// write serializes a message built with the next sequence number. Events
+// are sent from the interpreter's goroutine, so writes are locked.
+func (s *Session) write(build func(seq int) interface{}) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	// A failed write means the client has gone away; the read loop notices.
+	_ = writeMessage(s.out, build(s.seq))
+}
+
+// outputWriter forwards program output and diagnostics to the client.
+type outputWriter struct {
+	session  *Session
+	category string
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.session.sendEvent("output", map[string]interface{}{"category": w.category, "output": string(p)})
+	return len(p), nil
+}
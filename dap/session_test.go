@@ -0,0 +1,216 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testClient struct {
+	t    *testing.T
+	seq  int
+	in   io.Writer
+	msgs chan map[string]interface{}
+}
+
+func newTestClient(t *testing.T) *testClient {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	c := &testClient{t: t, in: clientOut, msgs: make(chan map[string]interface{}, 100)}
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		NewSession(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+	// Closing the client's end of the requests ends the session, which
+	// must finish serving before the test does.
+	t.Cleanup(func() {
+		clientOut.Close()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case <-served:
+				return
+			case <-c.msgs:
+			case <-timeout:
+				t.Error("Timed out waiting for the session to finish serving")
+				return
+			}
+		}
+	})
+	go func() {
+		reader := bufio.NewReader(clientIn)
+		for {
+			body, readErr := readMessage(reader)
+			if readErr != nil {
+				close(c.msgs)
+				return
+			}
+			var msg map[string]interface{}
+			json.Unmarshal(body, &msg)
+			c.msgs <- msg
+		}
+	}()
+	return c
+}
+
+func (c *testClient) send(command string, args interface{}) {
+	c.seq++
+	raw, _ := json.Marshal(args)
+	if writeErr := writeMessage(c.in, request{Seq: c.seq, Type: "request", Command: command, Arguments: raw}); writeErr != nil {
+		c.t.Fatalf("Error sending %s: %s", command, writeErr)
+	}
+}
+
+// expect reads messages until one of the given type and name (command or
+// event) arrives, failing the test if it does not arrive in time.
+func (c *testClient) expect(kind, name string) map[string]interface{} {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.msgs:
+			if !ok {
+				c.t.Fatalf("Session closed while waiting for %s %s", kind, name)
+			}
+			if msg["type"] == kind && (msg["command"] == name || msg["event"] == name) {
+				if kind == "response" && msg["success"] != true {
+					c.t.Fatalf("Request %s failed: %v", name, msg["message"])
+				}
+				return msg
+			}
+		case <-timeout:
+			c.t.Fatalf("Timed out waiting for %s %s", kind, name)
+		}
+	}
+}
+
+// expectStop waits for a stopped event and checks its reason and line.
+func (c *testClient) expectStop(reason string, line uint) {
+	stopped := c.expect("event", "stopped")
+	if got := body(stopped)["reason"]; got != reason {
+		c.t.Fatalf("Expected stop reason %s, got %v", reason, got)
+	}
+	c.send("stackTrace", map[string]interface{}{"threadId": threadID})
+	frames := body(c.expect("response", "stackTrace"))["stackFrames"].([]interface{})
+	if got := frames[0].(map[string]interface{})["line"]; got != float64(line) {
+		c.t.Fatalf("Expected to stop on line %d, got %v", line, got)
+	}
+}
+
+func body(msg map[string]interface{}) map[string]interface{} {
+	return msg["body"].(map[string]interface{})
+}
+
+func TestSessionBreakpointsAndVariables(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "test.lox")
+	script := "var a = 1;\nvar b = a + 1;\nprint b;\nprint a + b;\n"
+	if writeErr := os.WriteFile(program, []byte(script), 0o644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	c := newTestClient(t)
+	c.send("initialize", map[string]interface{}{"adapterID": "glox"})
+	c.expect("response", "initialize")
+	c.expect("event", "initialized")
+
+	c.send("launch", launchArguments{Program: program})
+	c.expect("response", "launch")
+	c.send("setBreakpoints", setBreakpointsArguments{
+		Source:      source{Path: program},
+		Breakpoints: []sourceBreakpoint{{Line: 3}, {Line: 4, Condition: "b == 5"}, {Line: 2, Condition: "a == 1"}},
+	})
+	c.expect("response", "setBreakpoints")
+	c.send("configurationDone", nil)
+	c.expect("response", "configurationDone")
+
+	c.expectStop("breakpoint", 2)
+	c.send("continue", map[string]interface{}{"threadId": threadID})
+	c.expect("response", "continue")
+	c.expectStop("breakpoint", 3)
+
+	c.send("scopes", scopesArguments{FrameID: 0})
+	scopes := body(c.expect("response", "scopes"))["scopes"].([]interface{})
+	globals := scopes[len(scopes)-1].(map[string]interface{})
+	c.send("variables", variablesArguments{VariablesReference: int(globals["variablesReference"].(float64))})
+	variables := body(c.expect("response", "variables"))["variables"].([]interface{})
+	expected := []variable{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}
+	if len(variables) != len(expected) {
+		t.Fatalf("Expected %d variables, got %d", len(expected), len(variables))
+	}
+	for idx, v := range variables {
+		got := v.(map[string]interface{})
+		if got["name"] != expected[idx].Name || got["value"] != expected[idx].Value {
+			t.Fatalf("Expected variable %+v, got %v", expected[idx], got)
+		}
+	}
+
+	c.send("evaluate", evaluateArguments{Expression: "a + b * 10"})
+	if result := body(c.expect("response", "evaluate"))["result"]; result != "21" {
+		t.Fatalf("Expected evaluate result 21, got %v", result)
+	}
+
+	c.send("next", map[string]interface{}{"threadId": threadID})
+	c.expect("response", "next")
+	output := c.expect("event", "output")
+	if text := body(output)["output"]; text != "2\n" {
+		t.Fatalf("Expected output \"2\\n\", got %q", text)
+	}
+	c.expectStop("step", 4)
+
+	// The conditional breakpoint on line 4 is false, so continuing runs to the end.
+	c.send("continue", map[string]interface{}{"threadId": threadID})
+	c.expect("response", "continue")
+	if code := body(c.expect("event", "exited"))["exitCode"]; code != float64(0) {
+		t.Fatalf("Expected exit code 0, got %v", code)
+	}
+	c.expect("event", "terminated")
+
+	c.send("disconnect", nil)
+	c.expect("response", "disconnect")
+}
+
+func TestSessionBreakpointVerification(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "test.lox")
+	script := "var a = 1;\n\n// A comment.\nprint a;\n"
+	if writeErr := os.WriteFile(program, []byte(script), 0o644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	c := newTestClient(t)
+	c.send("initialize", map[string]interface{}{"adapterID": "glox"})
+	c.expect("response", "initialize")
+	c.expect("event", "initialized")
+
+	// A breakpoint before a statement moves to it; one after the last
+	// statement can never be hit.
+	c.send("setBreakpoints", setBreakpointsArguments{
+		Source:      source{Path: program},
+		Breakpoints: []sourceBreakpoint{{Line: 2}, {Line: 9}},
+	})
+	result := body(c.expect("response", "setBreakpoints"))["breakpoints"].([]interface{})
+	expected := []breakpoint{{Verified: true, Line: 4}, {Verified: false, Line: 9}}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d breakpoints, got %d", len(expected), len(result))
+	}
+	for idx, bp := range result {
+		got := bp.(map[string]interface{})
+		if got["verified"] != expected[idx].Verified || got["line"] != float64(expected[idx].Line) {
+			t.Errorf("Expected breakpoint %+v, got %v", expected[idx], got)
+		}
+	}
+
+	c.send("launch", launchArguments{Program: program})
+	c.expect("response", "launch")
+	c.send("configurationDone", nil)
+	c.expect("response", "configurationDone")
+	c.expectStop("breakpoint", 4)
+
+	c.send("disconnect", nil)
+	c.expect("response", "disconnect")
+}
@@ -0,0 +1,166 @@
+package debug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/interpreter"
+)
+
+const consoleHelp = `Commands:
+  break [file:]line   set a breakpoint (alias b)
+  continue            run until the next breakpoint or watch (alias c)
+  next                step to the next line, stepping over calls (alias n)
+  step                step to the next line, stepping into calls (alias s)
+  print <expr>        evaluate an expression in the current scope (alias p)
+  watch <var>         stop whenever the variable's value changes
+  bt                  print a backtrace
+  quit                stop the program and exit (alias q)
+`
+
+// Console is a line-oriented terminal debugger, in the spirit of gdb, for a
+// single Lox script.
+type Console struct {
+	program string
+	lines   []string
+	in      *bufio.Reader
+	out     io.Writer
+
+	interp   *interpreter.Interpreter
+	debugger *Debugger
+	stops    chan Stop
+}
+
+// NewConsole creates a console debugging the script at program whose
+// contents are source. Commands are read from in; program output and
+// debugger messages are written to out.
+func NewConsole(program, source string, in io.Reader, out io.Writer) *Console {
+	c := &Console{
+		program: program,
+		lines:   strings.Split(source, "\n"),
+		in:      bufio.NewReader(in),
+		out:     out,
+		interp:  interpreter.New(),
+		stops:   make(chan Stop),
+	}
+	c.interp.SetOutput(out)
+	c.interp.SetFile(program)
+	c.debugger = New(c.interp, func(stop Stop) { c.stops <- stop })
+	return c
+}
+
+// Run executes stmts, stopping before the first statement so breakpoints
+// can be set, and processes commands until the program ends or the user
+// quits. It returns the error that stopped the program, if any.
+func (c *Console) Run(stmts []ast.Stmt) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- c.debugger.Run(stmts, true)
+	}()
+
+	for {
+		select {
+		case stop := <-c.stops:
+			c.showStop(stop)
+			if quit := c.commands(); quit {
+				c.debugger.Terminate()
+				<-done
+				return nil
+			}
+		case runErr := <-done:
+			if runErr != nil && runErr != ErrTerminated {
+				return runErr
+			}
+			fmt.Fprintln(c.out, "Program exited.")
+			return nil
+		}
+	}
+}
+
+// commands reads and runs commands until one resumes the program. It
+// reports whether the user asked to quit.
+func (c *Console) commands() bool {
+	for {
+		fmt.Fprint(c.out, "(glox) ")
+		line, readErr := c.in.ReadString('\n')
+		if readErr != nil && line == "" {
+			return true
+		}
+		command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch command {
+		case "":
+		case "break", "b":
+			c.setBreakpoint(arg)
+		case "continue", "c":
+			c.debugger.Continue()
+			return false
+		case "next", "n":
+			c.debugger.StepOver()
+			return false
+		case "step", "s":
+			c.debugger.StepIn()
+			return false
+		case "print", "p":
+			value, evalErr := c.debugger.Evaluate(arg, 0)
+			if evalErr != nil {
+				fmt.Fprintf(c.out, "Error: %s\n", evalErr)
+			} else {
+				fmt.Fprintln(c.out, interpreter.Stringify(value))
+			}
+		case "watch":
+			value, watchErr := c.debugger.AddWatch(arg)
+			if watchErr != nil {
+				fmt.Fprintf(c.out, "Error: %s\n", watchErr)
+			} else {
+				fmt.Fprintf(c.out, "Watching %s = %s\n", arg, value)
+			}
+		case "bt", "backtrace":
+			frames, _ := c.debugger.CallStack()
+			for idx, frame := range frames {
+				fmt.Fprintf(c.out, "#%d  %s at %s:%d\n", idx, frame.Name, filepath.Base(c.program), frame.Line)
+			}
+		case "quit", "q":
+			return true
+		case "help", "h":
+			fmt.Fprint(c.out, consoleHelp)
+		default:
+			fmt.Fprintf(c.out, "Unknown command %q. Type help for a list of commands.\n", command)
+		}
+	}
+}
+
+func (c *Console) setBreakpoint(arg string) {
+	file, lineStr, found := strings.Cut(arg, ":")
+	if !found {
+		file, lineStr = "", arg
+	}
+	if file != "" && file != c.program && filepath.Base(file) != filepath.Base(c.program) {
+		fmt.Fprintf(c.out, "No such file %q.\n", file)
+		return
+	}
+	line, convErr := strconv.ParseUint(lineStr, 10, 0)
+	if convErr != nil || line == 0 {
+		fmt.Fprintf(c.out, "Invalid line %q.\n", lineStr)
+		return
+	}
+	breakpoints := append(c.debugger.Breakpoints(), Breakpoint{Line: uint(line)})
+	c.debugger.SetBreakpoints(breakpoints)
+	fmt.Fprintf(c.out, "Breakpoint at %s:%d\n", filepath.Base(c.program), line)
+}
+
+func (c *Console) showStop(stop Stop) {
+	if stop.Reason == StopWatch {
+		fmt.Fprintf(c.out, "Watch %s changed: %s -> %s\n", stop.Watch, stop.Old, stop.New)
+	}
+	fmt.Fprintf(c.out, "Stopped at %s:%d (%s)\n", filepath.Base(c.program), stop.Line, stop.Reason)
+	if stop.Line > 0 && int(stop.Line) <= len(c.lines) {
+		fmt.Fprintf(c.out, "%4d  %s\n", stop.Line, strings.TrimSpace(c.lines[stop.Line-1]))
+	}
+}
@@ -0,0 +1,91 @@
+package debug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+)
+
+func TestConsole(t *testing.T) {
+	source := `var a = 1;
+var b = a + 1;
+print b;
+var b = a * 10;
+print a + b;
+`
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:  "Breakpoint and print",
+			input: "break test.lox:3\ncontinue\nprint a + b\nbt\ncontinue\n",
+			expected: []string{
+				"Stopped at test.lox:1 (entry)",
+				"Breakpoint at test.lox:3",
+				"Stopped at test.lox:3 (breakpoint)",
+				"(glox) 3\n",
+				"#0  script at test.lox:3",
+				"11\nProgram exited.",
+			},
+		},
+		{
+			name:  "Stepping",
+			input: "next\nstep\nnext\np b\nquit\n",
+			expected: []string{
+				"Stopped at test.lox:2 (step)",
+				"Stopped at test.lox:3 (step)",
+				"2\nStopped at test.lox:4 (step)",
+				"(glox) 2\n",
+			},
+		},
+		{
+			name:  "Watch",
+			input: "watch b\ncontinue\ncontinue\ncontinue\n",
+			expected: []string{
+				"Watching b = <undefined variable: b>",
+				"Watch b changed: <undefined variable: b> -> 2",
+				"Stopped at test.lox:3 (watch)",
+				"Watch b changed: 2 -> 10",
+				"Stopped at test.lox:5 (watch)",
+				"11\nProgram exited.",
+			},
+		},
+		{
+			name:  "Invalid commands",
+			input: "break other.lox:3\nbreak x\nwatch +\nfoo\nq\n",
+			expected: []string{
+				`No such file "other.lox".`,
+				`Invalid line "x".`,
+				"Error: parse error",
+				`Unknown command "foo".`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := scanner.New(source)
+			stmts, parseErr := parser.New(s.ScanTokens()).Parse()
+			if parseErr != nil {
+				t.Fatalf("Error during parsing: %s", parseErr)
+			}
+
+			var out strings.Builder
+			console := NewConsole("test.lox", source, strings.NewReader(tt.input), &out)
+			if runErr := console.Run(stmts); runErr != nil {
+				t.Fatalf("Unexpected runtime error: %s", runErr)
+			}
+
+			output := out.String()
+			for _, want := range tt.expected {
+				if !strings.Contains(output, want) {
+					t.Fatalf("Expected output to contain %q, got:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
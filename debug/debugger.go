@@ -0,0 +1,382 @@
+// Package debug implements the breakpoint and stepping engine used by the
+// glox debugger front ends. It drives an interpreter.Interpreter through its
+// statement hook, pausing the interpreter's goroutine whenever a breakpoint
+// is hit or a step completes.
+package debug
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/environment"
+	"github.com/nicholasq/glox/interpreter"
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+)
+
+// ErrNotPaused is returned by requests that need the program to be paused.
+var ErrNotPaused = errors.New("program is not paused")
+
+// ErrTerminated is returned by Run when the program was stopped with
+// Terminate, and by requests made of a paused program once it has been.
+var ErrTerminated = errors.New("program terminated")
+
+// StopReason describes why execution paused.
+type StopReason string
+
+const (
+	StopEntry      StopReason = "entry"
+	StopBreakpoint StopReason = "breakpoint"
+	StopStep       StopReason = "step"
+	StopPause      StopReason = "pause"
+	StopWatch      StopReason = "watch"
+)
+
+// Stop is reported each time execution pauses.
+type Stop struct {
+	Reason StopReason
+	Line   uint
+	// For StopWatch, Watch is the watched expression and Old and New are
+	// its formatted values before and after the change.
+	Watch    string
+	Old, New string
+}
+
+// Breakpoint pauses execution when a statement starting on Line is reached.
+// If Condition is set, it is evaluated in the current environment and the
+// breakpoint only fires when the result is truthy.
+type Breakpoint struct {
+	Line      uint
+	Condition string
+}
+
+type stepMode int
+
+const (
+	modeRun stepMode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+type watch struct {
+	expr  string
+	value string
+}
+
+type resumeCmd struct {
+	mode stepMode
+}
+
+type Debugger struct {
+	interp *interpreter.Interpreter
+	onStop func(Stop)
+
+	mu          sync.Mutex
+	breakpoints map[uint]Breakpoint
+	watches     []*watch
+	pauseReq    bool
+	paused      bool
+	killed      bool
+
+	// The fields below are only touched on the interpreter's goroutine.
+	entry      bool
+	mode       stepMode
+	stepDepth  int
+	lastLine   uint
+	lastDepth  int
+	evaluating bool
+
+	requests chan func()
+	resume   chan resumeCmd
+	// kill is closed by Terminate, releasing a paused program.
+	kill chan struct{}
+}
+
+// New creates a debugger for interp. onStop is called on the interpreter's
+// goroutine every time execution pauses; it must not block on the debugger.
+func New(interp *interpreter.Interpreter, onStop func(Stop)) *Debugger {
+	return &Debugger{
+		interp:      interp,
+		onStop:      onStop,
+		breakpoints: make(map[uint]Breakpoint),
+		requests:    make(chan func()),
+		resume:      make(chan resumeCmd),
+		kill:        make(chan struct{}),
+	}
+}
+
+// SetBreakpoints replaces all breakpoints. It may be called while the program runs.
+func (d *Debugger) SetBreakpoints(breakpoints []Breakpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = make(map[uint]Breakpoint, len(breakpoints))
+	for _, bp := range breakpoints {
+		d.breakpoints[bp.Line] = bp
+	}
+}
+
+// Breakpoints returns the current breakpoints.
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	breakpoints := make([]Breakpoint, 0, len(d.breakpoints))
+	for _, bp := range d.breakpoints {
+		breakpoints = append(breakpoints, bp)
+	}
+	return breakpoints
+}
+
+// AddWatch pauses the program whenever the formatted value of expr changes.
+// It must be called while paused and returns the expression's current value.
+func (d *Debugger) AddWatch(expr string) (string, error) {
+	if _, parseErr := d.parse(expr); parseErr != nil {
+		return "", parseErr
+	}
+	var value string
+	doErr := d.Do(func() {
+		value = d.watchValue(expr)
+	})
+	if doErr != nil {
+		return "", doErr
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.watches = append(d.watches, &watch{expr: expr, value: value})
+	return value, nil
+}
+
+// Run executes stmts under the debugger on the calling goroutine. If
+// stopOnEntry is set, execution pauses before the first statement.
+func (d *Debugger) Run(stmts []ast.Stmt, stopOnEntry bool) (result error) {
+	d.entry = stopOnEntry
+	d.interp.SetHook(d.hook)
+	defer func() {
+		d.interp.SetHook(nil)
+		if r := recover(); r != nil {
+			if r != ErrTerminated {
+				panic(r)
+			}
+			result = ErrTerminated
+		}
+	}()
+	return d.interp.Interpret(stmts)
+}
+
+// Continue resumes execution until the next breakpoint.
+func (d *Debugger) Continue() error { return d.send(resumeCmd{mode: modeRun}) }
+
+// StepIn resumes execution until the next statement on a different line.
+func (d *Debugger) StepIn() error { return d.send(resumeCmd{mode: modeStepIn}) }
+
+// StepOver resumes execution until the next line in the current frame or a caller.
+func (d *Debugger) StepOver() error { return d.send(resumeCmd{mode: modeStepOver}) }
+
+// StepOut resumes execution until the current frame returns.
+func (d *Debugger) StepOut() error { return d.send(resumeCmd{mode: modeStepOut}) }
+
+// Pause asks the running program to stop before its next statement.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pauseReq = true
+}
+
+// Terminate stops the program before its next statement, or at once if it
+// is paused; Run then returns ErrTerminated.
+func (d *Debugger) Terminate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.killed {
+		d.killed = true
+		close(d.kill)
+	}
+}
+
+// Paused reports whether the program is currently paused.
+func (d *Debugger) Paused() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.paused
+}
+
+// Do runs f on the interpreter's goroutine while the program is paused, so
+// that f can safely inspect interpreter state.
+func (d *Debugger) Do(f func()) error {
+	if !d.Paused() {
+		return ErrNotPaused
+	}
+	done := make(chan struct{})
+	select {
+	case d.requests <- func() {
+		defer close(done)
+		f()
+	}:
+	case <-d.kill:
+		return ErrTerminated
+	}
+	<-done
+	return nil
+}
+
+// CallStack returns the paused program's frames, innermost first.
+func (d *Debugger) CallStack() ([]interpreter.Frame, error) {
+	var frames []interpreter.Frame
+	doErr := d.Do(func() {
+		frames = d.interp.CallStack()
+	})
+	return frames, doErr
+}
+
+// Evaluate parses source as an expression and evaluates it in the
+// environment of the given frame, where 0 is the innermost frame.
+func (d *Debugger) Evaluate(source string, frame int) (interface{}, error) {
+	var value interface{}
+	var evalErr error
+	doErr := d.Do(func() {
+		frames := d.interp.CallStack()
+		if frame < 0 || frame >= len(frames) {
+			evalErr = errors.New("no such frame")
+			return
+		}
+		value, evalErr = d.evaluate(source, frames[frame].Env)
+	})
+	if doErr != nil {
+		return nil, doErr
+	}
+	return value, evalErr
+}
+
+// parse parses source as an expression, reporting syntax errors where the
+// interpreter reports its errors.
+func (d *Debugger) parse(source string) (ast.Expr, error) {
+	s := scanner.New(source)
+	s.Reporter = d.interp.Reporter()
+	p := parser.New(s.ScanTokens())
+	p.SetReporter(d.interp.Reporter())
+	return p.ParseExpression()
+}
+
+func (d *Debugger) evaluate(source string, env *environment.Environment) (interface{}, error) {
+	expr, parseErr := d.parse(source)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	d.evaluating = true
+	defer func() { d.evaluating = false }()
+	return d.interp.Evaluate(expr, env)
+}
+
+func (d *Debugger) send(cmd resumeCmd) error {
+	if !d.Paused() {
+		return ErrNotPaused
+	}
+	select {
+	case d.resume <- cmd:
+		return nil
+	case <-d.kill:
+		return ErrTerminated
+	}
+}
+
+func (d *Debugger) hook(stmt ast.Stmt) {
+	if d.evaluating {
+		return
+	}
+	line := stmt.Pos()
+	depth := len(d.interp.CallStack())
+	newLine := line != d.lastLine || depth != d.lastDepth
+	d.lastLine, d.lastDepth = line, depth
+
+	d.mu.Lock()
+	killed := d.killed
+	pauseReq := d.pauseReq
+	d.pauseReq = false
+	bp, hasBreakpoint := d.breakpoints[line]
+	watches := d.watches
+	d.mu.Unlock()
+
+	if killed {
+		panic(ErrTerminated)
+	}
+
+	for _, w := range watches {
+		if value := d.watchValue(w.expr); value != w.value {
+			stop := Stop{Reason: StopWatch, Line: line, Watch: w.expr, Old: w.value, New: value}
+			w.value = value
+			d.pause(stop, depth)
+			return
+		}
+	}
+
+	var reason StopReason
+	switch {
+	case d.entry:
+		d.entry = false
+		reason = StopEntry
+	case pauseReq:
+		reason = StopPause
+	case newLine && d.mode == modeStepIn:
+		reason = StopStep
+	case newLine && d.mode == modeStepOver && depth <= d.stepDepth:
+		reason = StopStep
+	case newLine && d.mode == modeStepOut && depth < d.stepDepth:
+		reason = StopStep
+	case newLine && hasBreakpoint && d.conditionHolds(bp):
+		reason = StopBreakpoint
+	default:
+		return
+	}
+	d.pause(Stop{Reason: reason, Line: line}, depth)
+}
+
+func (d *Debugger) conditionHolds(bp Breakpoint) bool {
+	if bp.Condition == "" {
+		return true
+	}
+	value, evalErr := d.evaluate(bp.Condition, d.interp.CallStack()[0].Env)
+	if evalErr != nil {
+		// Stop on a broken condition so the user can see and fix it.
+		return true
+	}
+	return value != nil && value != false
+}
+
+// watchValue formats the current value of a watched expression. Errors,
+// such as the variable not being defined yet, are part of the value so that
+// defining the variable counts as a change.
+func (d *Debugger) watchValue(expr string) string {
+	value, evalErr := d.evaluate(expr, d.interp.CallStack()[0].Env)
+	if evalErr != nil {
+		return "<" + evalErr.Error() + ">"
+	}
+	return interpreter.Stringify(value)
+}
+
+// pause stops the program until a resume command or Terminate releases it,
+// meanwhile running the requests made through Do.
+func (d *Debugger) pause(stop Stop, depth int) {
+	d.mu.Lock()
+	d.paused = true
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.paused = false
+		d.mu.Unlock()
+	}()
+
+	d.onStop(stop)
+	for {
+		select {
+		case f := <-d.requests:
+			f()
+		case <-d.kill:
+			panic(ErrTerminated)
+		case cmd := <-d.resume:
+			d.mode = cmd.mode
+			d.stepDepth = depth
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package debug
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicholasq/glox/interpreter"
+	"github.com/nicholasq/glox/native"
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+)
+
+// TestTerminateBeforePause terminates the program while a watch is being
+// evaluated, after the hook has checked for termination but before the
+// change it finds pauses the program. Run must still return.
+func TestTerminateBeforePause(t *testing.T) {
+	s := scanner.New("var a = 1;\nprint a;\n")
+	stmts, parseErr := parser.New(s.ScanTokens()).Parse()
+	if parseErr != nil {
+		t.Fatalf("Error during parsing: %s", parseErr)
+	}
+
+	interp := interpreter.New()
+	stopped := make(chan Stop, 1)
+	d := New(interp, func(stop Stop) { stopped <- stop })
+	calls := 0.0
+	interp.Globals().Define("terminate", &native.Function{Name: "terminate", Fn: func(ctx *native.Context, args []interface{}) (interface{}, error) {
+		if calls > 0 {
+			d.Terminate()
+		}
+		calls++
+		return calls, nil
+	}})
+
+	done := make(chan error)
+	go func() { done <- d.Run(stmts, true) }()
+	<-stopped
+	if _, watchErr := d.AddWatch("terminate()"); watchErr != nil {
+		t.Fatalf("Unexpected error: %s", watchErr)
+	}
+	if continueErr := d.Continue(); continueErr != nil {
+		t.Fatalf("Unexpected error: %s", continueErr)
+	}
+	select {
+	case runErr := <-done:
+		if runErr != ErrTerminated {
+			t.Fatalf("Expected ErrTerminated, got %v", runErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Terminate")
+	}
+}
@@ -7,8 +7,20 @@ import (
 	"github.com/nicholasq/glox/token"
 )
 
+// Environment holds the variable bindings of a single scope.
+// Scopes are chained through Enclosing, ending at the global environment.
 type Environment struct {
-	values map[string]interface{}
+	Enclosing *Environment
+	values    map[string]interface{}
+}
+
+// New creates an empty environment nested inside enclosing.
+// A nil enclosing environment creates a global scope.
+func New(enclosing *Environment) *Environment {
+	return &Environment{
+		Enclosing: enclosing,
+		values:    make(map[string]interface{}),
+	}
 }
 
 func (e *Environment) Define(name string, value interface{}) {
@@ -19,5 +31,52 @@ func (e *Environment) Get(name token.Token) (interface{}, error) {
 	if val, ok := e.values[name.Lexeme]; ok {
 		return val, nil
 	}
+	if e.Enclosing != nil {
+		return e.Enclosing.Get(name)
+	}
 	return nil, errors.New(fmt.Sprintf("undefined variable: %v", name.Lexeme))
 }
+
+// Assign replaces the value of the variable name in the innermost scope
+// that defines it.
+func (e *Environment) Assign(name token.Token, value interface{}) error {
+	if _, ok := e.values[name.Lexeme]; ok {
+		e.values[name.Lexeme] = value
+		return nil
+	}
+	if e.Enclosing != nil {
+		return e.Enclosing.Assign(name, value)
+	}
+	return errors.New(fmt.Sprintf("undefined variable: %v", name.Lexeme))
+}
+
+// GetAt returns the value of the variable name defined in the scope
+// distance levels out from this one, as located by the interpreter's
+// resolver.
+func (e *Environment) GetAt(distance int, name string) interface{} {
+	return e.ancestor(distance).values[name]
+}
+
+// AssignAt replaces the value of the variable name defined in the scope
+// distance levels out from this one.
+func (e *Environment) AssignAt(distance int, name string, value interface{}) {
+	e.ancestor(distance).values[name] = value
+}
+
+func (e *Environment) ancestor(distance int) *Environment {
+	env := e
+	for ; distance > 0; distance-- {
+		env = env.Enclosing
+	}
+	return env
+}
+
+// Values returns a copy of the bindings defined directly in this scope,
+// not including those of enclosing scopes.
+func (e *Environment) Values() map[string]interface{} {
+	values := make(map[string]interface{}, len(e.values))
+	for name, value := range e.values {
+		values[name] = value
+	}
+	return values
+}
@@ -2,24 +2,76 @@ package error
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/nicholasq/glox/token"
 )
 
+// Writer is where scanner, parser and runtime errors are reported.
+// It defaults to standard output.
+var Writer io.Writer = os.Stdout
+
 func ErrorReport(Line uint, message string) {
 	//todo: implement
 	//report(Line, "", message)
 }
 
 func Report(line uint, where string, message string) bool {
-	fmt.Printf("[Line %d] Error %s: %s\n", line, where, message)
-	return true
+	return (*Reporter)(nil).Report(line, where, message)
 }
 
 func GloxError(tok token.Token, message string) {
+	(*Reporter)(nil).GloxError(tok, message)
+}
+
+// RuntimeErrorReport reports an error raised while the interpreter was executing.
+func RuntimeErrorReport(rtErr *RuntimeError) {
+	(*Reporter)(nil).RuntimeErrorReport(rtErr)
+}
+
+// Reporter reports errors to a writer of its own, for code that must not
+// share Writer, such as a debugging session running alongside others. A
+// nil *Reporter reports to Writer, as the package's functions do.
+type Reporter struct {
+	Writer io.Writer
+	// File, if set, is the path of the file the errors are in, which
+	// is named along with their lines.
+	File string
+}
+
+// ForFile returns a reporter writing where r does that names path as the
+// file its errors are in.
+func (r *Reporter) ForFile(path string) *Reporter {
+	return &Reporter{Writer: r.writer(), File: path}
+}
+
+func (r *Reporter) writer() io.Writer {
+	if r == nil {
+		return Writer
+	}
+	return r.Writer
+}
+
+func (r *Reporter) Report(line uint, where string, message string) bool {
+	if r != nil && r.File != "" {
+		fmt.Fprintf(r.writer(), "[%s, line %d] Error %s: %s\n", r.File, line, where, message)
+	} else {
+		fmt.Fprintf(r.writer(), "[Line %d] Error %s: %s\n", line, where, message)
+	}
+	return true
+}
+
+// GloxError reports an error at tok.
+func (r *Reporter) GloxError(tok token.Token, message string) {
 	if tok.TokenType == token.EOF {
-		Report(tok.Line, " at end", message)
+		r.Report(tok.Line, " at end", message)
 	} else {
-		Report(tok.Line, " at '"+tok.Lexeme+"'", message)
+		r.Report(tok.Line, " at '"+tok.Lexeme+"'", message)
 	}
 }
+
+// RuntimeErrorReport reports an error raised while the interpreter was executing.
+func (r *Reporter) RuntimeErrorReport(rtErr *RuntimeError) {
+	fmt.Fprintf(r.writer(), "%s\n[line %d]\n", rtErr.Message, rtErr.Token.Line)
+}
@@ -5,4 +5,12 @@ import "github.com/nicholasq/glox/token"
 type RuntimeError struct {
 	Token   token.Token
 	Message string
+	// Thrown is set for an error raised by a throw statement, and Value is
+	// the value it threw.
+	Thrown bool
+	Value  interface{}
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message
 }
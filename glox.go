@@ -2,32 +2,263 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/chunk"
+	"github.com/nicholasq/glox/compiler"
+	"github.com/nicholasq/glox/dap"
+	"github.com/nicholasq/glox/debug"
+	err "github.com/nicholasq/glox/error"
 	"github.com/nicholasq/glox/interpreter"
+	"github.com/nicholasq/glox/module"
+	"github.com/nicholasq/glox/native"
+	"github.com/nicholasq/glox/optimize"
 	"github.com/nicholasq/glox/parser"
 	"github.com/nicholasq/glox/scanner"
+	"github.com/nicholasq/glox/util"
+	"github.com/nicholasq/glox/vm"
 )
 
 var hadError = false
 var hadRuntimeError = false
 
+var backend = flag.String("backend", "tree", "execution backend: tree (tree-walking interpreter) or vm (bytecode)")
+
+var traceExecution = flag.Bool("trace-execution", false, "print the vm stack and each instruction as it runs (vm backend only)")
+
+var optimizeAst = flag.Bool("optimize", true, "run constant folding and dead-code elimination before executing")
+
+var dumpOptimized = flag.Bool("dump-optimized", false, "print the optimized syntax tree instead of running the script")
+
+var interp = interpreter.New()
+var machine = vm.New()
+
 func main() {
-	args := os.Args
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
 
-	if len(args) > 2 {
-		fmt.Println("Usage: glox [script]")
+	if *backend != "tree" && *backend != "vm" {
+		fmt.Printf("Unknown backend %q.\n", *backend)
 		os.Exit(64)
-	} else if len(args) == 2 {
-		runFile(args[1])
-	} else {
+	}
+	if *traceExecution {
+		if *backend != "vm" {
+			fmt.Println("--trace-execution requires --backend=vm.")
+			os.Exit(64)
+		}
+		machine.SetTrace(os.Stdout)
+	}
+	if *dumpOptimized && !*optimizeAst {
+		fmt.Println("--dump-optimized can't be used with --optimize=false.")
+		os.Exit(64)
+	}
+	loader := module.New()
+	loader.Optimize = *optimizeAst
+	interp.SetLoader(loader)
+	machine.SetLoader(loader)
+
+	switch {
+	case len(args) == 0:
 		runPrompt()
+	case args[0] == "dap" && len(args) == 1:
+		runDap()
+	case args[0] == "debug" && len(args) == 2:
+		runDebugger(args[1])
+	case args[0] == "tokens" && len(args) == 2:
+		runTokens(args[1], false)
+	case args[0] == "tokens" && len(args) == 3 && args[1] == "--json":
+		runTokens(args[2], true)
+	case args[0] == "ast" && len(args) == 2:
+		runAstPrinter(args[1], false)
+	case args[0] == "ast" && len(args) == 3 && args[1] == "--tree":
+		runAstPrinter(args[2], true)
+	case args[0] == "ast" && len(args) == 3 && args[1] == "--json":
+		runAstJSON(args[2])
+	case args[0] == "disasm" && len(args) == 2:
+		runDisassembler(args[1])
+	case args[0] == "compile" && len(args) == 2:
+		runCompiler(args[1], strings.TrimSuffix(args[1], ".lox")+".loxc")
+	case args[0] == "compile" && len(args) == 4 && args[2] == "-o":
+		runCompiler(args[1], args[3])
+	case args[0] == "run" && len(args) == 2:
+		runBytecode(args[1])
+	case len(args) == 1:
+		runFile(args[0])
+	default:
+		flag.Usage()
+		os.Exit(64)
+	}
+}
+
+const usage = `Usage: glox [flags] [script.lox]
+       glox dap
+       glox debug script.lox
+       glox tokens [--json] script.lox
+       glox ast [--tree | --json] script.lox
+       glox disasm script.lox
+       glox compile script.lox [-o script.loxc]
+       glox run script.loxc
+Flags:
+`
+
+// runDap serves a Debug Adapter Protocol session over stdin and stdout.
+func runDap() {
+	session := dap.NewSession(os.Stdin, os.Stdout)
+	if serveErr := session.Serve(); serveErr != nil {
+		fmt.Fprintln(os.Stderr, "dap:", serveErr)
+		os.Exit(1)
+	}
+}
+
+// runDebugger runs a script under the interactive terminal debugger.
+func runDebugger(fileName string) {
+	source := readScript(fileName)
+	scanner := scanner.New(source)
+	stmts, parseErr := parser.New(scanner.ScanTokens()).Parse()
+	if parseErr != nil {
+		os.Exit(65)
+	}
+	console := debug.NewConsole(fileName, source, os.Stdin, os.Stdout)
+	runErr := console.Run(stmts)
+	if runErr == interpreter.ErrResolve {
+		os.Exit(65)
+	}
+	if runErr != nil {
+		reportRunError(runErr)
+		os.Exit(70)
+	}
+}
+
+// runTokens scans a script and prints its tokens, as a table or as JSON.
+func runTokens(fileName string, asJSON bool) {
+	scanner := scanner.New(readScript(fileName))
+	tokens := scanner.ScanTokens()
+	var printErr error
+	if asJSON {
+		printErr = util.PrintTokensJSON(os.Stdout, tokens)
+	} else {
+		printErr = util.PrintTokens(os.Stdout, tokens)
+	}
+	if printErr != nil {
+		fmt.Println("Error writing tokens: ", printErr)
+		os.Exit(1)
+	}
+}
+
+// runAstPrinter parses a script and prints its syntax tree as
+// S-expressions, indented across lines if tree is set.
+func runAstPrinter(fileName string, tree bool) {
+	printer := util.AstPrinter{Indent: tree}
+	fmt.Print(printer.PrintProgram(parseScript(fileName)))
+}
+
+// runAstJSON parses a script and prints its syntax tree as JSON.
+func runAstJSON(fileName string) {
+	data, marshalErr := ast.MarshalJSON(parseScript(fileName))
+	if marshalErr != nil {
+		fmt.Println("Error encoding syntax tree: ", marshalErr)
+		os.Exit(1)
 	}
+	var indented bytes.Buffer
+	json.Indent(&indented, data, "", "  ")
+	indented.WriteByte('\n')
+	indented.WriteTo(os.Stdout)
+}
+
+// runDisassembler compiles a script and prints its bytecode.
+func runDisassembler(fileName string) {
+	chunk.Disassemble(os.Stdout, compileScript(fileName))
+}
+
+// runCompiler compiles a script and saves its bytecode to outName.
+func runCompiler(fileName string, outName string) {
+	script := compileScript(fileName)
+	file, createErr := os.Create(outName)
+	if createErr != nil {
+		fmt.Println("Error creating file: ", createErr)
+		os.Exit(1)
+	}
+	defer file.Close()
+	if encodeErr := chunk.Encode(file, script); encodeErr != nil {
+		fmt.Println("Error writing bytecode: ", encodeErr)
+		os.Exit(1)
+	}
+}
+
+// runBytecode runs a script previously saved by runCompiler on the vm.
+func runBytecode(fileName string) {
+	if !strings.HasSuffix(fileName, ".loxc") {
+		fmt.Println("File must be a .loxc file.")
+		os.Exit(1)
+	}
+	file, openErr := os.Open(fileName)
+	if openErr != nil {
+		fmt.Println("Error opening file: ", openErr)
+		os.Exit(1)
+	}
+	defer file.Close()
+	machine.SetFile(fileName)
+	script, decodeErr := chunk.Decode(bufio.NewReader(file))
+	if decodeErr != nil {
+		fmt.Printf("Error loading %s: %s\n", fileName, decodeErr)
+		os.Exit(65)
+	}
+	if runErr := machine.Interpret(script); runErr != nil {
+		reportRunError(runErr)
+		os.Exit(70)
+	}
+}
+
+// parseScript scans and parses a .lox file, exiting on errors.
+func parseScript(fileName string) []ast.Stmt {
+	scanner := scanner.New(readScript(fileName))
+	stmts, parseErr := parser.New(scanner.ScanTokens()).Parse()
+	if parseErr != nil {
+		os.Exit(65)
+	}
+	return stmts
+}
+
+// compileScript scans, parses and compiles a .lox file, exiting on errors.
+func compileScript(fileName string) *chunk.Function {
+	stmts := parseScript(fileName)
+	if *optimizeAst {
+		stmts = optimize.Optimize(stmts)
+	}
+	script, compileErr := compiler.Compile(stmts)
+	if compileErr != nil {
+		os.Exit(65)
+	}
+	return script
 }
 
 func runFile(fileName string) {
+	interp.SetFile(fileName)
+	machine.SetFile(fileName)
+	run(readScript(fileName))
+
+	if hadError {
+		os.Exit(65)
+	}
+
+	if hadRuntimeError {
+		os.Exit(70)
+	}
+}
+
+// readScript reads the contents of a .lox file, exiting if it cannot.
+func readScript(fileName string) string {
 	if len(fileName) < 4 || fileName[len(fileName)-4:] != ".lox" {
 		fmt.Println("File must be a .lox file.")
 		os.Exit(1)
@@ -48,16 +279,7 @@ func runFile(fileName string) {
 		os.Exit(1)
 	}
 
-	strContents := string(bytes)
-	run(strContents)
-
-	if hadError {
-		os.Exit(65)
-	}
-
-	if hadRuntimeError {
-		os.Exit(70)
-	}
+	return string(bytes)
 }
 
 func runPrompt() {
@@ -78,17 +300,56 @@ func run(script string) {
 	scanner := scanner.New(script)
 	tokens := scanner.ScanTokens()
 	parser := parser.New(tokens)
-	stmts, err := parser.Parse()
+	stmts, parseErr := parser.Parse()
 
-	if err != nil {
-		// todo add error handling
-		panic(err)
+	if parseErr != nil {
+		hadError = true
 	}
 
 	if hadError {
 		return
 	}
-	interpreter := interpreter.Interpreter{}
-	value := interpreter.Interpret(*stmts)
-	fmt.Printf("%v", value)
+
+	if *optimizeAst {
+		stmts = optimize.Optimize(stmts)
+	}
+	if *dumpOptimized {
+		printer := util.AstPrinter{}
+		fmt.Print(printer.PrintProgram(stmts))
+		return
+	}
+
+	var runErr error
+	if *backend == "vm" {
+		script, compileErr := compiler.Compile(stmts)
+		if compileErr != nil {
+			hadError = true
+			return
+		}
+		runErr = machine.Interpret(script)
+	} else {
+		runErr = interp.Interpret(stmts)
+		if runErr == interpreter.ErrResolve {
+			hadError = true
+			return
+		}
+	}
+	if runErr != nil {
+		reportRunError(runErr)
+		hadRuntimeError = true
+	}
+}
+
+// reportRunError reports an error that stopped a running script. Besides
+// runtime errors, the interpreter can fail to write the script's output.
+// A script that called exit() ends glox with the status it asked for.
+func reportRunError(runErr error) {
+	if exit, ok := runErr.(*native.Exit); ok {
+		os.Exit(exit.Code)
+	}
+	if rtErr, ok := runErr.(*err.RuntimeError); ok {
+		err.RuntimeErrorReport(rtErr)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Error:", runErr)
 }
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/compiler"
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/interpreter"
+	"github.com/nicholasq/glox/module"
+	"github.com/nicholasq/glox/optimize"
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+	"github.com/nicholasq/glox/vm"
+)
+
+var expectOutput = regexp.MustCompile(`// expect: (.*)$`)
+var expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)$`)
+var expectError = regexp.MustCompile(`// expect error: (.+)$`)
+
+// backends runs the program source, read from path, writing its output to
+// out.
+var backends = map[string]func(path, source string, out *bytes.Buffer) error{
+	"tree": func(path, source string, out *bytes.Buffer) error {
+		stmts, parseErr := parse(source)
+		if parseErr != nil {
+			return parseErr
+		}
+		interp := interpreter.New()
+		interp.SetOutput(out)
+		interp.SetFile(path)
+		return interp.Interpret(stmts)
+	},
+	"optimized": func(path, source string, out *bytes.Buffer) error {
+		stmts, parseErr := parse(source)
+		if parseErr != nil {
+			return parseErr
+		}
+		loader := module.New()
+		loader.Optimize = true
+		interp := interpreter.New()
+		interp.SetOutput(out)
+		interp.SetFile(path)
+		interp.SetLoader(loader)
+		return interp.Interpret(optimize.Optimize(stmts))
+	},
+	"vm": func(path, source string, out *bytes.Buffer) error {
+		stmts, parseErr := parse(source)
+		if parseErr != nil {
+			return parseErr
+		}
+		script, compileErr := compiler.Compile(stmts)
+		if compileErr != nil {
+			return compileErr
+		}
+		machine := vm.New()
+		machine.SetOutput(out)
+		machine.SetFile(path)
+		return machine.Interpret(script)
+	},
+	"optimized vm": func(path, source string, out *bytes.Buffer) error {
+		stmts, parseErr := parse(source)
+		if parseErr != nil {
+			return parseErr
+		}
+		script, compileErr := compiler.Compile(optimize.Optimize(stmts))
+		if compileErr != nil {
+			return compileErr
+		}
+		loader := module.New()
+		loader.Optimize = true
+		machine := vm.New()
+		machine.SetOutput(out)
+		machine.SetFile(path)
+		machine.SetLoader(loader)
+		return machine.Interpret(script)
+	},
+}
+
+// TestMain runs glox's main function instead of the tests when the test
+// binary is re-run by runGlox.
+func TestMain(m *testing.M) {
+	if os.Getenv("GLOX_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runGlox runs glox with args, returning its standard output and exit
+// status.
+func runGlox(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "GLOX_TEST_MAIN=1")
+	out, runErr := cmd.Output()
+	if exitErr, ok := runErr.(*exec.ExitError); ok {
+		return string(out), exitErr.ExitCode()
+	} else if runErr != nil {
+		t.Fatal(runErr)
+	}
+	return string(out), 0
+}
+
+func TestRunBytecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		output   string
+		exitCode int
+	}{
+		{"exit", "print 1;\nexit(3);\nprint 2;\n", "1\n", 3},
+		{"uncaught throw", "throw \"oops\";\n", "Uncaught exception: oops\n[line 1]\n", 70},
+		{"runtime error", "print -nil;\n", "Operand must be a number.\n[line 1]\n", 70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			source := filepath.Join(dir, "script.lox")
+			compiled := filepath.Join(dir, "script.loxc")
+			if writeErr := os.WriteFile(source, []byte(tt.source), 0o644); writeErr != nil {
+				t.Fatal(writeErr)
+			}
+			if out, code := runGlox(t, "compile", source, "-o", compiled); code != 0 {
+				t.Fatalf("compile exited with %d: %s", code, out)
+			}
+			out, code := runGlox(t, "run", compiled)
+			if code != tt.exitCode {
+				t.Errorf("Expected exit status %d, got %d", tt.exitCode, code)
+			}
+			if out != tt.output {
+				t.Errorf("Expected output %q, got %q", tt.output, out)
+			}
+		})
+	}
+}
+
+func TestDumpOptimized(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "script.lox")
+	if writeErr := os.WriteFile(source, []byte("print 0 / 0;\n"), 0o644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	if out, code := runGlox(t, "--dump-optimized", source); code != 0 || out != "(print +NaN)\n" {
+		t.Errorf("Expected (print +NaN) and status 0, got %q and %d", out, code)
+	}
+	out, code := runGlox(t, "--dump-optimized", "--optimize=false", source)
+	if expected := "--dump-optimized can't be used with --optimize=false.\n"; code != 64 || out != expected {
+		t.Errorf("Expected %q and status 64, got %q and %d", expected, out, code)
+	}
+}
+
+func parse(source string) ([]ast.Stmt, error) {
+	s := scanner.New(source)
+	return parser.New(s.ScanTokens()).Parse()
+}
+
+// TestScripts runs every script under test/ on each backend and checks its
+// output against the "// expect: " and "// expect runtime error: "
+// comments in the script. A script with an "// expect error: " comment
+// must instead be rejected before it runs, with that error reported on the
+// comment's line.
+func TestScripts(t *testing.T) {
+	paths, globErr := filepath.Glob(filepath.Join("test", "*.lox"))
+	if globErr != nil {
+		t.Fatal(globErr)
+	}
+
+	for _, path := range paths {
+		source, readErr := os.ReadFile(path)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+
+		var expected []string
+		var expectedError, expectedStatic string
+		for idx, line := range strings.Split(string(source), "\n") {
+			if match := expectError.FindStringSubmatch(line); match != nil {
+				expectedStatic = fmt.Sprintf("[Line %d] Error", idx+1)
+				expectedError = match[1]
+			}
+			if match := expectOutput.FindStringSubmatch(line); match != nil {
+				expected = append(expected, match[1])
+			}
+			if match := expectRuntimeError.FindStringSubmatch(line); match != nil {
+				expectedError = fmt.Sprintf("%s [line %d]", match[1], idx+1)
+			}
+		}
+
+		for name, run := range backends {
+			t.Run(filepath.Base(path)+"/"+name, func(t *testing.T) {
+				var out bytes.Buffer
+				if expectedStatic != "" {
+					var reported bytes.Buffer
+					err.Writer = &reported
+					runErr := run(path, string(source), &out)
+					err.Writer = os.Stdout
+					if _, ok := runErr.(*err.RuntimeError); ok || runErr == nil {
+						t.Fatalf("Expected the script to be rejected, got %v", runErr)
+					}
+					if !strings.HasPrefix(reported.String(), expectedStatic) || !strings.Contains(reported.String(), ": "+expectedError) {
+						t.Fatalf("Expected %s ...: %s, got %q", expectedStatic, expectedError, reported.String())
+					}
+					if out.Len() != 0 {
+						t.Fatalf("Expected no output, got %q", out.String())
+					}
+					return
+				}
+				runErr := run(path, string(source), &out)
+
+				actualError := ""
+				if rtErr, ok := runErr.(*err.RuntimeError); ok {
+					actualError = fmt.Sprintf("%s [line %d]", rtErr.Message, rtErr.Token.Line)
+				} else if runErr != nil {
+					t.Fatalf("Unexpected error: %s", runErr)
+				}
+				if actualError != expectedError {
+					t.Fatalf("Expected runtime error %q, got %q", expectedError, actualError)
+				}
+
+				actual := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+				if out.Len() == 0 {
+					actual = nil
+				}
+				if len(actual) != len(expected) {
+					t.Fatalf("Expected output %q, got %q", expected, actual)
+				}
+				for idx := range expected {
+					if actual[idx] != expected[idx] {
+						t.Fatalf("Line %d: expected %q, got %q", idx+1, expected[idx], actual[idx])
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package interpreter
+
+import (
+	"errors"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/environment"
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/native"
+)
+
+// maxFrames is the deepest the call stack can grow, the same limit the vm
+// has, so that runaway recursion fails the same way on both backends.
+const maxFrames = 64
+
+// errStackOverflow is returned by a call that would exceed maxFrames.
+var errStackOverflow = errors.New("Stack overflow.")
+
+// returnValue is returned by a return statement. It unwinds execution to
+// the function being run, which stops it and returns value.
+type returnValue struct {
+	value interface{}
+}
+
+func (r *returnValue) Error() string { return "return outside of a function" }
+
+// function is the runtime value of a lambda: its declaration along with
+// the environment it was created in, which its body can see.
+type function struct {
+	declaration *ast.Lambda
+	closure     *environment.Environment
+	interp      *Interpreter
+}
+
+var _ native.Callable = (*function)(nil)
+
+func (f *function) Arity() int { return len(f.declaration.Params) }
+
+// Call runs the function's body in a new scope, enclosed by the closure,
+// that binds each parameter to its argument. Its frame is popped however
+// the body ends; a runtime error raised by the body has the call stack
+// recorded first, so that a try statement that catches it can report
+// where it was raised.
+func (f *function) Call(ctx *native.Context, args []interface{}) (interface{}, error) {
+	i := f.interp
+	if len(i.frames) >= maxFrames {
+		return nil, errStackOverflow
+	}
+	env := environment.New(f.closure)
+	for idx, param := range f.declaration.Params {
+		env.Define(param.Lexeme, args[idx])
+	}
+	depth := len(i.frames)
+	i.frames = append(i.frames, Frame{Name: "lambda", Line: f.declaration.Line, Env: env})
+	defer func() {
+		if r := recover(); r != nil {
+			if rtErr, ok := r.(*err.RuntimeError); ok && i.unwinding != rtErr {
+				i.unwinding, i.unwound = rtErr, i.stackTrace(rtErr)
+			}
+			i.frames = i.frames[:depth]
+			panic(r)
+		}
+		i.frames = i.frames[:depth]
+	}()
+	execErr := i.executeBlock(f.declaration.Body, env)
+	if ret, ok := execErr.(*returnValue); ok {
+		return ret.value, nil
+	}
+	return nil, execErr
+}
+
+func (f *function) String() string { return "<fn lambda>" }
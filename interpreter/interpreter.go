@@ -1,40 +1,233 @@
 package interpreter
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
 
 	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/environment"
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/module"
+	"github.com/nicholasq/glox/native"
 	"github.com/nicholasq/glox/token"
 )
 
+// Hook is called immediately before the interpreter executes a statement.
+// Debuggers use it to inspect state and to pause execution.
+type Hook func(stmt ast.Stmt)
+
+// Frame describes an active entry on the interpreter's call stack.
+type Frame struct {
+	Name string
+	Line uint
+	Env  *environment.Environment
+}
+
+// LoxCallable is a value that Lox code can call, such as a native function.
+type LoxCallable = native.Callable
+
+// LoxList is the runtime value of a list.
+type LoxList = native.List
+
+// errBreak and errContinue are returned by break and continue statements.
+// They unwind execution to the innermost enclosing loop, which the parser
+// guarantees there is, and that loop stops them.
+var (
+	errBreak    = errors.New("break outside of a loop")
+	errContinue = errors.New("continue outside of a loop")
+)
+
 type Interpreter struct {
-	globals     interface{}
-	environment interface{}
-	locals      map[interface{}]int
+	globals     *environment.Environment
+	environment *environment.Environment
+	// locals maps each variable and assignment expression the resolver has
+	// seen to the number of scopes between it and its declaration, or to
+	// global.
+	locals  map[interface{}]int
+	out     io.Writer
+	context *native.Context
+	hook    Hook
+	frames  []Frame
+	// unwinding is the runtime error most recently raised through a call,
+	// and unwound is the call stack where it was raised, recorded before
+	// the call's frame was popped.
+	unwinding *err.RuntimeError
+	unwound   []string
+	// file is the path of the script being run, which imports are
+	// resolved against, and loader loads the modules it imports.
+	file   string
+	loader *module.Loader
+	// reporter is where the resolver reports errors, the error package's
+	// Writer if it is nil.
+	reporter *err.Reporter
+}
+
+var (
+	_ ast.ExprVisitor[interface{}] = (*Interpreter)(nil)
+	_ ast.StmtVisitor[error]       = (*Interpreter)(nil)
+)
+
+// New returns an interpreter whose global scope holds the native functions.
+func New() *Interpreter {
+	globals := environment.New(nil)
+	for name, value := range native.Globals {
+		globals.Define(name, value)
+	}
+	return &Interpreter{
+		globals:     globals,
+		environment: globals,
+		locals:      make(map[interface{}]int),
+		out:         os.Stdout,
+		context:     &native.Context{In: bufio.NewReader(os.Stdin)},
+		frames:      []Frame{{Name: "script", Env: globals}},
+		loader:      module.New(),
+	}
+}
+
+// SetOutput sets the writer that print statements write to.
+func (i *Interpreter) SetOutput(w io.Writer) {
+	i.out = w
+}
+
+// SetInput sets the reader that input() and readLine() read from.
+func (i *Interpreter) SetInput(r io.Reader) {
+	i.context.In = bufio.NewReader(r)
+}
+
+// SetFile sets the path of the script being run, which the paths of its
+// imports are relative to. Without one they are relative to the working
+// directory.
+func (i *Interpreter) SetFile(path string) {
+	i.file = path
+}
+
+// SetLoader sets the loader that imports modules, replacing the one New
+// created.
+func (i *Interpreter) SetLoader(loader *module.Loader) {
+	i.loader = loader
+}
+
+// SetReporter sets where errors found before the program runs are reported.
+func (i *Interpreter) SetReporter(reporter *err.Reporter) {
+	i.reporter = reporter
+}
+
+// Reporter returns where errors found before the program runs are reported.
+func (i *Interpreter) Reporter() *err.Reporter {
+	return i.reporter
+}
+
+// SetHook installs hook to be called before every statement. A nil hook removes it.
+func (i *Interpreter) SetHook(hook Hook) {
+	i.hook = hook
 }
 
-func (i *Interpreter) Interpret(statements []ast.Stmt) error {
-	// todo figure out a way to return errors from evaluate
+// Globals returns the global environment.
+func (i *Interpreter) Globals() *environment.Environment {
+	return i.globals
+}
+
+// CallStack returns a snapshot of the active frames, innermost first.
+func (i *Interpreter) CallStack() []Frame {
+	frames := make([]Frame, len(i.frames))
+	for idx, frame := range i.frames {
+		frames[len(i.frames)-1-idx] = frame
+	}
+	return frames
+}
+
+// Interpret resolves the variables of statements and then executes them in
+// order. It returns ErrResolve without running anything if resolving
+// reports errors. Otherwise it stops at the first error: a runtime error is
+// returned as an *error.RuntimeError, a call of exit() as a *native.Exit,
+// and a failure to write output as is.
+func (i *Interpreter) Interpret(statements []ast.Stmt) (result error) {
+	if resolveErr := i.resolve(statements); resolveErr != nil {
+		return resolveErr
+	}
+	depth := len(i.frames)
+	defer func() {
+		if r := recover(); r != nil {
+			i.frames = i.frames[:depth]
+			i.unwinding, i.unwound = nil, nil
+			result = recoverError(r)
+		}
+	}()
 	for _, stmt := range statements {
-		i.execute(stmt)
+		if execErr := i.execute(stmt); execErr != nil {
+			return execErr
+		}
 	}
 	return nil
 }
 
-func (i *Interpreter) execute(stmt ast.Stmt) {
-	stmt.Accept(i)
+// Evaluate evaluates expr with env as the current environment and returns
+// its value, or the runtime error it raised.
+func (i *Interpreter) Evaluate(expr ast.Expr, env *environment.Environment) (value interface{}, result error) {
+	previous := i.environment
+	i.environment = env
+	depth := len(i.frames)
+	defer func() {
+		i.environment = previous
+		if r := recover(); r != nil {
+			i.frames = i.frames[:depth]
+			i.unwinding, i.unwound = nil, nil
+			value, result = nil, recoverError(r)
+		}
+	}()
+	return i.evaluate(expr), nil
 }
 
-func stringify(value interface{}) string {
-	if value == nil {
-		return "nil"
+// recoverError returns the error carried by a panic that ended execution,
+// re-panicking if the panic was not raised by the program.
+func recoverError(r interface{}) error {
+	switch r := r.(type) {
+	case *err.RuntimeError:
+		return r
+	case *native.Exit:
+		return r
 	}
-	if flt, ok := value.(float64); ok {
-		fltStr := fmt.Sprintf("%v", flt)
-		return fltStr
+	panic(r)
+}
+
+// execute runs a single statement. Runtime errors raised while evaluating
+// expressions panic and are recovered by Interpret; execute itself returns
+// the errors statements report directly.
+func (i *Interpreter) execute(stmt ast.Stmt) error {
+	frame := &i.frames[len(i.frames)-1]
+	frame.Line = stmt.Pos()
+	frame.Env = i.environment
+	if i.hook != nil {
+		i.hook(stmt)
 	}
+	return ast.AcceptStmt[error](stmt, i)
+}
 
-	return fmt.Sprintf("%v", value)
+// Stringify formats a Lox value the way print displays it.
+func Stringify(value interface{}) string {
+	return native.Stringify(value)
+}
+
+func (i *Interpreter) VisitAssignExpr(expr *ast.Assign) interface{} {
+	value := i.evaluate(expr.Value)
+	distance, ok := i.locals[expr]
+	switch {
+	case ok && distance != global:
+		i.environment.AssignAt(distance, expr.Name.Lexeme, value)
+	case ok:
+		if assignErr := i.globals.Assign(expr.Name, value); assignErr != nil {
+			panic(&err.RuntimeError{Token: expr.Name, Message: assignErr.Error()})
+		}
+	default:
+		if assignErr := i.environment.Assign(expr.Name, value); assignErr != nil {
+			panic(&err.RuntimeError{Token: expr.Name, Message: assignErr.Error()})
+		}
+	}
+	return value
 }
 
 func (i *Interpreter) VisitBinaryExpr(expr *ast.Binary) interface{} {
@@ -43,92 +236,461 @@ func (i *Interpreter) VisitBinaryExpr(expr *ast.Binary) interface{} {
 
 	switch expr.Operator.TokenType {
 	case token.MINUS:
-		return left.(float64) - right.(float64)
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return l - r
 	case token.SLASH:
-		return left.(float64) / right.(float64)
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return l / r
 	case token.STAR:
-		return left.(float64) * right.(float64)
-	case token.PLUS: // todo: add string concatenation
-		return left.(float64) + right.(float64)
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return l * r
+	case token.PERCENT:
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return native.Modulo(l, r)
+	case token.TILDE_SLASH:
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return native.FloorDivide(l, r)
+	case token.STAR_STAR:
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return math.Pow(l, r)
+	case token.AMPERSAND, token.PIPE, token.CARET, token.LESS_LESS, token.GREATER_GREATER:
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		result, bitErr := native.Bitwise(expr.Operator.TokenType, l, r)
+		if bitErr != nil {
+			panic(&err.RuntimeError{Token: expr.Operator, Message: bitErr.Error()})
+		}
+		return result
+	case token.PLUS:
+		if l, ok := left.(float64); ok {
+			if r, ok := right.(float64); ok {
+				return l + r
+			}
+		}
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r
+			}
+		}
+		panic(&err.RuntimeError{Token: expr.Operator, Message: "Operands must be two numbers or two strings."})
 	case token.GREATER:
-		return left.(float64) > right.(float64)
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return l > r
 	case token.GREATER_EQUAL:
-		return left.(float64) >= right.(float64)
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return l >= r
 	case token.LESS:
-		return left.(float64) < right.(float64)
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return l < r
 	case token.LESS_EQUAL:
-		return left.(float64) <= right.(float64)
+		l, r := checkNumberOperands(expr.Operator, left, right)
+		return l <= r
 	case token.BANG_EQUAL:
 		return !isEqual(left, right)
 	case token.EQUAL_EQUAL:
 		return isEqual(left, right)
+	case token.COMMA:
+		return right
 	}
 	return nil
 }
 
+func (i *Interpreter) VisitCallExpr(expr *ast.Call) interface{} {
+	callee := i.evaluate(expr.Callee)
+	args := make([]interface{}, 0, len(expr.Arguments))
+	for _, argument := range expr.Arguments {
+		args = append(args, i.evaluate(argument))
+	}
+
+	function, ok := callee.(LoxCallable)
+	if !ok {
+		panic(&err.RuntimeError{Token: expr.Paren, Message: "Can only call functions."})
+	}
+	if arity := function.Arity(); arity >= 0 && len(args) != arity {
+		panic(&err.RuntimeError{Token: expr.Paren, Message: fmt.Sprintf("Expected %d arguments but got %d.", arity, len(args))})
+	}
+	result, callErr := function.Call(i.context, args)
+	if callErr != nil {
+		if exit, ok := callErr.(*native.Exit); ok {
+			panic(exit)
+		}
+		panic(&err.RuntimeError{Token: expr.Paren, Message: callErr.Error()})
+	}
+	return result
+}
+
+func (i *Interpreter) VisitConditionalExpr(expr *ast.Conditional) interface{} {
+	if isTruthy(i.evaluate(expr.Condition)) {
+		return i.evaluate(expr.ThenBranch)
+	}
+	return i.evaluate(expr.ElseBranch)
+}
+
+func (i *Interpreter) VisitGetExpr(expr *ast.Get) interface{} {
+	value, getErr := native.Property(i.evaluate(expr.Object), expr.Name.Lexeme)
+	if getErr != nil {
+		panic(&err.RuntimeError{Token: expr.Name, Message: getErr.Error()})
+	}
+	return value
+}
+
 func (i *Interpreter) VisitGroupingExpr(expr *ast.Grouping) interface{} {
 	return i.evaluate(expr.Expression)
 }
 
+func (i *Interpreter) VisitIndexExpr(expr *ast.Index) interface{} {
+	object := i.evaluate(expr.Object)
+	index := i.evaluate(expr.Index)
+	value, indexErr := native.Index(object, index)
+	if indexErr != nil {
+		panic(&err.RuntimeError{Token: expr.Bracket, Message: indexErr.Error()})
+	}
+	return value
+}
+
+func (i *Interpreter) VisitIndexSetExpr(expr *ast.IndexSet) interface{} {
+	object := i.evaluate(expr.Object)
+	index := i.evaluate(expr.Index)
+	value := i.evaluate(expr.Value)
+	if setErr := native.SetIndex(object, index, value); setErr != nil {
+		panic(&err.RuntimeError{Token: expr.Bracket, Message: setErr.Error()})
+	}
+	return value
+}
+
+// VisitLambdaExpr creates a function that closes over the current
+// environment.
+func (i *Interpreter) VisitLambdaExpr(expr *ast.Lambda) interface{} {
+	return &function{declaration: expr, closure: i.environment, interp: i}
+}
+
+func (i *Interpreter) VisitListLiteralExpr(expr *ast.ListLiteral) interface{} {
+	list := &LoxList{Elements: make([]interface{}, 0, len(expr.Elements))}
+	for _, element := range expr.Elements {
+		list.Elements = append(list.Elements, i.evaluate(element))
+	}
+	return list
+}
+
 func (i *Interpreter) VisitLiteralExpr(expr *ast.Literal) interface{} {
 	return expr.Value
 }
 
+func (i *Interpreter) VisitMapLiteralExpr(expr *ast.MapLiteral) interface{} {
+	m := native.NewMap()
+	for idx, key := range expr.Keys {
+		k := i.evaluate(key)
+		v := i.evaluate(expr.Values[idx])
+		if storeErr := m.Store(k, v); storeErr != nil {
+			panic(&err.RuntimeError{Token: expr.Brace, Message: storeErr.Error()})
+		}
+	}
+	return m
+}
+
+func (i *Interpreter) VisitSliceExpr(expr *ast.Slice) interface{} {
+	object := i.evaluate(expr.Object)
+	var low, high interface{}
+	if expr.Low != nil {
+		low = i.evaluate(expr.Low)
+	}
+	if expr.High != nil {
+		high = i.evaluate(expr.High)
+	}
+	value, sliceErr := native.Slice(object, low, high)
+	if sliceErr != nil {
+		panic(&err.RuntimeError{Token: expr.Bracket, Message: sliceErr.Error()})
+	}
+	return value
+}
+
 func (i *Interpreter) VisitUnaryExpr(expr *ast.Unary) interface{} {
 	right := i.evaluate(expr.Right)
 
 	switch expr.Operator.TokenType {
 	case token.MINUS:
-		return -(right.(float64))
+		return -checkNumberOperand(expr.Operator, right)
+	case token.TILDE:
+		result, bitErr := native.Complement(checkNumberOperand(expr.Operator, right))
+		if bitErr != nil {
+			panic(&err.RuntimeError{Token: expr.Operator, Message: bitErr.Error()})
+		}
+		return result
 	case token.BANG:
-		return isTruthy(right)
+		return !isTruthy(right)
 	default:
 		return nil
 	}
 }
 
-func (i *Interpreter) VisitVariableExpr(expr *ast.Variable) interface{}
+func (i *Interpreter) VisitVariableExpr(expr *ast.Variable) interface{} {
+	value, getErr := i.lookUpVariable(expr.Name, expr)
+	if getErr != nil {
+		panic(&err.RuntimeError{Token: expr.Name, Message: getErr.Error()})
+	}
+	return value
+}
+
+// lookUpVariable returns the value of the variable that expr, named name,
+// was resolved to. An expression that was never resolved, such as one a
+// debugger evaluates in some frame, looks name up through the enclosing
+// scopes instead.
+func (i *Interpreter) lookUpVariable(name token.Token, expr ast.Expr) (interface{}, error) {
+	distance, ok := i.locals[expr]
+	switch {
+	case ok && distance != global:
+		return i.environment.GetAt(distance, name.Lexeme), nil
+	case ok:
+		return i.globals.Get(name)
+	}
+	return i.environment.Get(name)
+}
+
+func (i *Interpreter) VisitBlockStmt(stmt *ast.BlockStmt) error {
+	return i.executeBlock(stmt.Statements, environment.New(i.environment))
+}
+
+// executeBlock runs statements with env as the current environment,
+// restoring the previous one afterwards even if a runtime error unwinds.
+func (i *Interpreter) executeBlock(statements []ast.Stmt, env *environment.Environment) error {
+	previous := i.environment
+	i.environment = env
+	defer func() {
+		i.environment = previous
+	}()
+	for _, stmt := range statements {
+		if execErr := i.execute(stmt); execErr != nil {
+			return execErr
+		}
+	}
+	return nil
+}
 
-func (i *Interpreter) VisitExpressionStmt(stmt *ast.ExpressionStmt) {
+func (i *Interpreter) VisitExpressionStmt(stmt *ast.ExpressionStmt) error {
 	i.evaluate(stmt.Expression)
+	return nil
 }
 
-func (i *Interpreter) VisitPrintStmt(stmt *ast.PrintStmt) {
-	value := i.evaluate(stmt.Expression)
-	strValue := stringify(value)
-	fmt.Printf("%v\n", strValue)
+// VisitForInStmt runs the loop body in a new scope for each element, so
+// that every iteration has its own binding of the loop variable.
+func (i *Interpreter) VisitForInStmt(stmt *ast.ForInStmt) error {
+	iterator, iterErr := native.Iterate(i.context, i.evaluate(stmt.Iterable))
+	if iterErr != nil {
+		i.iterationError(stmt.In, iterErr)
+	}
+	for {
+		element, ok, nextErr := iterator.Next()
+		if nextErr != nil {
+			i.iterationError(stmt.In, nextErr)
+		}
+		if !ok {
+			break
+		}
+		env := environment.New(i.environment)
+		env.Define(stmt.Name.Lexeme, element)
+		execErr := i.executeBlock([]ast.Stmt{stmt.Body}, env)
+		if execErr == errBreak {
+			break
+		}
+		if execErr != nil && execErr != errContinue {
+			return execErr
+		}
+	}
+	return nil
+}
+
+// iterationError raises an error returned while starting or stepping
+// through a for-in loop, ending the program if it came from exit().
+func (i *Interpreter) iterationError(in token.Token, iterErr error) {
+	if exit, ok := iterErr.(*native.Exit); ok {
+		panic(exit)
+	}
+	panic(&err.RuntimeError{Token: in, Message: iterErr.Error()})
 }
 
-func (i *Interpreter) VisitVarStmt(stmt *ast.VarStmt) {
+func (i *Interpreter) VisitWhileStmt(stmt *ast.WhileStmt) error {
+	for isTruthy(i.evaluate(stmt.Condition)) {
+		execErr := i.execute(stmt.Body)
+		if execErr == errBreak {
+			break
+		}
+		if execErr != nil && execErr != errContinue {
+			return execErr
+		}
+		if stmt.Increment != nil {
+			i.evaluate(stmt.Increment)
+		}
+	}
+	return nil
+}
 
+func (i *Interpreter) VisitIfStmt(stmt *ast.IfStmt) error {
+	if isTruthy(i.evaluate(stmt.Condition)) {
+		return i.execute(stmt.ThenBranch)
+	}
+	if stmt.ElseBranch != nil {
+		return i.execute(stmt.ElseBranch)
+	}
+	return nil
 }
 
-func (i *Interpreter) evaluate(expr ast.Expr) interface{} {
-	return expr.Accept(i)
+func (i *Interpreter) VisitReturnStmt(stmt *ast.ReturnStmt) error {
+	var value interface{}
+	if stmt.Value != nil {
+		value = i.evaluate(stmt.Value)
+	}
+	return &returnValue{value: value}
 }
 
-func isTruthy(v interface{}) bool {
-	if v == nil {
-		return false
+func (i *Interpreter) VisitThrowStmt(stmt *ast.ThrowStmt) error {
+	panic(native.Throw(i.evaluate(stmt.Value), stmt.Keyword))
+}
+
+func (i *Interpreter) VisitTryStmt(stmt *ast.TryStmt) error {
+	execErr, caught, stack := i.protect(func() error {
+		return i.execute(stmt.Body)
+	})
+	if caught != nil && stmt.Catch != nil {
+		env := environment.New(i.environment)
+		env.Define(stmt.CatchName.Lexeme, native.Caught(caught, stack))
+		execErr, caught, _ = i.protect(func() error {
+			return i.executeBlock([]ast.Stmt{stmt.Catch}, env)
+		})
 	}
-	truth, ok := v.(bool)
-	if ok {
-		return truth
-	} else {
-		return true
+	if stmt.Finally != nil {
+		// A break, continue or exception in the finally clause replaces
+		// whatever the statement was going to do.
+		if finallyErr := i.execute(stmt.Finally); finallyErr != nil {
+			return finallyErr
+		}
+	}
+	if caught != nil {
+		panic(caught)
 	}
+	return execErr
 }
 
-func checkNumberOperand(v interface{}) float64 {
+// protect runs fn and returns its result. If fn raises a runtime error,
+// protect returns it instead, along with the call stack where it was
+// raised, and restores the interpreter to the state it was in beforehand.
+func (i *Interpreter) protect(fn func() error) (result error, caught *err.RuntimeError, stack []string) {
+	depth := len(i.frames)
+	env := i.environment
+	defer func() {
+		if r := recover(); r != nil {
+			rtErr, ok := r.(*err.RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			stack = i.stackTrace(rtErr)
+			if i.unwinding == rtErr {
+				stack = i.unwound
+			}
+			i.unwinding, i.unwound = nil, nil
+			i.frames = i.frames[:depth]
+			i.environment = env
+			result, caught = nil, rtErr
+		}
+	}()
+	return fn(), nil, nil
+}
+
+// stackTrace describes the active frames, innermost first, taking the line
+// of rtErr as the line being run in the innermost one.
+func (i *Interpreter) stackTrace(rtErr *err.RuntimeError) []string {
+	stack := make([]string, 0, len(i.frames))
+	for idx := len(i.frames) - 1; idx >= 0; idx-- {
+		line := i.frames[idx].Line
+		if idx == len(i.frames)-1 {
+			line = rtErr.Token.Line
+		}
+		stack = append(stack, native.StackEntry(i.frames[idx].Name, line))
+	}
+	return stack
+}
+
+func (i *Interpreter) VisitBreakStmt(stmt *ast.BreakStmt) error {
+	return errBreak
+}
+
+func (i *Interpreter) VisitContinueStmt(stmt *ast.ContinueStmt) error {
+	return errContinue
+}
+
+func (i *Interpreter) VisitImportStmt(stmt *ast.ImportStmt) error {
+	ns, loadErr := i.loader.Load(i.file, stmt.Path.Literal.(string), i.runModule)
+	if loadErr != nil {
+		panic(module.ImportError(loadErr, stmt.Keyword))
+	}
+	if stmt.Names == nil {
+		i.environment.Define(stmt.Alias.Lexeme, ns)
+		return nil
+	}
+	for _, name := range stmt.Names {
+		member, memberErr := module.Member(ns, name.Lexeme)
+		if memberErr != nil {
+			panic(&err.RuntimeError{Token: name, Message: memberErr.Error()})
+		}
+		i.environment.Define(name.Lexeme, member)
+	}
+	return nil
+}
+
+// runModule runs an imported module in an interpreter of its own, which
+// shares this one's input, output, loader and reporter.
+func (i *Interpreter) runModule(path string, stmts []ast.Stmt) (map[string]interface{}, error) {
+	child := New()
+	child.out, child.context, child.loader, child.file = i.out, i.context, i.loader, path
+	child.reporter = i.reporter
+	if runErr := child.Interpret(stmts); runErr != nil {
+		return nil, runErr
+	}
+	return child.globals.Values(), nil
+}
+
+func (i *Interpreter) VisitPrintStmt(stmt *ast.PrintStmt) error {
+	value := i.evaluate(stmt.Expression)
+	strValue := Stringify(value)
+	_, writeErr := fmt.Fprintf(i.out, "%v\n", strValue)
+	return writeErr
+}
+
+func (i *Interpreter) VisitVarStmt(stmt *ast.VarStmt) error {
+	var value interface{}
+	if stmt.Initializer != nil {
+		value = i.evaluate(stmt.Initializer)
+	}
+	i.environment.Define(stmt.Name.Lexeme, value)
+	return nil
+}
+
+func (i *Interpreter) evaluate(expr ast.Expr) interface{} {
+	return ast.AcceptExpr[interface{}](expr, i)
+}
+
+func isTruthy(v interface{}) bool {
+	return native.IsTruthy(v)
+}
+
+func checkNumberOperand(operator token.Token, v interface{}) float64 {
 	num, ok := v.(float64)
 	if ok {
 		return num
 	} else {
-		panic("operand must be a number")
+		panic(&err.RuntimeError{Token: operator, Message: "Operand must be a number."})
+	}
+}
+
+func checkNumberOperands(operator token.Token, left interface{}, right interface{}) (float64, float64) {
+	l, lOk := left.(float64)
+	r, rOk := right.(float64)
+	if !lOk || !rOk {
+		panic(&err.RuntimeError{Token: operator, Message: "Operands must be numbers."})
 	}
+	return l, r
 }
 
-// todo: figure out how to compare the many different possible data types
+// isEqual implements Lox equality. Values of different types are never
+// equal; nil, booleans, numbers and strings compare by value.
 func isEqual(a interface{}, b interface{}) bool {
 
 	if a == nil && b == nil {
@@ -137,5 +699,5 @@ func isEqual(a interface{}, b interface{}) bool {
 	if a == nil {
 		return false
 	}
-	return false
+	return a == b
 }
@@ -0,0 +1,321 @@
+package interpreter
+
+import (
+	"errors"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/token"
+)
+
+// ErrResolve is returned by Interpret when the program refers to variables
+// in a way that can be rejected before running it, such as reading a local
+// variable in its own initializer. The errors themselves are reported
+// through the interpreter's reporter.
+var ErrResolve = errors.New("resolve error")
+
+// global is the distance recorded in locals for a variable that refers to
+// a global.
+const global = -1
+
+// resolver works out, before a program runs, which declaration each
+// variable refers to. It records in the interpreter's locals how many
+// scopes lie between each variable expression and its declaration, so that
+// a closure keeps referring to the variables that were in scope where it
+// was written, whatever is declared around it later.
+//
+// The scopes it tracks must match the environments the interpreter
+// creates: one for each block, function call, for-in iteration and catch
+// clause. Variables declared outside all of them are globals.
+type resolver struct {
+	interp *Interpreter
+	// scopes are the local scopes enclosing the code being resolved,
+	// innermost last. Each maps the names declared in it to whether their
+	// initializers have been resolved.
+	scopes   []map[string]bool
+	hadError bool
+}
+
+var (
+	_ ast.ExprVisitor[struct{}] = (*resolver)(nil)
+	_ ast.StmtVisitor[struct{}] = (*resolver)(nil)
+)
+
+// resolve resolves the variables of statements, reporting ErrResolve if
+// any of them were rejected.
+func (i *Interpreter) resolve(statements []ast.Stmt) error {
+	r := &resolver{interp: i}
+	r.resolveStmts(statements)
+	if r.hadError {
+		return ErrResolve
+	}
+	return nil
+}
+
+func (r *resolver) resolveStmts(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		r.resolveStmt(stmt)
+	}
+}
+
+func (r *resolver) resolveStmt(stmt ast.Stmt) {
+	ast.AcceptStmt[struct{}](stmt, r)
+}
+
+func (r *resolver) resolveExpr(expr ast.Expr) {
+	if expr != nil {
+		ast.AcceptExpr[struct{}](expr, r)
+	}
+}
+
+func (r *resolver) beginScope() {
+	r.scopes = append(r.scopes, map[string]bool{})
+}
+
+func (r *resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// declare adds name to the innermost scope without marking it ready to be
+// read, so that its initializer can't refer to it.
+func (r *resolver) declare(name token.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[name.Lexeme]; ok {
+		r.error(name, "Already a variable with this name in this scope.")
+	}
+	scope[name.Lexeme] = false
+}
+
+// define marks name as ready to be read in the innermost scope.
+func (r *resolver) define(name token.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.Lexeme] = true
+}
+
+// resolveLocal records the distance from expr to the innermost scope that
+// declares name, or that it refers to a global if none does.
+func (r *resolver) resolveLocal(expr ast.Expr, name token.Token) {
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		if _, ok := r.scopes[idx][name.Lexeme]; ok {
+			r.interp.locals[expr] = len(r.scopes) - 1 - idx
+			return
+		}
+	}
+	r.interp.locals[expr] = global
+}
+
+func (r *resolver) error(tok token.Token, message string) {
+	r.interp.reporter.GloxError(tok, message)
+	r.hadError = true
+}
+
+func (r *resolver) VisitAssignExpr(expr *ast.Assign) struct{} {
+	r.resolveExpr(expr.Value)
+	r.resolveLocal(expr, expr.Name)
+	return struct{}{}
+}
+
+func (r *resolver) VisitBinaryExpr(expr *ast.Binary) struct{} {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return struct{}{}
+}
+
+func (r *resolver) VisitCallExpr(expr *ast.Call) struct{} {
+	r.resolveExpr(expr.Callee)
+	for _, arg := range expr.Arguments {
+		r.resolveExpr(arg)
+	}
+	return struct{}{}
+}
+
+func (r *resolver) VisitConditionalExpr(expr *ast.Conditional) struct{} {
+	r.resolveExpr(expr.Condition)
+	r.resolveExpr(expr.ThenBranch)
+	r.resolveExpr(expr.ElseBranch)
+	return struct{}{}
+}
+
+func (r *resolver) VisitGetExpr(expr *ast.Get) struct{} {
+	r.resolveExpr(expr.Object)
+	return struct{}{}
+}
+
+func (r *resolver) VisitGroupingExpr(expr *ast.Grouping) struct{} {
+	r.resolveExpr(expr.Expression)
+	return struct{}{}
+}
+
+func (r *resolver) VisitIndexExpr(expr *ast.Index) struct{} {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return struct{}{}
+}
+
+func (r *resolver) VisitIndexSetExpr(expr *ast.IndexSet) struct{} {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	r.resolveExpr(expr.Value)
+	return struct{}{}
+}
+
+// VisitLambdaExpr resolves a function's body in a scope holding its
+// parameters, the one a call binds them in.
+func (r *resolver) VisitLambdaExpr(expr *ast.Lambda) struct{} {
+	r.beginScope()
+	for _, param := range expr.Params {
+		r.declare(param)
+		r.define(param)
+	}
+	r.resolveStmts(expr.Body)
+	r.endScope()
+	return struct{}{}
+}
+
+func (r *resolver) VisitListLiteralExpr(expr *ast.ListLiteral) struct{} {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return struct{}{}
+}
+
+func (r *resolver) VisitLiteralExpr(expr *ast.Literal) struct{} {
+	return struct{}{}
+}
+
+func (r *resolver) VisitMapLiteralExpr(expr *ast.MapLiteral) struct{} {
+	for idx, key := range expr.Keys {
+		r.resolveExpr(key)
+		r.resolveExpr(expr.Values[idx])
+	}
+	return struct{}{}
+}
+
+func (r *resolver) VisitSliceExpr(expr *ast.Slice) struct{} {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Low)
+	r.resolveExpr(expr.High)
+	return struct{}{}
+}
+
+func (r *resolver) VisitUnaryExpr(expr *ast.Unary) struct{} {
+	r.resolveExpr(expr.Right)
+	return struct{}{}
+}
+
+func (r *resolver) VisitVariableExpr(expr *ast.Variable) struct{} {
+	if len(r.scopes) > 0 {
+		if ready, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]; ok && !ready {
+			r.error(expr.Name, "Can't read local variable in its own initializer.")
+		}
+	}
+	r.resolveLocal(expr, expr.Name)
+	return struct{}{}
+}
+
+func (r *resolver) VisitBlockStmt(stmt *ast.BlockStmt) struct{} {
+	r.beginScope()
+	r.resolveStmts(stmt.Statements)
+	r.endScope()
+	return struct{}{}
+}
+
+func (r *resolver) VisitBreakStmt(stmt *ast.BreakStmt) struct{} {
+	return struct{}{}
+}
+
+func (r *resolver) VisitContinueStmt(stmt *ast.ContinueStmt) struct{} {
+	return struct{}{}
+}
+
+func (r *resolver) VisitExpressionStmt(stmt *ast.ExpressionStmt) struct{} {
+	r.resolveExpr(stmt.Expression)
+	return struct{}{}
+}
+
+// VisitForInStmt resolves the loop's body in a scope holding the loop
+// variable, which each iteration binds afresh.
+func (r *resolver) VisitForInStmt(stmt *ast.ForInStmt) struct{} {
+	r.resolveExpr(stmt.Iterable)
+	r.beginScope()
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.resolveStmt(stmt.Body)
+	r.endScope()
+	return struct{}{}
+}
+
+func (r *resolver) VisitIfStmt(stmt *ast.IfStmt) struct{} {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		r.resolveStmt(stmt.ElseBranch)
+	}
+	return struct{}{}
+}
+
+func (r *resolver) VisitImportStmt(stmt *ast.ImportStmt) struct{} {
+	if stmt.Names == nil {
+		r.declare(stmt.Alias)
+		r.define(stmt.Alias)
+		return struct{}{}
+	}
+	for _, name := range stmt.Names {
+		r.declare(name)
+		r.define(name)
+	}
+	return struct{}{}
+}
+
+func (r *resolver) VisitPrintStmt(stmt *ast.PrintStmt) struct{} {
+	r.resolveExpr(stmt.Expression)
+	return struct{}{}
+}
+
+func (r *resolver) VisitReturnStmt(stmt *ast.ReturnStmt) struct{} {
+	r.resolveExpr(stmt.Value)
+	return struct{}{}
+}
+
+func (r *resolver) VisitThrowStmt(stmt *ast.ThrowStmt) struct{} {
+	r.resolveExpr(stmt.Value)
+	return struct{}{}
+}
+
+// VisitTryStmt resolves a catch clause in a scope holding the caught
+// exception.
+func (r *resolver) VisitTryStmt(stmt *ast.TryStmt) struct{} {
+	r.resolveStmt(stmt.Body)
+	if stmt.Catch != nil {
+		r.beginScope()
+		r.declare(stmt.CatchName)
+		r.define(stmt.CatchName)
+		r.resolveStmt(stmt.Catch)
+		r.endScope()
+	}
+	if stmt.Finally != nil {
+		r.resolveStmt(stmt.Finally)
+	}
+	return struct{}{}
+}
+
+// VisitVarStmt declares the variable before resolving its initializer and
+// defines it afterwards, so that the initializer can't read it, although a
+// function in the initializer can, since it runs later.
+func (r *resolver) VisitVarStmt(stmt *ast.VarStmt) struct{} {
+	r.declare(stmt.Name)
+	r.resolveExpr(stmt.Initializer)
+	r.define(stmt.Name)
+	return struct{}{}
+}
+
+func (r *resolver) VisitWhileStmt(stmt *ast.WhileStmt) struct{} {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.Body)
+	r.resolveExpr(stmt.Increment)
+	return struct{}{}
+}
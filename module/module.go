@@ -0,0 +1,171 @@
+// Package module finds, runs and caches the modules that Lox scripts
+// import. Each module runs once, in a global scope of its own, and is
+// imported as a namespace holding the globals it defined.
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicholasq/glox/ast"
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/native"
+	"github.com/nicholasq/glox/optimize"
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+	"github.com/nicholasq/glox/token"
+)
+
+// Runner runs the statements of the module at path in a new global scope
+// and returns the globals it ends with, including the native ones.
+type Runner func(path string, stmts []ast.Stmt) (map[string]interface{}, error)
+
+// Loader loads the modules a program imports, running each of them only
+// once however many times it is imported.
+type Loader struct {
+	// SearchPath lists the directories searched, in order, for a module
+	// that is not found next to the file importing it. New sets it from
+	// the LOX_PATH environment variable.
+	SearchPath []string
+	// Optimize makes the loader optimize each module before running it.
+	Optimize bool
+	// Reporter is where syntax errors in modules are reported, along with
+	// the module's path. If it is nil they go to the error package's Writer.
+	Reporter *err.Reporter
+
+	// modules holds the modules loaded so far, keyed by absolute path.
+	modules map[string]*native.Namespace
+	// loading holds the paths of the files whose imports are being run,
+	// outermost first, so that import cycles can be reported.
+	loading []string
+}
+
+// New returns a loader with nothing loaded, searching the directories
+// listed in LOX_PATH.
+func New() *Loader {
+	var searchPath []string
+	if list := os.Getenv("LOX_PATH"); list != "" {
+		searchPath = filepath.SplitList(list)
+	}
+	return &Loader{SearchPath: searchPath, modules: make(map[string]*native.Namespace)}
+}
+
+// Resolve returns the path of the module imported as name by the file
+// from. A relative name is looked up in from's directory, or the working
+// directory if from is empty, and then in each directory of SearchPath.
+func (l *Loader) Resolve(from string, name string) (string, error) {
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		candidates = []string{filepath.Join(filepath.Dir(from), name)}
+		for _, dir := range l.SearchPath {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+	for _, candidate := range candidates {
+		if info, statErr := os.Stat(candidate); statErr == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("Cannot find module '%s'.", name)
+}
+
+// Load returns the namespace of the module imported as name by the file
+// from, first running the module with run if it has not been loaded yet.
+// Syntax errors in the module are reported as they are found, like those
+// of the main script, but naming the module's path. A runtime error raised by the module is returned
+// with the module's path and line in front of its message.
+func (l *Loader) Load(from string, name string, run Runner) (*native.Namespace, error) {
+	path, resolveErr := l.Resolve(from, name)
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	key, absErr := filepath.Abs(path)
+	if absErr != nil {
+		return nil, absErr
+	}
+	if ns, ok := l.modules[key]; ok {
+		return ns, nil
+	}
+
+	if len(l.loading) == 0 && from != "" {
+		l.loading = append(l.loading, from)
+		defer func() { l.loading = nil }()
+	}
+	for idx, loading := range l.loading {
+		if loadingKey, _ := filepath.Abs(loading); loadingKey == key {
+			cycle := append(append([]string(nil), l.loading[idx:]...), path)
+			return nil, fmt.Errorf("Import cycle: %s.", strings.Join(cycle, " -> "))
+		}
+	}
+	l.loading = append(l.loading, path)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	source, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, fmt.Errorf("Cannot read module '%s'.", path)
+	}
+	reporter := l.Reporter.ForFile(path)
+	scanner := scanner.New(string(source))
+	scanner.Reporter = reporter
+	p := parser.New(scanner.ScanTokens())
+	p.SetReporter(reporter)
+	stmts, parseErr := p.Parse()
+	if parseErr != nil {
+		return nil, fmt.Errorf("Could not parse module '%s'.", path)
+	}
+	if l.Optimize {
+		stmts = optimize.Optimize(stmts)
+	}
+	globals, runErr := run(path, stmts)
+	if rtErr, ok := runErr.(*err.RuntimeError); ok {
+		located := *rtErr
+		located.Message = fmt.Sprintf("%s:%d: %s", path, rtErr.Token.Line, rtErr.Message)
+		return nil, &located
+	}
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	ns := &native.Namespace{Name: strings.TrimSuffix(filepath.Base(path), ".lox"), Members: exports(globals)}
+	l.modules[key] = ns
+	return ns, nil
+}
+
+// exports returns the globals a module defined, leaving out the native
+// ones it did not replace.
+func exports(globals map[string]interface{}) map[string]interface{} {
+	members := make(map[string]interface{}, len(globals))
+	for name, value := range globals {
+		if builtin, ok := native.Globals[name]; ok && builtin == value {
+			continue
+		}
+		members[name] = value
+	}
+	return members
+}
+
+// Member returns the member called name of a module's namespace, for an
+// import of the form import { name } from "path";.
+func Member(ns *native.Namespace, name string) (interface{}, error) {
+	if value, ok := ns.Get(name); ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("Module '%s' has no member '%s'.", ns.Name, name)
+}
+
+// ImportError returns the error an import statement raises at keyword
+// when Load fails with loadErr. A call of exit() in the module is returned
+// as is, so that it still ends the program.
+func ImportError(loadErr error, keyword token.Token) error {
+	switch loadErr := loadErr.(type) {
+	case *native.Exit:
+		return loadErr
+	case *err.RuntimeError:
+		raised := *loadErr
+		raised.Token = keyword
+		return &raised
+	}
+	return &err.RuntimeError{Token: keyword, Message: loadErr.Error()}
+}
@@ -0,0 +1,219 @@
+package module
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicholasq/glox/ast"
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/native"
+	"github.com/nicholasq/glox/token"
+)
+
+// writeFiles creates files under dir, keyed by path relative to it.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if mkdirErr := os.MkdirAll(filepath.Dir(path), 0o755); mkdirErr != nil {
+			t.Fatal(mkdirErr)
+		}
+		if writeErr := os.WriteFile(path, []byte(contents), 0o644); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+}
+
+// importer returns a runner that follows a module's imports with l and
+// records the path of each module it runs in ran. The globals it returns
+// are a native function and one variable, named after the module.
+func importer(l *Loader, ran *[]string) Runner {
+	var run Runner
+	run = func(path string, stmts []ast.Stmt) (map[string]interface{}, error) {
+		*ran = append(*ran, path)
+		for _, stmt := range stmts {
+			if stmt, ok := stmt.(*ast.ImportStmt); ok {
+				if _, loadErr := l.Load(path, stmt.Path.Literal.(string), run); loadErr != nil {
+					return nil, loadErr
+				}
+			}
+		}
+		return map[string]interface{}{"clock": native.Globals["clock"], "name": filepath.Base(path)}, nil
+	}
+	return run
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main/util.lox":  "",
+		"main/dir.lox/x": "",
+		"lib/util.lox":   "",
+		"lib/extra.lox":  "",
+		"more/other.lox": "",
+	})
+	l := &Loader{SearchPath: []string{filepath.Join(dir, "lib"), filepath.Join(dir, "more")}}
+	from := filepath.Join(dir, "main", "main.lox")
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"util.lox", filepath.Join(dir, "main", "util.lox")},
+		{"extra.lox", filepath.Join(dir, "lib", "extra.lox")},
+		{"other.lox", filepath.Join(dir, "more", "other.lox")},
+		{"../lib/util.lox", filepath.Join(dir, "lib", "util.lox")},
+		{filepath.Join(dir, "more", "other.lox"), filepath.Join(dir, "more", "other.lox")},
+		{"dir.lox", ""},
+		{"missing.lox", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, resolveErr := l.Resolve(from, tt.name)
+			if tt.expected == "" {
+				if resolveErr == nil || resolveErr.Error() != "Cannot find module '"+tt.name+"'." {
+					t.Errorf("Expected the module not to be found, got %q, %v", path, resolveErr)
+				}
+				return
+			}
+			if resolveErr != nil || path != tt.expected {
+				t.Errorf("Expected %s, got %q, %v", tt.expected, path, resolveErr)
+			}
+		})
+	}
+}
+
+func TestLoadCaches(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.lox":     `import "lib/b.lox" as b;`,
+		"lib/b.lox": `var x = 1;`,
+	})
+	l := New()
+	var ran []string
+	run := importer(l, &ran)
+	main := filepath.Join(dir, "main.lox")
+
+	a, loadErr := l.Load(main, "a.lox", run)
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	b, loadErr := l.Load(main, "lib/../lib/b.lox", run)
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	if again, _ := l.Load(main, "a.lox", run); again != a {
+		t.Error("Expected a second import to return the same namespace")
+	}
+	if len(ran) != 2 {
+		t.Errorf("Expected each module to run once, ran %v", ran)
+	}
+	if a.Name != "a" || b.Name != "b" {
+		t.Errorf("Expected namespaces named a and b, got %s and %s", a.Name, b.Name)
+	}
+	if len(b.Members) != 1 || b.Members["name"] != "b.lox" {
+		t.Errorf("Expected only the module's own globals as members, got %v", b.Members)
+	}
+	if len(l.loading) != 0 {
+		t.Errorf("Expected no modules left loading, got %v", l.loading)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"self.lox":   `import "self.lox" as self;`,
+		"a.lox":      `import "b.lox" as b;`,
+		"b.lox":      `import "main.lox" as main;`,
+		"main.lox":   `import "b.lox" as b;`,
+		"syntax.lox": `var = 1;`,
+	})
+	main := filepath.Join(dir, "main.lox")
+	self := filepath.Join(dir, "self.lox")
+	a, b := filepath.Join(dir, "a.lox"), filepath.Join(dir, "b.lox")
+	tests := []struct {
+		name     string
+		from     string
+		expected string
+	}{
+		{"self.lox", main, "Import cycle: " + self + " -> " + self + "."},
+		{"a.lox", main, "Import cycle: " + main + " -> " + a + " -> " + b + " -> " + main + "."},
+		{"b.lox", filepath.Join(dir, "repl.lox"), "Import cycle: " + b + " -> " + main + " -> " + b + "."},
+		{"syntax.lox", main, "Could not parse module '" + filepath.Join(dir, "syntax.lox") + "'."},
+		{"missing.lox", main, "Cannot find module 'missing.lox'."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New()
+			l.Reporter = &err.Reporter{Writer: io.Discard}
+			var ran []string
+			_, loadErr := l.Load(tt.from, tt.name, importer(l, &ran))
+			if loadErr == nil || loadErr.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, loadErr)
+			}
+		})
+	}
+}
+
+func TestLoadSyntaxErrorNamesModule(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"syntax.lox": "var ok = 1;\nvar = 1;\n@\n"})
+	path := filepath.Join(dir, "syntax.lox")
+	var reported bytes.Buffer
+	l := New()
+	l.Reporter = &err.Reporter{Writer: &reported}
+	var ran []string
+	if _, loadErr := l.Load(filepath.Join(dir, "main.lox"), "syntax.lox", importer(l, &ran)); loadErr == nil {
+		t.Fatal("Expected the module to fail to load")
+	}
+	expected := "[" + path + ", line 3] Error : Unexpected character.\n" +
+		"[" + path + ", line 2] Error  at '=': Expect variable name.\n"
+	if reported.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, reported.String())
+	}
+}
+
+func TestLoadRuntimeError(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"bad.lox": "print 1;"})
+	path := filepath.Join(dir, "bad.lox")
+	raised := &err.RuntimeError{Token: token.Token{Line: 3}, Message: "Oops.", Thrown: true, Value: 1.0}
+	l := New()
+	_, loadErr := l.Load(filepath.Join(dir, "main.lox"), "bad.lox", func(string, []ast.Stmt) (map[string]interface{}, error) {
+		return nil, raised
+	})
+
+	keyword := token.Token{TokenType: token.IMPORT, Lexeme: "import", Line: 9}
+	rtErr, ok := ImportError(loadErr, keyword).(*err.RuntimeError)
+	if !ok {
+		t.Fatalf("Expected a runtime error, got %v", loadErr)
+	}
+	if expected := path + ":3: Oops."; rtErr.Message != expected || rtErr.Token.Line != 9 {
+		t.Errorf("Expected %q on line 9, got %q on line %d", expected, rtErr.Message, rtErr.Token.Line)
+	}
+	if !rtErr.Thrown || rtErr.Value != 1.0 {
+		t.Errorf("Expected the thrown value to be kept, got %v", rtErr.Value)
+	}
+	if raised.Message != "Oops." {
+		t.Errorf("Expected the module's error to be left alone, got %q", raised.Message)
+	}
+
+	exit := &native.Exit{Code: 3}
+	if ImportError(exit, keyword) != exit {
+		t.Error("Expected exit() in a module to end the program")
+	}
+}
+
+func TestMember(t *testing.T) {
+	ns := &native.Namespace{Name: "util", Members: map[string]interface{}{"x": 1.0}}
+	if value, memberErr := Member(ns, "x"); memberErr != nil || value != 1.0 {
+		t.Errorf("Expected 1, got %v, %v", value, memberErr)
+	}
+	if _, memberErr := Member(ns, "y"); memberErr == nil || memberErr.Error() != "Module 'util' has no member 'y'." {
+		t.Errorf("Expected a missing member error, got %v", memberErr)
+	}
+}
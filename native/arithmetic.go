@@ -0,0 +1,84 @@
+package native
+
+import (
+	"errors"
+	"math"
+
+	"github.com/nicholasq/glox/token"
+)
+
+// Modulo returns the remainder of dividing left by right. As in Python, the
+// remainder takes the sign of right, so that it agrees with FloorDivide:
+// left == right*FloorDivide(left, right) + Modulo(left, right).
+func Modulo(left, right float64) float64 {
+	rem := math.Mod(left, right)
+	if rem != 0 && (rem < 0) != (right < 0) {
+		rem += right
+	}
+	return rem
+}
+
+// FloorDivide divides left by right, rounding the quotient down. Division
+// by zero gives an infinity or NaN, as it does for /.
+func FloorDivide(left, right float64) float64 {
+	return math.Floor(left / right)
+}
+
+// Bitwise applies the bitwise operator op, one of AMPERSAND, PIPE, CARET,
+// LESS_LESS and GREATER_GREATER, to two integral numbers. The operands are
+// treated as 64-bit two's complement integers.
+func Bitwise(op token.TokenType, left, right float64) (float64, error) {
+	l, lErr := integer(left)
+	r, rErr := integer(right)
+	if lErr == errFractional || rErr == errFractional {
+		return 0, errors.New("Operands must be integers.")
+	}
+	if lErr != nil || rErr != nil {
+		return 0, errors.New("Operands must fit in a 64-bit integer.")
+	}
+	switch op {
+	case token.AMPERSAND:
+		return float64(l & r), nil
+	case token.PIPE:
+		return float64(l | r), nil
+	case token.CARET:
+		return float64(l ^ r), nil
+	}
+	if r < 0 {
+		return 0, errors.New("Shift count must not be negative.")
+	}
+	if op == token.LESS_LESS {
+		return float64(l << r), nil
+	}
+	return float64(l >> r), nil
+}
+
+// Complement returns the bitwise complement of an integral number, ~x.
+func Complement(operand float64) (float64, error) {
+	num, numErr := integer(operand)
+	if numErr == errFractional {
+		return 0, errors.New("Operand must be an integer.")
+	}
+	if numErr != nil {
+		return 0, errors.New("Operand must fit in a 64-bit integer.")
+	}
+	return float64(^num), nil
+}
+
+// errFractional and errOutOfRange are returned by integer for a number that
+// isn't an integer and one that doesn't fit in an int64.
+var (
+	errFractional = errors.New("number has a fractional part")
+	errOutOfRange = errors.New("number is out of range")
+)
+
+// integer converts num to an int64.
+func integer(num float64) (int64, error) {
+	if num != math.Trunc(num) {
+		return 0, errFractional
+	}
+	if num < math.MinInt64 || num >= math.MaxInt64 {
+		return 0, errOutOfRange
+	}
+	return int64(num), nil
+}
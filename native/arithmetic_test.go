@@ -0,0 +1,90 @@
+package native
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nicholasq/glox/token"
+)
+
+func TestModuloAndFloorDivide(t *testing.T) {
+	tests := []struct {
+		left, right       float64
+		quotient, modulus float64
+	}{
+		{7, 3, 2, 1},
+		{-7, 3, -3, 2},
+		{7, -3, -3, -2},
+		{-7, -3, 2, -1},
+		{7.5, 2, 3, 1.5},
+		{6, 3, 2, 0},
+	}
+
+	for _, tt := range tests {
+		if actual := FloorDivide(tt.left, tt.right); actual != tt.quotient {
+			t.Errorf("%v ~/ %v: expected %v, got %v", tt.left, tt.right, tt.quotient, actual)
+		}
+		if actual := Modulo(tt.left, tt.right); actual != tt.modulus {
+			t.Errorf("%v %% %v: expected %v, got %v", tt.left, tt.right, tt.modulus, actual)
+		}
+	}
+}
+
+func TestBitwise(t *testing.T) {
+	tests := []struct {
+		op          token.TokenType
+		left, right float64
+		expected    float64
+	}{
+		{token.AMPERSAND, 12, 10, 8},
+		{token.PIPE, 12, 10, 14},
+		{token.CARET, 12, 10, 6},
+		{token.LESS_LESS, 1, 10, 1024},
+		{token.GREATER_GREATER, -16, 2, -4},
+		{token.AMPERSAND, -1, 255, 255},
+	}
+
+	for _, tt := range tests {
+		actual, bitErr := Bitwise(tt.op, tt.left, tt.right)
+		if bitErr != nil {
+			t.Fatalf("Unexpected error: %s", bitErr)
+		}
+		if actual != tt.expected {
+			t.Errorf("%s %v %v: expected %v, got %v", token.TokenNames[tt.op], tt.left, tt.right, tt.expected, actual)
+		}
+	}
+	if actual, _ := Complement(5); actual != -6 {
+		t.Errorf("~5: expected -6, got %v", actual)
+	}
+}
+
+func TestBitwiseErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          token.TokenType
+		left, right float64
+		expected    string
+	}{
+		{"fractional left operand", token.AMPERSAND, 1.5, 1, "Operands must be integers."},
+		{"fractional right operand", token.LESS_LESS, 1, 0.5, "Operands must be integers."},
+		{"operand out of range", token.PIPE, 1e19, 1, "Operands must fit in a 64-bit integer."},
+		{"infinite operand", token.AMPERSAND, 1, math.Inf(-1), "Operands must fit in a 64-bit integer."},
+		{"NaN operand", token.CARET, math.NaN(), 1, "Operands must be integers."},
+		{"negative shift", token.GREATER_GREATER, 1, -1, "Shift count must not be negative."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, bitErr := Bitwise(tt.op, tt.left, tt.right)
+			if bitErr == nil || bitErr.Error() != tt.expected {
+				t.Errorf("Expected %q, got %v", tt.expected, bitErr)
+			}
+		})
+	}
+	if _, bitErr := Complement(0.5); bitErr == nil || bitErr.Error() != "Operand must be an integer." {
+		t.Errorf("Expected an error for ~0.5, got %v", bitErr)
+	}
+	if _, bitErr := Complement(1e19); bitErr == nil || bitErr.Error() != "Operand must fit in a 64-bit integer." {
+		t.Errorf("Expected an error for ~1e19, got %v", bitErr)
+	}
+}
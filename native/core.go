@@ -0,0 +1,94 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func init() {
+	define("clock", 0, clock)
+	define("input", 0, readLine)
+	define("readLine", 0, readLine)
+	define("type", 1, typeOf)
+	define("str", 1, str)
+	define("num", 1, num)
+	define("len", 1, length)
+	define("exit", 1, exit)
+	define("assert", 2, assert)
+}
+
+// clock returns the number of seconds since the Unix epoch.
+func clock(ctx *Context, args []interface{}) (interface{}, error) {
+	return float64(time.Now().UnixNano()) / float64(time.Second), nil
+}
+
+// readLine returns the next line of input without its line ending, or nil
+// at the end of the input.
+func readLine(ctx *Context, args []interface{}) (interface{}, error) {
+	line, readErr := ctx.In.ReadString('\n')
+	if readErr == io.EOF && line == "" {
+		return nil, nil
+	}
+	if readErr != nil && readErr != io.EOF {
+		return nil, fmt.Errorf("Could not read input: %s.", readErr)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func typeOf(ctx *Context, args []interface{}) (interface{}, error) {
+	return TypeName(args[0]), nil
+}
+
+func str(ctx *Context, args []interface{}) (interface{}, error) {
+	return Stringify(args[0]), nil
+}
+
+// num converts a string to a number. Numbers are returned unchanged.
+func num(ctx *Context, args []interface{}) (interface{}, error) {
+	switch value := args[0].(type) {
+	case float64:
+		return value, nil
+	case string:
+		number, parseErr := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if parseErr != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, fmt.Errorf("Cannot convert %q to a number.", value)
+		}
+		return number, nil
+	}
+	return nil, fmt.Errorf("Cannot convert a %s to a number.", TypeName(args[0]))
+}
+
+// length returns the number of characters in a string, elements in a list
+// or entries in a map.
+func length(ctx *Context, args []interface{}) (interface{}, error) {
+	switch value := args[0].(type) {
+	case string:
+		return float64(utf8.RuneCountInString(value)), nil
+	case *List:
+		return float64(len(value.Elements)), nil
+	case *Map:
+		return float64(value.Len()), nil
+	}
+	return nil, fmt.Errorf("Cannot take the length of a %s.", TypeName(args[0]))
+}
+
+func exit(ctx *Context, args []interface{}) (interface{}, error) {
+	code, ok := args[0].(float64)
+	if !ok || code != math.Trunc(code) || code < 0 || code > 255 {
+		return nil, errors.New("Exit code must be an integer between 0 and 255.")
+	}
+	return nil, &Exit{Code: int(code)}
+}
+
+func assert(ctx *Context, args []interface{}) (interface{}, error) {
+	if !IsTruthy(args[0]) {
+		return nil, fmt.Errorf("Assertion failed: %s", Stringify(args[1]))
+	}
+	return nil, nil
+}
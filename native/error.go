@@ -0,0 +1,66 @@
+package native
+
+import (
+	"fmt"
+
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/token"
+)
+
+// Error is the value a catch clause receives for a runtime error: its
+// message, the line it was raised on and the call stack at that point,
+// innermost first, with entries such as "[line 3] in script".
+type Error struct {
+	Message string
+	Line    uint
+	Stack   []string
+}
+
+func (e *Error) Get(name string) (interface{}, bool) {
+	switch name {
+	case "message":
+		return e.Message, true
+	case "line":
+		return float64(e.Line), true
+	case "stack":
+		stack := &List{Elements: make([]interface{}, len(e.Stack))}
+		for idx, entry := range e.Stack {
+			stack.Elements[idx] = entry
+		}
+		return stack, true
+	}
+	return nil, false
+}
+
+func (e *Error) String() string {
+	return e.Message
+}
+
+// Throw returns the runtime error that a throw statement raises for value
+// at keyword. Throwing an Error again keeps its message and line.
+func Throw(value interface{}, keyword token.Token) *err.RuntimeError {
+	rtErr := &err.RuntimeError{Token: keyword, Thrown: true, Value: value}
+	if e, ok := value.(*Error); ok {
+		rtErr.Message = e.Message
+		rtErr.Token.Line = e.Line
+	} else {
+		rtErr.Message = "Uncaught exception: " + Stringify(value)
+	}
+	return rtErr
+}
+
+// Caught returns the value a catch clause binds for rtErr: the value thrown
+// by a throw statement, or an Error describing any other runtime error.
+// stack is the call stack where rtErr was raised, innermost first.
+func Caught(rtErr *err.RuntimeError, stack []string) interface{} {
+	if rtErr.Thrown {
+		return rtErr.Value
+	}
+	return &Error{Message: rtErr.Message, Line: rtErr.Token.Line, Stack: stack}
+}
+
+// StackEntry formats one entry of an Error's stack: the line being run in
+// the function called name.
+func StackEntry(name string, line uint) string {
+	return fmt.Sprintf("[line %d] in %s", line, name)
+}
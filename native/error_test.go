@@ -0,0 +1,54 @@
+package native
+
+import (
+	"testing"
+
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/token"
+)
+
+func TestErrorProperties(t *testing.T) {
+	e := &Error{Message: "Oops.", Line: 3, Stack: []string{StackEntry("script", 3)}}
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"message", "Oops."},
+		{"line", "3"},
+		{"stack", "[[line 3] in script]"},
+	}
+	for _, tt := range tests {
+		value, propErr := Property(e, tt.name)
+		if propErr != nil {
+			t.Fatalf("e.%s: unexpected error: %s", tt.name, propErr)
+		}
+		if actual := Stringify(value); actual != tt.expected {
+			t.Errorf("e.%s: expected %s, got %s", tt.name, tt.expected, actual)
+		}
+	}
+	if actual := Stringify(e); actual != "Oops." {
+		t.Errorf("Expected an error to print as its message, got %s", actual)
+	}
+}
+
+func TestThrowAndCatch(t *testing.T) {
+	keyword := token.Token{TokenType: token.THROW, Lexeme: "throw", Line: 7}
+
+	thrown := Throw(list(1.0), keyword)
+	if thrown.Message != "Uncaught exception: [1]" || thrown.Token.Line != 7 {
+		t.Errorf("Expected an uncaught exception on line 7, got %q on line %d", thrown.Message, thrown.Token.Line)
+	}
+	if value := Caught(thrown, nil); Stringify(value) != "[1]" {
+		t.Errorf("Expected the thrown value to be caught, got %v", value)
+	}
+
+	rtErr := &err.RuntimeError{Token: token.Token{Line: 2}, Message: "Operand must be a number."}
+	e, ok := Caught(rtErr, []string{"[line 2] in script"}).(*Error)
+	if !ok || e.Message != rtErr.Message || e.Line != 2 || len(e.Stack) != 1 {
+		t.Fatalf("Expected an Error describing the runtime error, got %v", e)
+	}
+	rethrown := Throw(e, keyword)
+	if rethrown.Message != rtErr.Message || rethrown.Token.Line != 2 {
+		t.Errorf("Expected throwing an Error to keep its message and line, got %q on line %d", rethrown.Message, rethrown.Token.Line)
+	}
+}
@@ -0,0 +1,109 @@
+package native
+
+import "errors"
+
+// Iterator steps through the elements a for-in loop runs over.
+type Iterator struct {
+	next func() (interface{}, bool, error)
+}
+
+// Next returns the next element, reporting false once there are none left.
+// An error returned by an object's iterator methods is returned as is.
+func (it *Iterator) Next() (interface{}, bool, error) {
+	return it.next()
+}
+
+func (it *Iterator) String() string {
+	return "<iterator>"
+}
+
+// Iterate returns an iterator over the elements of a list, the keys of a
+// map, the characters of a string or the elements of an object that
+// follows the iteration protocol: its iter() method returns an iterator,
+// whose done() method reports whether it is finished and whose next()
+// method returns its next element. ctx is passed to those methods.
+//
+// A list is read as the loop goes, so elements pushed onto it during the
+// loop are visited too. A map's keys are taken when the loop starts: keys
+// added during the loop are not visited, and keys deleted before they are
+// reached are skipped.
+func Iterate(ctx *Context, value interface{}) (*Iterator, error) {
+	switch value := value.(type) {
+	case *List:
+		idx := 0
+		return &Iterator{next: func() (interface{}, bool, error) {
+			if idx >= len(value.Elements) {
+				return nil, false, nil
+			}
+			idx++
+			return value.Elements[idx-1], true, nil
+		}}, nil
+	case *Map:
+		keys := value.Keys()
+		return &Iterator{next: func() (interface{}, bool, error) {
+			for len(keys) > 0 {
+				key := keys[0]
+				keys = keys[1:]
+				if _, ok := value.Load(key); ok {
+					return key, true, nil
+				}
+			}
+			return nil, false, nil
+		}}, nil
+	case string:
+		runes := []rune(value)
+		return &Iterator{next: func() (interface{}, bool, error) {
+			if len(runes) == 0 {
+				return nil, false, nil
+			}
+			char := string(runes[0])
+			runes = runes[1:]
+			return char, true, nil
+		}}, nil
+	case Object:
+		if iter, ok := method(value, "iter"); ok {
+			return iterateObject(ctx, iter)
+		}
+	}
+	return nil, errors.New("Can only iterate over lists, maps, strings and objects with an iter() method.")
+}
+
+// iterateObject calls an object's iter method and returns an iterator that
+// steps through the elements of the iterator it returns.
+func iterateObject(ctx *Context, iter Callable) (*Iterator, error) {
+	iterator, iterErr := iter.Call(ctx, nil)
+	if iterErr != nil {
+		return nil, iterErr
+	}
+	object, _ := iterator.(Object)
+	done, doneOk := method(object, "done")
+	next, nextOk := method(object, "next")
+	if !doneOk || !nextOk {
+		return nil, errors.New("iter() must return an object with done() and next() methods.")
+	}
+	return &Iterator{next: func() (interface{}, bool, error) {
+		finished, doneErr := done.Call(ctx, nil)
+		if doneErr != nil || finished != nil && finished != false {
+			return nil, false, doneErr
+		}
+		element, nextErr := next.Call(ctx, nil)
+		return element, nextErr == nil, nextErr
+	}}, nil
+}
+
+// method returns the named member of object if it can be called without
+// arguments.
+func method(object Object, name string) (Callable, bool) {
+	if object == nil {
+		return nil, false
+	}
+	member, ok := object.Get(name)
+	if !ok {
+		return nil, false
+	}
+	callable, ok := member.(Callable)
+	if !ok || callable.Arity() > 0 {
+		return nil, false
+	}
+	return callable, true
+}
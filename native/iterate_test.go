@@ -0,0 +1,122 @@
+package native
+
+import (
+	"reflect"
+	"testing"
+)
+
+// collect runs an iterator over value to the end and returns its elements.
+// step, if not nil, is called after each element.
+func collect(t *testing.T, value interface{}, step func(element interface{})) []interface{} {
+	t.Helper()
+	it, iterErr := Iterate(&Context{}, value)
+	if iterErr != nil {
+		t.Fatalf("Unexpected error: %s", iterErr)
+	}
+	var elements []interface{}
+	for {
+		element, ok, nextErr := it.Next()
+		if nextErr != nil {
+			t.Fatalf("Unexpected error: %s", nextErr)
+		}
+		if !ok {
+			break
+		}
+		elements = append(elements, element)
+		if step != nil {
+			step(element)
+		}
+	}
+	return elements
+}
+
+func TestIterate(t *testing.T) {
+	m := NewMap()
+	m.Store("b", 1.0)
+	m.Store("a", 2.0)
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected []interface{}
+	}{
+		{"list", list(1.0, "x", nil), []interface{}{1.0, "x", nil}},
+		{"empty list", list(), nil},
+		{"map keys", m, []interface{}{"b", "a"}},
+		{"string characters", "héllo", []interface{}{"h", "é", "l", "l", "o"}},
+		{"empty string", "", nil},
+		{"iter() method", countdown(3), []interface{}{3.0, 2.0, 1.0}},
+		{"finished iterator", countdown(0), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := collect(t, tt.value, nil); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIterateWhileChanging(t *testing.T) {
+	xs := list(1.0, 2.0)
+	pushed := collect(t, xs, func(element interface{}) {
+		if element == 1.0 {
+			xs.Elements = append(xs.Elements, 3.0)
+		}
+	})
+	if !reflect.DeepEqual(pushed, []interface{}{1.0, 2.0, 3.0}) {
+		t.Errorf("Expected elements pushed during the loop to be visited, got %v", pushed)
+	}
+
+	m := NewMap()
+	for _, key := range []string{"a", "b", "c"} {
+		m.Store(key, nil)
+	}
+	keys := collect(t, m, func(element interface{}) {
+		if element == "a" {
+			m.Delete("b")
+			m.Store("d", nil)
+		}
+	})
+	if !reflect.DeepEqual(keys, []interface{}{"a", "c"}) {
+		t.Errorf("Expected deleted keys to be skipped and new ones not visited, got %v", keys)
+	}
+}
+
+func TestIterateError(t *testing.T) {
+	noIter := &Namespace{Name: "plain", Members: map[string]interface{}{"next": Globals["clock"]}}
+	for _, value := range []interface{}{nil, 1.0, true, Globals["clock"], noIter} {
+		if _, iterErr := Iterate(&Context{}, value); iterErr == nil || iterErr.Error() != "Can only iterate over lists, maps, strings and objects with an iter() method." {
+			t.Errorf("Iterate(%v): expected an error, got %v", value, iterErr)
+		}
+	}
+
+	badIter := &Namespace{Name: "bad", Members: map[string]interface{}{
+		"iter": &Function{Name: "iter", Fn: func(ctx *Context, args []interface{}) (interface{}, error) {
+			return 1.0, nil
+		}},
+	}}
+	if _, iterErr := Iterate(&Context{}, badIter); iterErr == nil || iterErr.Error() != "iter() must return an object with done() and next() methods." {
+		t.Errorf("Expected an error for an iter() returning a number, got %v", iterErr)
+	}
+}
+
+// countdown returns an object following the iteration protocol, whose
+// iterators count down from n to 1.
+func countdown(n float64) *Namespace {
+	iter := func(ctx *Context, args []interface{}) (interface{}, error) {
+		left := n
+		return &Namespace{Name: "iterator", Members: map[string]interface{}{
+			"done": &Function{Name: "done", Fn: func(ctx *Context, args []interface{}) (interface{}, error) {
+				return left == 0, nil
+			}},
+			"next": &Function{Name: "next", Fn: func(ctx *Context, args []interface{}) (interface{}, error) {
+				left--
+				return left + 1, nil
+			}},
+		}}, nil
+	}
+	return &Namespace{Name: "countdown", Members: map[string]interface{}{
+		"iter": &Function{Name: "iter", Fn: iter},
+	}}
+}
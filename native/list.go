@@ -0,0 +1,119 @@
+package native
+
+import (
+	"errors"
+	"math"
+)
+
+// List is a Lox list: an ordered sequence of values that grows and shrinks
+// as elements are pushed, popped, inserted and removed.
+type List struct {
+	Elements []interface{}
+}
+
+// listMethods are the methods of lists, read with the dot operator as in
+// xs.push(1). Each is called with the list it was read from followed by the
+// call's arguments; its Params does not count the list.
+var listMethods = map[string]*Function{}
+
+func init() {
+	defineMethod(listMethods, "push", 1, push)
+	defineMethod(listMethods, "pop", 0, pop)
+	defineMethod(listMethods, "insert", 2, insert)
+	defineMethod(listMethods, "remove", 1, remove)
+}
+
+func (l *List) Get(name string) (interface{}, bool) {
+	method, ok := listMethods[name]
+	if !ok {
+		return nil, false
+	}
+	return bind(l, method), true
+}
+
+func (l *List) String() string {
+	return format(l, make(map[interface{}]bool))
+}
+
+// push appends an element to the end of a list.
+func push(ctx *Context, args []interface{}) (interface{}, error) {
+	list := args[0].(*List)
+	list.Elements = append(list.Elements, args[1])
+	return nil, nil
+}
+
+// pop removes the last element of a list and returns it.
+func pop(ctx *Context, args []interface{}) (interface{}, error) {
+	list := args[0].(*List)
+	if len(list.Elements) == 0 {
+		return nil, errors.New("Can't pop from an empty list.")
+	}
+	last := list.Elements[len(list.Elements)-1]
+	list.Elements = list.Elements[:len(list.Elements)-1]
+	return last, nil
+}
+
+// insert inserts an element before the element at a position, or at the
+// end of the list if the position is its length.
+func insert(ctx *Context, args []interface{}) (interface{}, error) {
+	list := args[0].(*List)
+	index := args[1]
+	// Count negative positions back from the last element, not from the
+	// end position.
+	if num, ok := index.(float64); ok && num < 0 {
+		index = num + float64(len(list.Elements))
+	}
+	idx, idxErr := position("List", index, len(list.Elements)+1, false)
+	if idxErr != nil {
+		return nil, idxErr
+	}
+	list.Elements = append(list.Elements, nil)
+	copy(list.Elements[idx+1:], list.Elements[idx:])
+	list.Elements[idx] = args[2]
+	return nil, nil
+}
+
+// remove removes the element at a position and returns it.
+func remove(ctx *Context, args []interface{}) (interface{}, error) {
+	list := args[0].(*List)
+	idx, idxErr := position("List", args[1], len(list.Elements), true)
+	if idxErr != nil {
+		return nil, idxErr
+	}
+	removed := list.Elements[idx]
+	list.Elements = append(list.Elements[:idx], list.Elements[idx+1:]...)
+	return removed, nil
+}
+
+func (l *List) slice(start, end interface{}) (*List, error) {
+	length := len(l.Elements)
+	from, fromErr := bound(start, 0, length)
+	if fromErr != nil {
+		return nil, fromErr
+	}
+	to, toErr := bound(end, length, length)
+	if toErr != nil {
+		return nil, toErr
+	}
+	result := &List{}
+	if from < to {
+		result.Elements = append(result.Elements, l.Elements[from:to]...)
+	}
+	return result, nil
+}
+
+// bound converts a slice bound to a position in [0, length], returning
+// missing for a nil bound.
+func bound(value interface{}, missing, length int) (int, error) {
+	if value == nil {
+		return missing, nil
+	}
+	num, ok := value.(float64)
+	if !ok || num != math.Trunc(num) {
+		return 0, errors.New("Slice bounds must be integers.")
+	}
+	if num < 0 {
+		num += float64(length)
+	}
+	return int(math.Max(0, math.Min(num, float64(length)))), nil
+}
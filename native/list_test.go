@@ -0,0 +1,148 @@
+package native
+
+import (
+	"reflect"
+	"testing"
+)
+
+func list(elements ...interface{}) *List {
+	return &List{Elements: elements}
+}
+
+func TestListMethods(t *testing.T) {
+	tests := []struct {
+		name     string
+		self     *List
+		method   string
+		args     []interface{}
+		result   interface{}
+		expected *List
+	}{
+		{"push", list(1.0), "push", []interface{}{"a"}, nil, list(1.0, "a")},
+		{"push onto empty", list(), "push", []interface{}{nil}, nil, list(nil)},
+		{"pop", list(1.0, 2.0), "pop", nil, 2.0, list(1.0)},
+		{"insert at start", list(1.0, 2.0), "insert", []interface{}{0.0, 0.0}, nil, list(0.0, 1.0, 2.0)},
+		{"insert at end", list(1.0, 2.0), "insert", []interface{}{2.0, 3.0}, nil, list(1.0, 2.0, 3.0)},
+		{"insert before last", list(1.0, 2.0), "insert", []interface{}{-1.0, 1.5}, nil, list(1.0, 1.5, 2.0)},
+		{"insert into empty", list(), "insert", []interface{}{0.0, "a"}, nil, list("a")},
+		{"remove", list(1.0, 2.0, 3.0), "remove", []interface{}{1.0}, 2.0, list(1.0, 3.0)},
+		{"remove last", list(1.0, 2.0, 3.0), "remove", []interface{}{-1.0}, 3.0, list(1.0, 2.0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, callErr := callMethod(t, tt.self, tt.method, tt.args...)
+			if callErr != nil {
+				t.Fatalf("Unexpected error: %s", callErr)
+			}
+			if result != tt.result {
+				t.Errorf("Expected result %v, got %v", tt.result, result)
+			}
+			if !reflect.DeepEqual(tt.self, tt.expected) {
+				t.Errorf("Expected the list to become %v, got %v", tt.expected, tt.self)
+			}
+		})
+	}
+}
+
+func TestListMethodErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		self     *List
+		method   string
+		args     []interface{}
+		expected string
+	}{
+		{"pop from empty", list(), "pop", nil, "Can't pop from an empty list."},
+		{"insert past the end", list(1.0), "insert", []interface{}{2.0, 0.0}, "List index out of range."},
+		{"insert before the start", list(1.0), "insert", []interface{}{-2.0, 0.0}, "List index out of range."},
+		{"remove from empty", list(), "remove", []interface{}{0.0}, "List index out of range."},
+		{"remove with a string", list(1.0), "remove", []interface{}{"0"}, "List index must be an integer."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, callErr := callMethod(t, tt.self, tt.method, tt.args...)
+			if callErr == nil || callErr.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, callErr)
+			}
+		})
+	}
+}
+
+func TestListIndex(t *testing.T) {
+	xs := list("a", "b", "c")
+	for index, expected := range map[float64]interface{}{0: "a", 2: "c", -1: "c", -3: "a"} {
+		if value, indexErr := Index(xs, index); indexErr != nil || value != expected {
+			t.Errorf("xs[%v]: expected %v, got %v (%v)", index, expected, value, indexErr)
+		}
+	}
+	for _, index := range []float64{3, -4} {
+		if _, indexErr := Index(xs, index); indexErr == nil || indexErr.Error() != "List index out of range." {
+			t.Errorf("xs[%v]: expected an out of range error, got %v", index, indexErr)
+		}
+	}
+
+	if setErr := SetIndex(xs, -1.0, "z"); setErr != nil {
+		t.Fatal(setErr)
+	}
+	if !reflect.DeepEqual(xs, list("a", "b", "z")) {
+		t.Errorf("Expected [a, b, z], got %v", xs)
+	}
+	if setErr := SetIndex(xs, 3.0, "z"); setErr == nil || setErr.Error() != "List index out of range." {
+		t.Errorf("Expected an out of range error, got %v", setErr)
+	}
+	if setErr := SetIndex("abc", 0.0, "z"); setErr == nil || setErr.Error() != "Can only assign to elements of lists and maps." {
+		t.Errorf("Expected an error assigning into a string, got %v", setErr)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	xs := list(0.0, 1.0, 2.0, 3.0)
+	tests := []struct {
+		low, high interface{}
+		expected  *List
+	}{
+		{1.0, 3.0, list(1.0, 2.0)},
+		{nil, 2.0, list(0.0, 1.0)},
+		{2.0, nil, list(2.0, 3.0)},
+		{nil, nil, list(0.0, 1.0, 2.0, 3.0)},
+		{-1.0, nil, list(3.0)},
+		{nil, -1.0, list(0.0, 1.0, 2.0)},
+		{-10.0, 10.0, list(0.0, 1.0, 2.0, 3.0)},
+		{3.0, 1.0, list()},
+	}
+
+	for _, tt := range tests {
+		result, sliceErr := Slice(xs, tt.low, tt.high)
+		if sliceErr != nil {
+			t.Fatalf("xs[%v:%v]: unexpected error: %s", tt.low, tt.high, sliceErr)
+		}
+		if Stringify(result) != Stringify(tt.expected) {
+			t.Errorf("xs[%v:%v]: expected %v, got %v", tt.low, tt.high, tt.expected, result)
+		}
+	}
+
+	whole, _ := Slice(xs, nil, nil)
+	if whole == xs {
+		t.Error("Expected slicing to copy the list")
+	}
+	if _, sliceErr := Slice(xs, 0.5, nil); sliceErr == nil || sliceErr.Error() != "Slice bounds must be integers." {
+		t.Errorf("Expected an error for a fractional bound, got %v", sliceErr)
+	}
+	if _, sliceErr := Slice("abc", nil, nil); sliceErr == nil || sliceErr.Error() != "Can only slice lists." {
+		t.Errorf("Expected an error slicing a string, got %v", sliceErr)
+	}
+}
+
+func TestListString(t *testing.T) {
+	xs := list(1.0, "a", nil, list(true))
+	if actual := Stringify(xs); actual != "[1, a, nil, [true]]" {
+		t.Errorf("Expected [1, a, nil, [true]], got %s", actual)
+	}
+	ys := list(1.0)
+	ys.Elements = append(ys.Elements, list(ys), ys)
+	if actual := Stringify(ys); actual != "[1, [[...]], [...]]" {
+		t.Errorf("Expected [1, [[...]], [...]], got %s", actual)
+	}
+}
@@ -0,0 +1,141 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Map is a Lox map from keys to values that remembers the order in which
+// keys were added. Keys are looked up by Lox equality: nil, booleans,
+// numbers and strings by value, and every other value by identity.
+type Map struct {
+	keys   []interface{}
+	values []interface{}
+	// index maps each key to its position in keys and values. Go's
+	// equality on these values is Lox equality, so they can key it
+	// directly.
+	index map[interface{}]int
+}
+
+// mapMethods are the methods of maps, read with the dot operator as in
+// m.keys(). Each is called with the map it was read from followed by the
+// call's arguments; its Params does not count the map.
+var mapMethods = map[string]*Function{}
+
+func init() {
+	defineMethod(mapMethods, "keys", 0, func(ctx *Context, args []interface{}) (interface{}, error) {
+		return &List{Elements: args[0].(*Map).Keys()}, nil
+	})
+	defineMethod(mapMethods, "values", 0, func(ctx *Context, args []interface{}) (interface{}, error) {
+		m := args[0].(*Map)
+		return &List{Elements: append([]interface{}(nil), m.values...)}, nil
+	})
+	defineMethod(mapMethods, "has", 1, func(ctx *Context, args []interface{}) (interface{}, error) {
+		_, ok := args[0].(*Map).Load(args[1])
+		return ok, nil
+	})
+	defineMethod(mapMethods, "delete", 1, func(ctx *Context, args []interface{}) (interface{}, error) {
+		return args[0].(*Map).Delete(args[1]), nil
+	})
+}
+
+// NewMap returns an empty map.
+func NewMap() *Map {
+	return &Map{index: make(map[interface{}]int)}
+}
+
+// Len returns the number of entries in the map.
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the map's keys in the order they were added.
+func (m *Map) Keys() []interface{} {
+	return append([]interface{}(nil), m.keys...)
+}
+
+// Load returns the value stored under key, reporting false if there is none.
+func (m *Map) Load(key interface{}) (interface{}, bool) {
+	idx, ok := m.index[key]
+	if !ok {
+		return nil, false
+	}
+	return m.values[idx], true
+}
+
+// Store sets the value stored under key. A new key is added after the
+// others; an existing one keeps its place. NaN can't be a key, since it
+// isn't equal to itself.
+func (m *Map) Store(key, value interface{}) error {
+	if num, ok := key.(float64); ok && math.IsNaN(num) {
+		return errors.New("Map keys can't be NaN.")
+	}
+	if idx, ok := m.index[key]; ok {
+		m.values[idx] = value
+		return nil
+	}
+	m.index[key] = len(m.keys)
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+	return nil
+}
+
+// Delete removes the entry for key, reporting whether there was one.
+func (m *Map) Delete(key interface{}) bool {
+	idx, ok := m.index[key]
+	if !ok {
+		return false
+	}
+	delete(m.index, key)
+	m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+	m.values = append(m.values[:idx], m.values[idx+1:]...)
+	for _, later := range m.keys[idx:] {
+		m.index[later]--
+	}
+	return true
+}
+
+func (m *Map) Get(name string) (interface{}, bool) {
+	method, ok := mapMethods[name]
+	if !ok {
+		return nil, false
+	}
+	return bind(m, method), true
+}
+
+func (m *Map) String() string {
+	return format(m, make(map[interface{}]bool))
+}
+
+// format formats a value as print shows it. A list or map that contains
+// itself, directly or through other lists and maps, is shown as [...] or
+// {...} where it recurs. seen holds the lists and maps being formatted.
+func format(value interface{}, seen map[interface{}]bool) string {
+	switch value := value.(type) {
+	case *List:
+		if seen[value] {
+			return "[...]"
+		}
+		seen[value] = true
+		defer delete(seen, value)
+		parts := make([]string, len(value.Elements))
+		for idx, element := range value.Elements {
+			parts[idx] = format(element, seen)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case *Map:
+		if seen[value] {
+			return "{...}"
+		}
+		seen[value] = true
+		defer delete(seen, value)
+		parts := make([]string, len(value.keys))
+		for idx, key := range value.keys {
+			parts[idx] = fmt.Sprintf("%s: %s", format(key, seen), format(value.values[idx], seen))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return Stringify(value)
+}
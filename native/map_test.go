@@ -0,0 +1,128 @@
+package native
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMapStoreAndLoad(t *testing.T) {
+	m := NewMap()
+	xs := list(1.0)
+	entries := []struct {
+		key, value interface{}
+	}{
+		{"a", 1.0},
+		{1.0, "number"},
+		{"1", "string"},
+		{true, "boolean"},
+		{nil, "nil"},
+		{xs, "list"},
+	}
+	for _, entry := range entries {
+		if storeErr := m.Store(entry.key, entry.value); storeErr != nil {
+			t.Fatalf("Store(%v): unexpected error: %s", entry.key, storeErr)
+		}
+	}
+	for _, entry := range entries {
+		if value, ok := m.Load(entry.key); !ok || value != entry.value {
+			t.Errorf("Load(%v): expected %v, got %v (%v)", entry.key, entry.value, value, ok)
+		}
+	}
+	if _, ok := m.Load(list(1.0)); ok {
+		t.Error("Expected lists to be looked up by identity")
+	}
+	if _, ok := m.Load(false); ok {
+		t.Error("Expected no entry for false")
+	}
+
+	m.Store("a", 2.0)
+	if value, _ := m.Load("a"); value != 2.0 || m.Len() != len(entries) {
+		t.Errorf("Expected storing an existing key to replace its value, got %v with %d entries", value, m.Len())
+	}
+	if storeErr := m.Store(math.NaN(), 1.0); storeErr == nil || storeErr.Error() != "Map keys can't be NaN." {
+		t.Errorf("Expected an error storing a NaN key, got %v", storeErr)
+	}
+}
+
+func TestMapOrder(t *testing.T) {
+	m := NewMap()
+	for _, key := range []string{"c", "a", "b", "d"} {
+		m.Store(key, nil)
+	}
+	if !m.Delete("a") || m.Delete("a") {
+		t.Error("Expected Delete to report whether the key was present")
+	}
+	m.Store("a", nil)
+	m.Store("c", 1.0)
+	if keys := m.Keys(); !reflect.DeepEqual(keys, []interface{}{"c", "b", "d", "a"}) {
+		t.Errorf("Expected keys in insertion order [c b d a], got %v", keys)
+	}
+	for _, key := range []string{"b", "d", "a"} {
+		if _, ok := m.Load(key); !ok {
+			t.Errorf("Expected %s to survive deleting an earlier key", key)
+		}
+	}
+}
+
+func TestMapMethods(t *testing.T) {
+	m := NewMap()
+	m.Store("a", 1.0)
+	m.Store("b", 2.0)
+	tests := []struct {
+		method   string
+		args     []interface{}
+		expected string
+	}{
+		{"keys", nil, "[a, b]"},
+		{"values", nil, "[1, 2]"},
+		{"has", []interface{}{"a"}, "true"},
+		{"has", []interface{}{1.0}, "false"},
+		{"delete", []interface{}{"a"}, "true"},
+		{"delete", []interface{}{"a"}, "false"},
+		{"keys", nil, "[b]"},
+	}
+
+	for _, tt := range tests {
+		result, callErr := callMethod(t, m, tt.method, tt.args...)
+		if callErr != nil {
+			t.Fatalf("%s(%v): unexpected error: %s", tt.method, tt.args, callErr)
+		}
+		if actual := Stringify(result); actual != tt.expected {
+			t.Errorf("%s(%v): expected %s, got %s", tt.method, tt.args, tt.expected, actual)
+		}
+	}
+}
+
+func TestMapIndex(t *testing.T) {
+	m := NewMap()
+	if setErr := SetIndex(m, "k", 1.0); setErr != nil {
+		t.Fatal(setErr)
+	}
+	if value, indexErr := Index(m, "k"); indexErr != nil || value != 1.0 {
+		t.Errorf("Expected m[k] to be 1, got %v (%v)", value, indexErr)
+	}
+	if value, indexErr := Index(m, "missing"); indexErr != nil || value != nil {
+		t.Errorf("Expected a missing key to read as nil, got %v (%v)", value, indexErr)
+	}
+	if size, _ := length(nil, []interface{}{m}); size != 1.0 {
+		t.Errorf("Expected len 1, got %v", size)
+	}
+	if name := TypeName(m); name != "map" {
+		t.Errorf("Expected type map, got %s", name)
+	}
+}
+
+func TestMapString(t *testing.T) {
+	m := NewMap()
+	m.Store("a", list(1.0, nil))
+	m.Store(2.0, true)
+	if actual := Stringify(m); actual != "{a: [1, nil], 2: true}" {
+		t.Errorf("Expected {a: [1, nil], 2: true}, got %s", actual)
+	}
+	m.Store("self", m)
+	m.Store("in list", list(m))
+	if actual := Stringify(m); actual != "{a: [1, nil], 2: true, self: {...}, in list: [{...}]}" {
+		t.Errorf("Expected the map to show as {...} where it recurs, got %s", actual)
+	}
+}
@@ -0,0 +1,107 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+func init() {
+	members := map[string]interface{}{
+		"PI":  math.Pi,
+		"E":   math.E,
+		"INF": math.Inf(1),
+		"NAN": math.NaN(),
+	}
+	for name, fn := range map[string]func(float64) float64{
+		"sqrt":  math.Sqrt,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"abs":   math.Abs,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"log":   math.Log,
+		"exp":   math.Exp,
+	} {
+		members[name] = unary("math."+name, fn)
+	}
+	members["pow"] = &Function{Name: "math.pow", Params: 2, Fn: pow}
+	members["min"] = &Function{Name: "math.min", Params: -1, Fn: extreme("math.min", math.Min)}
+	members["max"] = &Function{Name: "math.max", Params: -1, Fn: extreme("math.max", math.Max)}
+	members["random"] = &Function{Name: "math.random", Params: 0, Fn: random}
+	members["seed"] = &Function{Name: "math.seed", Params: 1, Fn: seed}
+	Globals["math"] = &Namespace{Name: "math", Members: members}
+}
+
+// numbers checks that every argument passed to the function called name is
+// a number and returns them.
+func numbers(name string, args []interface{}) ([]float64, error) {
+	result := make([]float64, len(args))
+	for idx, arg := range args {
+		num, ok := arg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s() expects numbers but got a %s.", name, TypeName(arg))
+		}
+		result[idx] = num
+	}
+	return result, nil
+}
+
+// unary wraps a function of one number as a native function.
+func unary(name string, fn func(float64) float64) *Function {
+	return &Function{Name: name, Params: 1, Fn: func(ctx *Context, args []interface{}) (interface{}, error) {
+		nums, numErr := numbers(name, args)
+		if numErr != nil {
+			return nil, numErr
+		}
+		return fn(nums[0]), nil
+	}}
+}
+
+func pow(ctx *Context, args []interface{}) (interface{}, error) {
+	nums, numErr := numbers("math.pow", args)
+	if numErr != nil {
+		return nil, numErr
+	}
+	return math.Pow(nums[0], nums[1]), nil
+}
+
+// extreme returns a native function that reduces one or more numbers with
+// pick, such as math.Min.
+func extreme(name string, pick func(x, y float64) float64) func(ctx *Context, args []interface{}) (interface{}, error) {
+	return func(ctx *Context, args []interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s() expects at least one argument.", name)
+		}
+		nums, numErr := numbers(name, args)
+		if numErr != nil {
+			return nil, numErr
+		}
+		result := nums[0]
+		for _, num := range nums[1:] {
+			result = pick(result, num)
+		}
+		return result, nil
+	}
+}
+
+// random returns a number in [0, 1).
+func random(ctx *Context, args []interface{}) (interface{}, error) {
+	return ctx.random().Float64(), nil
+}
+
+// seed makes the numbers math.random() returns from now on repeatable.
+func seed(ctx *Context, args []interface{}) (interface{}, error) {
+	nums, numErr := numbers("math.seed", args)
+	if numErr != nil {
+		return nil, numErr
+	}
+	if nums[0] != math.Trunc(nums[0]) || math.Abs(nums[0]) > 1<<53 {
+		return nil, errors.New("math.seed() expects an integer.")
+	}
+	ctx.rng = rand.New(rand.NewSource(int64(nums[0])))
+	return nil, nil
+}
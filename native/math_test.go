@@ -0,0 +1,116 @@
+package native
+
+import (
+	"math"
+	"testing"
+)
+
+func callMath(t *testing.T, ctx *Context, name string, args ...interface{}) (interface{}, error) {
+	t.Helper()
+	member, ok := Globals["math"].(*Namespace).Get(name)
+	if !ok {
+		t.Fatalf("math.%s is not defined", name)
+	}
+	fn := member.(*Function)
+	if arity := fn.Arity(); arity >= 0 && arity != len(args) {
+		t.Fatalf("math.%s takes %d arguments, not %d", name, arity, len(args))
+	}
+	return fn.Call(ctx, args)
+}
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		fn       string
+		args     []interface{}
+		expected float64
+	}{
+		{"sqrt", []interface{}{2.25}, 1.5},
+		{"pow", []interface{}{2.0, -1.0}, 0.5},
+		{"floor", []interface{}{-0.5}, -1},
+		{"ceil", []interface{}{-0.5}, 0},
+		{"round", []interface{}{0.5}, 1},
+		{"abs", []interface{}{-0.25}, 0.25},
+		{"min", []interface{}{2.0, -1.0, 4.0}, -1},
+		{"max", []interface{}{2.0, -1.0, 4.0}, 4},
+		{"log", []interface{}{1.0}, 0},
+		{"exp", []interface{}{0.0}, 1},
+		{"cos", []interface{}{math.Pi}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			result, callErr := callMath(t, &Context{}, tt.fn, tt.args...)
+			if callErr != nil {
+				t.Fatalf("Unexpected error: %s", callErr)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMathErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       string
+		args     []interface{}
+		expected string
+	}{
+		{"non-number argument", "pow", []interface{}{2.0, "3"}, "math.pow() expects numbers but got a string."},
+		{"min without arguments", "min", nil, "math.min() expects at least one argument."},
+		{"max of nil", "max", []interface{}{1.0, nil}, "math.max() expects numbers but got a nil."},
+		{"fractional seed", "seed", []interface{}{1.5}, "math.seed() expects an integer."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, callErr := callMath(t, &Context{}, tt.fn, tt.args...)
+			if callErr == nil || callErr.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, callErr)
+			}
+		})
+	}
+}
+
+func TestMathRandom(t *testing.T) {
+	sequence := func(ctx *Context) []float64 {
+		if _, seedErr := callMath(t, ctx, "seed", 7.0); seedErr != nil {
+			t.Fatal(seedErr)
+		}
+		var result []float64
+		for idx := 0; idx < 5; idx++ {
+			value, _ := callMath(t, ctx, "random")
+			if value.(float64) < 0 || value.(float64) >= 1 {
+				t.Fatalf("Expected a number in [0, 1), got %v", value)
+			}
+			result = append(result, value.(float64))
+		}
+		return result
+	}
+
+	// Programs seeded alike see the same numbers, however many ran before.
+	first, second := &Context{}, &Context{}
+	callMath(t, second, "random")
+	a, b := sequence(first), sequence(second)
+	for idx := range a {
+		if a[idx] != b[idx] {
+			t.Fatalf("Expected the same sequence, got %v and %v", a, b)
+		}
+	}
+}
+
+func TestMathConstants(t *testing.T) {
+	ns := Globals["math"].(*Namespace)
+	for name, expected := range map[string]float64{"PI": math.Pi, "E": math.E, "INF": math.Inf(1)} {
+		if value, _ := ns.Get(name); value != expected {
+			t.Errorf("Expected math.%s to be %v, got %v", name, expected, value)
+		}
+	}
+	if value, _ := ns.Get("NAN"); !math.IsNaN(value.(float64)) {
+		t.Errorf("Expected math.NAN to be NaN, got %v", value)
+	}
+	if _, ok := ns.Get("nope"); ok {
+		t.Error("Expected math.nope to be undefined")
+	}
+}
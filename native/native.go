@@ -0,0 +1,231 @@
+// Package native implements the functions built into every Lox program and
+// the properties and elements of built-in values such as strings. They are
+// shared by the tree-walking interpreter and the vm, which define everything
+// in Globals into a program's global scope before running it.
+package native
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Context is the program state available to native functions.
+type Context struct {
+	// In is where input() and readLine() read from.
+	In *bufio.Reader
+
+	// rng is the generator behind math.random(), created on first use
+	// unless math.seed() has set it.
+	rng *rand.Rand
+}
+
+func (ctx *Context) random() *rand.Rand {
+	if ctx.rng == nil {
+		ctx.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return ctx.rng
+}
+
+// Callable is implemented by every value that Lox code can call.
+type Callable interface {
+	// Arity returns the number of arguments the callable takes, or -1 if
+	// it takes any number. Callers check the argument count before Call.
+	Arity() int
+	// Call runs the callable. A returned error is reported as a runtime
+	// error at the call, except for *Exit, which ends the program.
+	Call(ctx *Context, args []interface{}) (interface{}, error)
+}
+
+// Function is a function implemented in Go.
+type Function struct {
+	Name   string
+	Params int
+	Fn     func(ctx *Context, args []interface{}) (interface{}, error)
+}
+
+func (f *Function) Arity() int { return f.Params }
+
+func (f *Function) Call(ctx *Context, args []interface{}) (interface{}, error) {
+	return f.Fn(ctx, args)
+}
+
+func (f *Function) String() string { return "<native fn " + f.Name + ">" }
+
+// Object is implemented by values with properties, which Lox code reads
+// with the dot operator.
+type Object interface {
+	// Get returns the value of the named property, reporting false if the
+	// object has no such property.
+	Get(name string) (interface{}, bool)
+}
+
+// Namespace is a named collection of values, such as the math module.
+type Namespace struct {
+	Name    string
+	Members map[string]interface{}
+}
+
+func (n *Namespace) Get(name string) (interface{}, bool) {
+	value, ok := n.Members[name]
+	return value, ok
+}
+
+func (n *Namespace) String() string { return "<namespace " + n.Name + ">" }
+
+// Property returns the named property of value: a member of an Object or a
+// method of a string.
+func Property(value interface{}, name string) (interface{}, error) {
+	switch value := value.(type) {
+	case Object:
+		if member, ok := value.Get(name); ok {
+			return member, nil
+		}
+	case string:
+		if method, ok := stringMethods[name]; ok {
+			return bind(value, method), nil
+		}
+	default:
+		return nil, errors.New("Only objects have properties.")
+	}
+	return nil, fmt.Errorf("Undefined property '%s'.", name)
+}
+
+// Index returns the element of value at position index: one character of
+// a string, or one element of a list. Negative positions in a list count
+// back from its end. Indexing a map returns the value stored under the key
+// index, or nil if there is none.
+func Index(value, index interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		runes := []rune(value)
+		idx, idxErr := position("String", index, len(runes), false)
+		if idxErr != nil {
+			return nil, idxErr
+		}
+		return string(runes[idx]), nil
+	case *List:
+		idx, idxErr := position("List", index, len(value.Elements), true)
+		if idxErr != nil {
+			return nil, idxErr
+		}
+		return value.Elements[idx], nil
+	case *Map:
+		element, _ := value.Load(index)
+		return element, nil
+	}
+	return nil, errors.New("Can only index lists, maps and strings.")
+}
+
+// SetIndex replaces the element of a list at position index, or stores
+// element in a map under the key index.
+func SetIndex(value, index, element interface{}) error {
+	switch value := value.(type) {
+	case *List:
+		idx, idxErr := position("List", index, len(value.Elements), true)
+		if idxErr != nil {
+			return idxErr
+		}
+		value.Elements[idx] = element
+		return nil
+	case *Map:
+		return value.Store(index, element)
+	}
+	return errors.New("Can only assign to elements of lists and maps.")
+}
+
+// Slice returns a new list holding the elements of a list from start up to
+// but not including end. Negative bounds count back from the end of the
+// list, nil bounds stand for its start and end, and bounds beyond either
+// end are clamped to it.
+func Slice(value, start, end interface{}) (interface{}, error) {
+	list, ok := value.(*List)
+	if !ok {
+		return nil, errors.New("Can only slice lists.")
+	}
+	sliced, sliceErr := list.slice(start, end)
+	if sliceErr != nil {
+		return nil, sliceErr
+	}
+	return sliced, nil
+}
+
+// position checks that index is an integer in [0, length) and returns it.
+// If fromEnd is set, negative indexes count back from length first. kind
+// names the indexed type in errors.
+func position(kind string, index interface{}, length int, fromEnd bool) (int, error) {
+	num, ok := index.(float64)
+	if !ok || num != math.Trunc(num) {
+		return 0, fmt.Errorf("%s index must be an integer.", kind)
+	}
+	if fromEnd && num < 0 {
+		num += float64(length)
+	}
+	if num < 0 || num >= float64(length) {
+		return 0, fmt.Errorf("%s index out of range.", kind)
+	}
+	return int(num), nil
+}
+
+// Exit is returned by exit() to end the program with Code as its exit status.
+type Exit struct {
+	Code int
+}
+
+func (e *Exit) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+
+// Globals holds the values every program starts with in its global scope,
+// keyed by name.
+var Globals = make(map[string]interface{})
+
+func define(name string, params int, fn func(ctx *Context, args []interface{}) (interface{}, error)) {
+	Globals[name] = &Function{Name: name, Params: params, Fn: fn}
+}
+
+// Stringify formats a Lox value the way print displays it.
+func Stringify(value interface{}) string {
+	if value == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// TypeName returns the name of a value's type, as reported by type().
+func TypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case Callable:
+		return "function"
+	case *Namespace:
+		return "namespace"
+	case *List:
+		return "list"
+	case *Map:
+		return "map"
+	case *Error:
+		return "error"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// IsTruthy reports whether a value counts as true in a condition: everything
+// except nil and false.
+func IsTruthy(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if truth, ok := value.(bool); ok {
+		return truth
+	}
+	return true
+}
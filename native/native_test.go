@@ -0,0 +1,111 @@
+package native
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func call(t *testing.T, ctx *Context, name string, args ...interface{}) (interface{}, error) {
+	t.Helper()
+	fn, ok := Globals[name].(*Function)
+	if !ok {
+		t.Fatalf("%s is not a native function", name)
+	}
+	if fn.Arity() != len(args) {
+		t.Fatalf("%s takes %d arguments, not %d", name, fn.Arity(), len(args))
+	}
+	return fn.Call(ctx, args)
+}
+
+func TestNatives(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"type of nil", "type", []interface{}{nil}, "nil"},
+		{"type of boolean", "type", []interface{}{false}, "boolean"},
+		{"type of number", "type", []interface{}{1.0}, "number"},
+		{"type of string", "type", []interface{}{""}, "string"},
+		{"type of function", "type", []interface{}{Globals["len"]}, "function"},
+		{"str of number", "str", []interface{}{2.5}, "2.5"},
+		{"str of nil", "str", []interface{}{nil}, "nil"},
+		{"str of function", "str", []interface{}{Globals["str"]}, "<native fn str>"},
+		{"num of string", "num", []interface{}{" -12.5\n"}, -12.5},
+		{"num of number", "num", []interface{}{3.0}, 3.0},
+		{"len counts characters", "len", []interface{}{"héllo"}, 5.0},
+		{"len of empty string", "len", []interface{}{""}, 0.0},
+		{"passing assert", "assert", []interface{}{1.0, "unused"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, callErr := call(t, &Context{}, tt.fn, tt.args...)
+			if callErr != nil {
+				t.Fatalf("Unexpected error: %s", callErr)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %#v, got %#v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNativeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       string
+		args     []interface{}
+		expected string
+	}{
+		{"num of invalid string", "num", []interface{}{"12abc"}, `Cannot convert "12abc" to a number.`},
+		{"num of infinity", "num", []interface{}{"Inf"}, `Cannot convert "Inf" to a number.`},
+		{"num of boolean", "num", []interface{}{true}, "Cannot convert a boolean to a number."},
+		{"len of number", "len", []interface{}{1.0}, "Cannot take the length of a number."},
+		{"exit with fraction", "exit", []interface{}{1.5}, "Exit code must be an integer between 0 and 255."},
+		{"exit out of range", "exit", []interface{}{256.0}, "Exit code must be an integer between 0 and 255."},
+		{"failing assert", "assert", []interface{}{nil, "x is set"}, "Assertion failed: x is set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, callErr := call(t, &Context{}, tt.fn, tt.args...)
+			if callErr == nil || callErr.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, callErr)
+			}
+		})
+	}
+}
+
+func TestExit(t *testing.T) {
+	_, callErr := call(t, &Context{}, "exit", 3.0)
+	var exit *Exit
+	if !errors.As(callErr, &exit) || exit.Code != 3 {
+		t.Errorf("Expected exit status 3, got %v", callErr)
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	ctx := &Context{In: bufio.NewReader(strings.NewReader("first\r\nsecond"))}
+	for _, expected := range []interface{}{"first", "second", nil} {
+		line, readErr := call(t, ctx, "readLine")
+		if readErr != nil {
+			t.Fatalf("Unexpected error: %s", readErr)
+		}
+		if line != expected {
+			t.Errorf("Expected %#v, got %#v", expected, line)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	first, _ := call(t, &Context{}, "clock")
+	second, _ := call(t, &Context{}, "clock")
+	if first.(float64) <= 0 || second.(float64) < first.(float64) || math.IsNaN(first.(float64)) {
+		t.Errorf("Expected increasing times, got %v then %v", first, second)
+	}
+}
@@ -0,0 +1,151 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringMethods are the methods of strings, read with the dot operator as
+// in s.upper(). Each is called with the string it was read from followed by
+// the call's arguments; its Params does not count the string.
+var stringMethods = map[string]*Function{}
+
+func init() {
+	defineMethod(stringMethods, "substr", 2, substr)
+	defineMethod(stringMethods, "indexOf", 1, indexOf)
+	defineMethod(stringMethods, "split", 1, split)
+	defineMethod(stringMethods, "join", 1, join)
+	defineMethod(stringMethods, "upper", 0, func(ctx *Context, args []interface{}) (interface{}, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	})
+	defineMethod(stringMethods, "lower", 0, func(ctx *Context, args []interface{}) (interface{}, error) {
+		return strings.ToLower(args[0].(string)), nil
+	})
+	defineMethod(stringMethods, "trim", 0, func(ctx *Context, args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(args[0].(string)), nil
+	})
+	defineMethod(stringMethods, "replace", 2, replace)
+	defineMethod(stringMethods, "startsWith", 1, startsWith)
+	defineMethod(stringMethods, "contains", 1, contains)
+	defineMethod(stringMethods, "chars", 0, chars)
+}
+
+func defineMethod(methods map[string]*Function, name string, params int, fn func(ctx *Context, args []interface{}) (interface{}, error)) {
+	methods[name] = &Function{Name: name, Params: params, Fn: fn}
+}
+
+// bind returns method as a function of its remaining arguments, with self
+// passed as the first.
+func bind(self interface{}, method *Function) *Function {
+	return &Function{Name: method.Name, Params: method.Params, Fn: func(ctx *Context, args []interface{}) (interface{}, error) {
+		return method.Fn(ctx, append([]interface{}{self}, args...))
+	}}
+}
+
+// stringArgs checks that the arguments passed to the method called name,
+// after the string it was called on, are strings and returns them.
+func stringArgs(name string, args []interface{}) ([]string, error) {
+	result := make([]string, len(args))
+	for idx, arg := range args {
+		str, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() expects strings but got a %s.", name, TypeName(arg))
+		}
+		result[idx] = str
+	}
+	return result, nil
+}
+
+// substr returns length characters of a string starting at index start.
+func substr(ctx *Context, args []interface{}) (interface{}, error) {
+	runes := []rune(args[0].(string))
+	start, startOk := args[1].(float64)
+	length, lengthOk := args[2].(float64)
+	if !startOk || !lengthOk || start != math.Trunc(start) || length != math.Trunc(length) {
+		return nil, errors.New("substr() expects integers.")
+	}
+	if start < 0 || length < 0 || start+length > float64(len(runes)) {
+		return nil, errors.New("Substring out of range.")
+	}
+	return string(runes[int(start):int(start+length)]), nil
+}
+
+// indexOf returns the position of the first occurrence of a substring,
+// counted in characters, or -1 if there is none.
+func indexOf(ctx *Context, args []interface{}) (interface{}, error) {
+	strs, strErr := stringArgs("indexOf", args)
+	if strErr != nil {
+		return nil, strErr
+	}
+	idx := strings.Index(strs[0], strs[1])
+	if idx < 0 {
+		return -1.0, nil
+	}
+	return float64(utf8.RuneCountInString(strs[0][:idx])), nil
+}
+
+// split returns the parts of a string between occurrences of a separator.
+// An empty separator splits the string into its characters.
+func split(ctx *Context, args []interface{}) (interface{}, error) {
+	strs, strErr := stringArgs("split", args)
+	if strErr != nil {
+		return nil, strErr
+	}
+	parts := strings.Split(strs[0], strs[1])
+	list := &List{Elements: make([]interface{}, len(parts))}
+	for idx, part := range parts {
+		list.Elements[idx] = part
+	}
+	return list, nil
+}
+
+// join concatenates the elements of a list, formatted as print shows them,
+// with the string it is called on between them.
+func join(ctx *Context, args []interface{}) (interface{}, error) {
+	list, ok := args[1].(*List)
+	if !ok {
+		return nil, fmt.Errorf("join() expects a list but got a %s.", TypeName(args[1]))
+	}
+	parts := make([]string, len(list.Elements))
+	for idx, element := range list.Elements {
+		parts[idx] = Stringify(element)
+	}
+	return strings.Join(parts, args[0].(string)), nil
+}
+
+// replace replaces every occurrence of a substring.
+func replace(ctx *Context, args []interface{}) (interface{}, error) {
+	strs, strErr := stringArgs("replace", args)
+	if strErr != nil {
+		return nil, strErr
+	}
+	return strings.ReplaceAll(strs[0], strs[1], strs[2]), nil
+}
+
+func startsWith(ctx *Context, args []interface{}) (interface{}, error) {
+	strs, strErr := stringArgs("startsWith", args)
+	if strErr != nil {
+		return nil, strErr
+	}
+	return strings.HasPrefix(strs[0], strs[1]), nil
+}
+
+func contains(ctx *Context, args []interface{}) (interface{}, error) {
+	strs, strErr := stringArgs("contains", args)
+	if strErr != nil {
+		return nil, strErr
+	}
+	return strings.Contains(strs[0], strs[1]), nil
+}
+
+// chars returns a list of the characters in a string.
+func chars(ctx *Context, args []interface{}) (interface{}, error) {
+	list := &List{}
+	for _, r := range args[0].(string) {
+		list.Elements = append(list.Elements, string(r))
+	}
+	return list, nil
+}
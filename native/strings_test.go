@@ -0,0 +1,140 @@
+package native
+
+import (
+	"reflect"
+	"testing"
+)
+
+func callMethod(t *testing.T, self interface{}, name string, args ...interface{}) (interface{}, error) {
+	t.Helper()
+	method, propErr := Property(self, name)
+	if propErr != nil {
+		t.Fatal(propErr)
+	}
+	fn := method.(*Function)
+	if fn.Arity() != len(args) {
+		t.Fatalf("%s takes %d arguments, not %d", name, fn.Arity(), len(args))
+	}
+	return fn.Call(&Context{}, args)
+}
+
+func TestStringMethods(t *testing.T) {
+	tests := []struct {
+		self     string
+		method   string
+		args     []interface{}
+		expected interface{}
+	}{
+		{"héllo", "substr", []interface{}{1.0, 3.0}, "éll"},
+		{"héllo", "substr", []interface{}{5.0, 0.0}, ""},
+		{"héllo", "indexOf", []interface{}{"lo"}, 3.0},
+		{"héllo", "indexOf", []interface{}{""}, 0.0},
+		{"a b", "split", []interface{}{" "}, &List{Elements: []interface{}{"a", "b"}}},
+		{"ab", "split", []interface{}{""}, &List{Elements: []interface{}{"a", "b"}}},
+		{", ", "join", []interface{}{&List{Elements: []interface{}{1.0, nil, "x"}}}, "1, nil, x"},
+		{"héllo", "upper", nil, "HÉLLO"},
+		{"ÀB", "lower", nil, "àb"},
+		{"\t x \n", "trim", nil, "x"},
+		{"aaa", "replace", []interface{}{"a", "b"}, "bbb"},
+		{"héllo", "startsWith", []interface{}{"hé"}, true},
+		{"héllo", "contains", []interface{}{"lx"}, false},
+		{"hé", "chars", nil, &List{Elements: []interface{}{"h", "é"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.self+"."+tt.method, func(t *testing.T) {
+			result, callErr := callMethod(t, tt.self, tt.method, tt.args...)
+			if callErr != nil {
+				t.Fatalf("Unexpected error: %s", callErr)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestStringMethodErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		args     []interface{}
+		expected string
+	}{
+		{"substr past the end", "substr", []interface{}{2.0, 2.0}, "Substring out of range."},
+		{"substr with negative start", "substr", []interface{}{-1.0, 1.0}, "Substring out of range."},
+		{"substr with fractional length", "substr", []interface{}{0.0, 0.5}, "substr() expects integers."},
+		{"indexOf a number", "indexOf", []interface{}{1.0}, "indexOf() expects strings but got a number."},
+		{"join a string", "join", []interface{}{"ab"}, "join() expects a list but got a string."},
+		{"replace with nil", "replace", []interface{}{"a", nil}, "replace() expects strings but got a nil."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, callErr := callMethod(t, "abc", tt.method, tt.args...)
+			if callErr == nil || callErr.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, callErr)
+			}
+		})
+	}
+}
+
+func TestProperty(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		property string
+		expected string
+	}{
+		{"undefined string method", "abc", "size", "Undefined property 'size'."},
+		{"undefined namespace member", Globals["math"], "tau", "Undefined property 'tau'."},
+		{"number", 1.0, "size", "Only objects have properties."},
+		{"nil", nil, "size", "Only objects have properties."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, propErr := Property(tt.value, tt.property)
+			if propErr == nil || propErr.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, propErr)
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		index    interface{}
+		expected interface{}
+		err      string
+	}{
+		{"first character", "héllo", 0.0, "h", ""},
+		{"multi-byte character", "héllo", 1.0, "é", ""},
+		{"last character", "héllo", 4.0, "o", ""},
+		{"past the end", "héllo", 5.0, nil, "String index out of range."},
+		{"negative", "héllo", -1.0, nil, "String index out of range."},
+		{"fraction", "héllo", 1.5, nil, "String index must be an integer."},
+		{"string index", "héllo", "0", nil, "String index must be an integer."},
+		{"number", 12.0, 0.0, nil, "Can only index lists, maps and strings."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, indexErr := Index(tt.value, tt.index)
+			if tt.err != "" {
+				if indexErr == nil || indexErr.Error() != tt.err {
+					t.Errorf("Expected error %q, got %v", tt.err, indexErr)
+				}
+				return
+			}
+			if indexErr != nil {
+				t.Fatalf("Unexpected error: %s", indexErr)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+// Package optimize implements AST-to-AST optimization passes. Every pass
+// preserves the program's observable behaviour, including the runtime
+// errors it raises: an expression that would fail at runtime, such as
+// 1 / "a", is left for the interpreter to evaluate.
+package optimize
+
+import (
+	"math"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/native"
+	"github.com/nicholasq/glox/token"
+)
+
+// Pass rewrites a program into an equivalent one. Passes build new nodes
+// rather than modifying the ones they are given.
+type Pass func(stmts []ast.Stmt) []ast.Stmt
+
+// Passes are the passes run by Optimize, in order.
+var Passes = []Pass{FoldConstants, EliminateDeadCode}
+
+// Optimize runs every pass in Passes over stmts.
+func Optimize(stmts []ast.Stmt) []ast.Stmt {
+	for _, pass := range Passes {
+		stmts = pass(stmts)
+	}
+	return stmts
+}
+
+// FoldConstants evaluates unary, binary and grouping expressions whose
+// operands are literals, replacing them with the resulting literal.
+func FoldConstants(stmts []ast.Stmt) []ast.Stmt {
+	f := &folder{}
+	result := make([]ast.Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		result = append(result, f.foldStmt(stmt))
+	}
+	return result
+}
+
+// EliminateDeadCode removes statements that have no effect or never run:
+// expression statements whose expression is a literal, the branch of an if
+// statement that its constant condition rules out, and statements that
+// follow a return, break, continue or throw in the same block. A removed
+// statement that was the body of a loop or a branch of an if is replaced
+// by an empty block.
+func EliminateDeadCode(stmts []ast.Stmt) []ast.Stmt {
+	return reachable(ast.RewriteProgram(stmts, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.ExpressionStmt:
+			if _, ok := node.Expression.(*ast.Literal); ok {
+				return nil
+			}
+		case *ast.IfStmt:
+			if literal, ok := node.Condition.(*ast.Literal); ok {
+				// A branch is a statement rather than a declaration,
+				// so it can take the if statement's place.
+				if isTruthy(literal.Value) {
+					return node.ThenBranch
+				}
+				return node.ElseBranch
+			}
+			node.ThenBranch = orEmpty(node.ThenBranch, node.Line)
+		case *ast.WhileStmt:
+			node.Body = orEmpty(node.Body, node.Line)
+		case *ast.ForInStmt:
+			node.Body = orEmpty(node.Body, node.Line)
+		case *ast.BlockStmt:
+			node.Statements = reachable(node.Statements)
+		case *ast.Lambda:
+			node.Body = reachable(node.Body)
+		}
+		return node
+	}))
+}
+
+// reachable returns stmts without the statements that follow one that
+// always jumps away.
+func reachable(stmts []ast.Stmt) []ast.Stmt {
+	for idx, stmt := range stmts {
+		if jumps(stmt) {
+			return stmts[:idx+1]
+		}
+	}
+	return stmts
+}
+
+// jumps reports whether stmt always ends with a return, break, continue
+// or throw, so that the statements after it never run.
+func jumps(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt, *ast.ThrowStmt:
+		return true
+	case *ast.BlockStmt:
+		return len(stmt.Statements) > 0 && jumps(stmt.Statements[len(stmt.Statements)-1])
+	case *ast.IfStmt:
+		return stmt.ElseBranch != nil && jumps(stmt.ThenBranch) && jumps(stmt.ElseBranch)
+	}
+	return false
+}
+
+// orEmpty returns stmt, or an empty block on line if stmt was removed.
+func orEmpty(stmt ast.Stmt, line uint) ast.Stmt {
+	if stmt == nil {
+		return &ast.BlockStmt{Line: line}
+	}
+	return stmt
+}
+
+// folder rebuilds each node it visits, returning the folded copy.
+type folder struct{}
+
+func (f *folder) foldStmt(stmt ast.Stmt) ast.Stmt {
+	return ast.AcceptStmt[ast.Stmt](stmt, f)
+}
+
+func (f *folder) fold(expr ast.Expr) ast.Expr {
+	if expr == nil {
+		return nil
+	}
+	return ast.AcceptExpr[ast.Expr](expr, f)
+}
+
+func (f *folder) VisitReturnStmt(stmt *ast.ReturnStmt) ast.Stmt {
+	return &ast.ReturnStmt{Keyword: stmt.Keyword, Value: f.fold(stmt.Value), Line: stmt.Line}
+}
+
+func (f *folder) VisitThrowStmt(stmt *ast.ThrowStmt) ast.Stmt {
+	return &ast.ThrowStmt{Keyword: stmt.Keyword, Value: f.fold(stmt.Value), Line: stmt.Line}
+}
+
+func (f *folder) VisitTryStmt(stmt *ast.TryStmt) ast.Stmt {
+	folded := &ast.TryStmt{Body: f.foldStmt(stmt.Body), CatchName: stmt.CatchName, Line: stmt.Line}
+	if stmt.Catch != nil {
+		folded.Catch = f.foldStmt(stmt.Catch)
+	}
+	if stmt.Finally != nil {
+		folded.Finally = f.foldStmt(stmt.Finally)
+	}
+	return folded
+}
+
+func (f *folder) VisitBreakStmt(stmt *ast.BreakStmt) ast.Stmt {
+	return stmt
+}
+
+func (f *folder) VisitContinueStmt(stmt *ast.ContinueStmt) ast.Stmt {
+	return stmt
+}
+
+func (f *folder) VisitImportStmt(stmt *ast.ImportStmt) ast.Stmt {
+	return stmt
+}
+
+func (f *folder) VisitIfStmt(stmt *ast.IfStmt) ast.Stmt {
+	var elseBranch ast.Stmt
+	if stmt.ElseBranch != nil {
+		elseBranch = f.foldStmt(stmt.ElseBranch)
+	}
+	return &ast.IfStmt{Condition: f.fold(stmt.Condition), ThenBranch: f.foldStmt(stmt.ThenBranch), ElseBranch: elseBranch, Line: stmt.Line}
+}
+
+func (f *folder) VisitWhileStmt(stmt *ast.WhileStmt) ast.Stmt {
+	return &ast.WhileStmt{Condition: f.fold(stmt.Condition), Body: f.foldStmt(stmt.Body), Increment: f.fold(stmt.Increment), Line: stmt.Line}
+}
+
+func (f *folder) VisitBlockStmt(stmt *ast.BlockStmt) ast.Stmt {
+	var stmts []ast.Stmt
+	for _, inner := range stmt.Statements {
+		stmts = append(stmts, f.foldStmt(inner))
+	}
+	return &ast.BlockStmt{Statements: stmts, Line: stmt.Line}
+}
+
+func (f *folder) VisitExpressionStmt(stmt *ast.ExpressionStmt) ast.Stmt {
+	return &ast.ExpressionStmt{Expression: f.fold(stmt.Expression), Line: stmt.Line}
+}
+
+func (f *folder) VisitForInStmt(stmt *ast.ForInStmt) ast.Stmt {
+	return &ast.ForInStmt{Name: stmt.Name, In: stmt.In, Iterable: f.fold(stmt.Iterable), Body: f.foldStmt(stmt.Body), Line: stmt.Line}
+}
+
+func (f *folder) VisitPrintStmt(stmt *ast.PrintStmt) ast.Stmt {
+	return &ast.PrintStmt{Expression: f.fold(stmt.Expression), Line: stmt.Line}
+}
+
+func (f *folder) VisitVarStmt(stmt *ast.VarStmt) ast.Stmt {
+	return &ast.VarStmt{Name: stmt.Name, Initializer: f.fold(stmt.Initializer), Line: stmt.Line}
+}
+
+func (f *folder) VisitAssignExpr(expr *ast.Assign) ast.Expr {
+	return &ast.Assign{Name: expr.Name, Value: f.fold(expr.Value), Line: expr.Line}
+}
+
+func (f *folder) VisitBinaryExpr(expr *ast.Binary) ast.Expr {
+	left := f.fold(expr.Left)
+	right := f.fold(expr.Right)
+	l, lOk := left.(*ast.Literal)
+	r, rOk := right.(*ast.Literal)
+	// A constant before a comma has no effect.
+	if lOk && expr.Operator.TokenType == token.COMMA {
+		return right
+	}
+	if lOk && rOk {
+		if value, ok := foldBinary(expr.Operator.TokenType, l.Value, r.Value); ok {
+			return &ast.Literal{Value: value, Line: expr.Line}
+		}
+	}
+	return &ast.Binary{Left: left, Operator: expr.Operator, Right: right, Line: expr.Line}
+}
+
+func (f *folder) VisitCallExpr(expr *ast.Call) ast.Expr {
+	args := make([]ast.Expr, 0, len(expr.Arguments))
+	for _, arg := range expr.Arguments {
+		args = append(args, f.fold(arg))
+	}
+	return &ast.Call{Callee: f.fold(expr.Callee), Paren: expr.Paren, Arguments: args, Line: expr.Line}
+}
+
+// VisitConditionalExpr replaces a conditional whose condition is constant
+// with the branch it selects.
+func (f *folder) VisitConditionalExpr(expr *ast.Conditional) ast.Expr {
+	condition := f.fold(expr.Condition)
+	if literal, ok := condition.(*ast.Literal); ok {
+		if isTruthy(literal.Value) {
+			return f.fold(expr.ThenBranch)
+		}
+		return f.fold(expr.ElseBranch)
+	}
+	return &ast.Conditional{Condition: condition, ThenBranch: f.fold(expr.ThenBranch), ElseBranch: f.fold(expr.ElseBranch), Line: expr.Line}
+}
+
+func (f *folder) VisitGetExpr(expr *ast.Get) ast.Expr {
+	return &ast.Get{Object: f.fold(expr.Object), Name: expr.Name, Line: expr.Line}
+}
+
+func (f *folder) VisitGroupingExpr(expr *ast.Grouping) ast.Expr {
+	inner := f.fold(expr.Expression)
+	if literal, ok := inner.(*ast.Literal); ok {
+		return literal
+	}
+	return &ast.Grouping{Expression: inner, Line: expr.Line}
+}
+
+func (f *folder) VisitIndexExpr(expr *ast.Index) ast.Expr {
+	return &ast.Index{Object: f.fold(expr.Object), Bracket: expr.Bracket, Index: f.fold(expr.Index), Line: expr.Line}
+}
+
+func (f *folder) VisitIndexSetExpr(expr *ast.IndexSet) ast.Expr {
+	return &ast.IndexSet{Object: f.fold(expr.Object), Bracket: expr.Bracket, Index: f.fold(expr.Index), Value: f.fold(expr.Value), Line: expr.Line}
+}
+
+func (f *folder) VisitLambdaExpr(expr *ast.Lambda) ast.Expr {
+	body := make([]ast.Stmt, 0, len(expr.Body))
+	for _, stmt := range expr.Body {
+		body = append(body, f.foldStmt(stmt))
+	}
+	return &ast.Lambda{Keyword: expr.Keyword, Params: expr.Params, Body: body, Line: expr.Line}
+}
+
+func (f *folder) VisitListLiteralExpr(expr *ast.ListLiteral) ast.Expr {
+	var elements []ast.Expr
+	for _, element := range expr.Elements {
+		elements = append(elements, f.fold(element))
+	}
+	return &ast.ListLiteral{Elements: elements, Line: expr.Line}
+}
+
+func (f *folder) VisitLiteralExpr(expr *ast.Literal) ast.Expr {
+	return expr
+}
+
+func (f *folder) VisitMapLiteralExpr(expr *ast.MapLiteral) ast.Expr {
+	var keys, values []ast.Expr
+	for idx, key := range expr.Keys {
+		keys = append(keys, f.fold(key))
+		values = append(values, f.fold(expr.Values[idx]))
+	}
+	return &ast.MapLiteral{Keys: keys, Values: values, Brace: expr.Brace, Line: expr.Line}
+}
+
+func (f *folder) VisitSliceExpr(expr *ast.Slice) ast.Expr {
+	return &ast.Slice{Object: f.fold(expr.Object), Bracket: expr.Bracket, Low: f.fold(expr.Low), High: f.fold(expr.High), Line: expr.Line}
+}
+
+func (f *folder) VisitUnaryExpr(expr *ast.Unary) ast.Expr {
+	right := f.fold(expr.Right)
+	if literal, ok := right.(*ast.Literal); ok {
+		switch expr.Operator.TokenType {
+		case token.BANG:
+			return &ast.Literal{Value: !isTruthy(literal.Value), Line: expr.Line}
+		case token.MINUS:
+			if num, ok := literal.Value.(float64); ok {
+				return &ast.Literal{Value: -num, Line: expr.Line}
+			}
+		case token.TILDE:
+			if num, ok := literal.Value.(float64); ok {
+				if result, bitErr := native.Complement(num); bitErr == nil {
+					return &ast.Literal{Value: result, Line: expr.Line}
+				}
+			}
+		}
+	}
+	return &ast.Unary{Operator: expr.Operator, Right: right, Line: expr.Line}
+}
+
+func (f *folder) VisitVariableExpr(expr *ast.Variable) ast.Expr {
+	return expr
+}
+
+// foldBinary computes a binary operation on two constants. It reports
+// false when the operation would raise a runtime error.
+func foldBinary(op token.TokenType, left, right interface{}) (interface{}, bool) {
+	switch op {
+	case token.EQUAL_EQUAL:
+		return left == right, true
+	case token.BANG_EQUAL:
+		return left != right, true
+	case token.PLUS:
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, true
+			}
+		}
+	}
+
+	l, lOk := left.(float64)
+	r, rOk := right.(float64)
+	if !lOk || !rOk {
+		return nil, false
+	}
+	switch op {
+	case token.PLUS:
+		return l + r, true
+	case token.MINUS:
+		return l - r, true
+	case token.STAR:
+		return l * r, true
+	case token.SLASH:
+		return l / r, true
+	case token.PERCENT:
+		return native.Modulo(l, r), true
+	case token.TILDE_SLASH:
+		return native.FloorDivide(l, r), true
+	case token.STAR_STAR:
+		return math.Pow(l, r), true
+	case token.AMPERSAND, token.PIPE, token.CARET, token.LESS_LESS, token.GREATER_GREATER:
+		result, bitErr := native.Bitwise(op, l, r)
+		return result, bitErr == nil
+	case token.GREATER:
+		return l > r, true
+	case token.GREATER_EQUAL:
+		return l >= r, true
+	case token.LESS:
+		return l < r, true
+	case token.LESS_EQUAL:
+		return l <= r, true
+	}
+	return nil, false
+}
+
+func isTruthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if truth, ok := v.(bool); ok {
+		return truth
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package optimize
+
+import (
+	"testing"
+
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+	"github.com/nicholasq/glox/util"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Arithmetic",
+			input:    "var day = (60 * 60 * 24);",
+			expected: []string{"(var day = 86400)"},
+		},
+		{
+			name:     "Partially constant",
+			input:    "print x + (2 * 3) - -(1);",
+			expected: []string{"(print (- (+ x 6) -1))"},
+		},
+		{
+			name:     "Comparison and equality",
+			input:    "print 1 < 2 == !nil;",
+			expected: []string{"(print true)"},
+		},
+		{
+			name:     "String concatenation",
+			input:    `print "a" + "b";`,
+			expected: []string{`(print "ab")`},
+		},
+		{
+			name:     "Runtime errors are preserved",
+			input:    `print 1 / "a"; print -"a"; print "a" < "b"; print 1 + nil;`,
+			expected: []string{`(print (/ 1 "a"))`, `(print (- "a"))`, `(print (< "a" "b"))`, "(print (+ 1 nil))"},
+		},
+		{
+			name:     "Dead expression statements",
+			input:    "1 + 2; (true); x; print 1;",
+			expected: []string{"(; x)", "(print 1)"},
+		},
+		{
+			name:     "Call arguments",
+			input:    `print f(1 + 2, "a" + "b")(-(4));`,
+			expected: []string{`(print (call (call f 3 "ab") -4))`},
+		},
+		{
+			name:     "Conditional with a constant condition",
+			input:    "print 1 < 2 ? f(1 + 1) : g(); print nil ? a : b ? 1 : 2 * 3;",
+			expected: []string{"(print (call f 2))", "(print (?: b 1 6))"},
+		},
+		{
+			name:     "Constants before a comma",
+			input:    "print (1, f(), 2 + 3); print x, 1;",
+			expected: []string{"(print (group (, (call f) 5)))", "(print (, x 1))"},
+		},
+		{
+			name:     "Loops and branches",
+			input:    "while (1 < 2) { print -(1); } if (!true) print 2 * 3; else x = 1 + 1;",
+			expected: []string{"(while true (block (print -1)))", "(; (= x 2))"},
+		},
+		{
+			name:     "Dead loop bodies and branches become empty blocks",
+			input:    "for (var x in xs) 1; while (a) nil; if (b) true; else false;",
+			expected: []string{"(for x in xs (block))", "(while a (block))", "(if b (block))"},
+		},
+		{
+			name:     "Modulo, integer division and exponent",
+			input:    "print -7 % 3; print 7 ~/ 2; print 2 ** 3 ** 2;",
+			expected: []string{"(print 2)", "(print 3)", "(print 512)"},
+		},
+		{
+			name:     "Bitwise operators",
+			input:    "print 12 & 10 | 1 << 4; print ~5 ^ 1;",
+			expected: []string{"(print 24)", "(print -5)"},
+		},
+		{
+			name:     "Bitwise errors are preserved",
+			input:    "print 1.5 & 1; print 1 >> -1; print ~0.5;",
+			expected: []string{"(print (& 1.5 1))", "(print (>> 1 -1))", "(print (~ 0.5))"},
+		},
+		{
+			name:     "Constant if conditions",
+			input:    `if ("yes") { print 1; } else print 2; if (nil) print 3; while (a) if (0 > 1) print 4;`,
+			expected: []string{"(block (print 1))", "(while a (block))"},
+		},
+		{
+			name:     "Statements after a jump",
+			input:    "while (a) { if (b) { continue; print 1; } break; print 2; } var f = fun () { return 1; print 3; };",
+			expected: []string{"(while a (block (if b (block (continue))) (break)))", "(var f = (fun () (return 1)))"},
+		},
+		{
+			name:     "Blocks and branches that always jump",
+			input:    "var f = () => { if (a) return 1; else { return 2; } print 3; }; { { throw 1; } print 1; } print 2;",
+			expected: []string{"(var f = (fun () (if a (return 1) (block (return 2)))))", "(block (block (throw 1)))"},
+		},
+		{
+			name:     "Calls are kept for their effects",
+			input:    "clock(); len(1 + 1);",
+			expected: []string{"(; (call clock))", "(; (call len 2))"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := scanner.New(tt.input)
+			stmts, parseErr := parser.New(s.ScanTokens()).Parse()
+			if parseErr != nil {
+				t.Fatalf("Error during parsing: %s", parseErr)
+			}
+
+			optimized := Optimize(stmts)
+			if len(optimized) != len(tt.expected) {
+				t.Fatalf("Expected %d statements, got %d", len(tt.expected), len(optimized))
+			}
+			printer := util.AstPrinter{}
+			for idx, stmt := range optimized {
+				if actual := printer.PrintStmt(stmt); actual != tt.expected[idx] {
+					t.Fatalf("Expected %s, got %s", tt.expected[idx], actual)
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,34 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/nicholasq/glox/ast"
 	err "github.com/nicholasq/glox/error"
 	"github.com/nicholasq/glox/token"
 )
 
+// ErrParse is returned when the parser encountered one or more syntax errors.
+// The errors themselves are reported through the error package, or the
+// parser's reporter, as they are found.
+var ErrParse = errors.New("parse error")
+
 type Parser struct {
-	tokens  []token.Token
-	current int
+	tokens   []token.Token
+	current  int
+	hadError bool
+	// loopDepth is the number of loops enclosing the statement being
+	// parsed, so that break and continue outside of one can be reported.
+	// It starts again from 0 in a function body.
+	loopDepth int
+	// functionDepth is the number of function bodies enclosing the
+	// statement being parsed, so that return outside of one can be
+	// reported.
+	functionDepth int
+	// reporter is where syntax errors are reported, the error package's
+	// Writer if it is nil.
+	reporter *err.Reporter
 }
 
 func New(tokens []token.Token) *Parser {
@@ -18,23 +38,64 @@ func New(tokens []token.Token) *Parser {
 	}
 }
 
+// SetReporter sets where syntax errors are reported.
+func (p *Parser) SetReporter(reporter *err.Reporter) {
+	p.reporter = reporter
+}
+
 func (p *Parser) Parse() ([]ast.Stmt, error) {
 	var stmts []ast.Stmt
 	for !p.isAtEnd() {
-		stmts = append(stmts, p.declaration())
+		if stmt := p.declaration(); stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+	}
+	if p.hadError {
+		return stmts, ErrParse
 	}
 	return stmts, nil
 }
 
-func (p *Parser) declaration() ast.Stmt {
-	//todo need to catch errors and call p.synchronize in the 'catch' block
+// ParseExpression parses the tokens as a single expression, such as a
+// debugger watch or breakpoint condition, and fails if any tokens remain.
+func (p *Parser) ParseExpression() (expr ast.Expr, result error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != ErrParse {
+				panic(r)
+			}
+			expr, result = nil, ErrParse
+		}
+	}()
+	expr = p.expression()
+	if !p.isAtEnd() {
+		p.logError(p.peek(), "Expect end of expression.")
+	}
+	return expr, nil
+}
+
+func (p *Parser) declaration() (stmt ast.Stmt) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != ErrParse {
+				panic(r)
+			}
+			p.hadError = true
+			p.synchronize()
+			stmt = nil
+		}
+	}()
 	if p.nextTokensMatchAny(token.VAR) {
 		return p.varDeclaration()
 	}
+	if p.nextTokensMatchAny(token.IMPORT) {
+		return p.importDeclaration()
+	}
 	return p.statement()
 }
 
 func (p *Parser) varDeclaration() ast.Stmt {
+	line := p.previous().Line
 	name := p.consume(token.IDENTIFIER, "Expect variable name.")
 
 	var initializer ast.Expr = nil
@@ -43,7 +104,33 @@ func (p *Parser) varDeclaration() ast.Stmt {
 		initializer = p.expression()
 	}
 	p.consume(token.SEMICOLON, "Expect ';' after variable declaration.")
-	return &ast.VarStmt{Name: name, Initializer: initializer}
+	return &ast.VarStmt{Name: name, Initializer: initializer, Line: line}
+}
+
+// importDeclaration parses an import of a whole module under a name, or
+// of some of its members. The import keyword has already been consumed.
+// "as" and "from" are not reserved words, so they are matched as
+// identifiers.
+func (p *Parser) importDeclaration() ast.Stmt {
+	keyword := p.previous()
+	stmt := &ast.ImportStmt{Keyword: keyword, Line: keyword.Line}
+	if p.nextTokensMatchAny(token.LEFT_BRACE) {
+		for {
+			stmt.Names = append(stmt.Names, p.consume(token.IDENTIFIER, "Expect name to import."))
+			if !p.nextTokensMatchAny(token.COMMA) {
+				break
+			}
+		}
+		p.consume(token.RIGHT_BRACE, "Expect '}' after imported names.")
+		p.consumeWord("from", "Expect 'from' after imported names.")
+		stmt.Path = p.consume(token.STRING, "Expect module path after 'from'.")
+	} else {
+		stmt.Path = p.consume(token.STRING, "Expect module path or '{' after 'import'.")
+		p.consumeWord("as", "Expect 'as' after module path.")
+		stmt.Alias = p.consume(token.IDENTIFIER, "Expect module name after 'as'.")
+	}
+	p.consume(token.SEMICOLON, "Expect ';' after import.")
+	return stmt
 }
 
 func (p *Parser) statement() ast.Stmt {
@@ -51,6 +138,23 @@ func (p *Parser) statement() ast.Stmt {
 		stmt := new(ast.PrintStmt)
 		*stmt = p.printStatement()
 		return stmt
+	} else if p.nextTokensMatchAny(token.FOR) {
+		return p.forStatement()
+	} else if p.nextTokensMatchAny(token.IF) {
+		return p.ifStatement()
+	} else if p.nextTokensMatchAny(token.WHILE) {
+		return p.whileStatement()
+	} else if p.nextTokensMatchAny(token.BREAK, token.CONTINUE) {
+		return p.jumpStatement()
+	} else if p.nextTokensMatchAny(token.RETURN) {
+		return p.returnStatement()
+	} else if p.nextTokensMatchAny(token.THROW) {
+		return p.throwStatement()
+	} else if p.nextTokensMatchAny(token.TRY) {
+		return p.tryStatement()
+	} else if p.currentTokenMatches(token.LEFT_BRACE) && !p.startsMapLiteral() {
+		line := p.advance().Line
+		return &ast.BlockStmt{Statements: p.block(), Line: line}
 	} else {
 		stmt := new(ast.ExpressionStmt)
 		*stmt = p.expressionStatement()
@@ -58,42 +162,303 @@ func (p *Parser) statement() ast.Stmt {
 	}
 }
 
+// forStatement parses a for-in loop or a C-style for loop, which it
+// desugars into a while loop inside a block holding the initializer. The
+// for keyword has already been consumed.
+func (p *Parser) forStatement() ast.Stmt {
+	line := p.previous().Line
+	p.consume(token.LEFT_PAREN, "Expect '(' after 'for'.")
+	if p.startsForIn() {
+		p.advance()
+		name := p.advance()
+		in := p.advance()
+		iterable := p.expression()
+		p.consume(token.RIGHT_PAREN, "Expect ')' after for clauses.")
+		return &ast.ForInStmt{Name: name, In: in, Iterable: iterable, Body: p.loopBody(), Line: line}
+	}
+
+	var initializer ast.Stmt
+	if p.nextTokensMatchAny(token.SEMICOLON) {
+		// No initializer.
+	} else if p.nextTokensMatchAny(token.VAR) {
+		initializer = p.varDeclaration()
+	} else {
+		stmt := p.expressionStatement()
+		initializer = &stmt
+	}
+	var condition ast.Expr = &ast.Literal{Value: true, Line: line}
+	if !p.currentTokenMatches(token.SEMICOLON) {
+		condition = p.expression()
+	}
+	p.consume(token.SEMICOLON, "Expect ';' after loop condition.")
+	var increment ast.Expr
+	if !p.currentTokenMatches(token.RIGHT_PAREN) {
+		increment = p.expression()
+	}
+	p.consume(token.RIGHT_PAREN, "Expect ')' after for clauses.")
+
+	var loop ast.Stmt = &ast.WhileStmt{Condition: condition, Body: p.loopBody(), Increment: increment, Line: line}
+	if initializer != nil {
+		loop = &ast.BlockStmt{Statements: []ast.Stmt{initializer, loop}, Line: line}
+	}
+	return loop
+}
+
+// startsForIn reports whether the clauses of the for loop at the current
+// token are those of a for-in loop, var IDENTIFIER in.
+func (p *Parser) startsForIn() bool {
+	return p.current+2 < len(p.tokens) &&
+		p.tokens[p.current].TokenType == token.VAR &&
+		p.tokens[p.current+1].TokenType == token.IDENTIFIER &&
+		p.tokens[p.current+2].TokenType == token.IN
+}
+
+func (p *Parser) whileStatement() ast.Stmt {
+	line := p.previous().Line
+	p.consume(token.LEFT_PAREN, "Expect '(' after 'while'.")
+	condition := p.expression()
+	p.consume(token.RIGHT_PAREN, "Expect ')' after condition.")
+	return &ast.WhileStmt{Condition: condition, Body: p.loopBody(), Line: line}
+}
+
+// loopBody parses the body of a loop, in which break and continue are
+// allowed.
+func (p *Parser) loopBody() ast.Stmt {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.statement()
+}
+
+func (p *Parser) ifStatement() ast.Stmt {
+	line := p.previous().Line
+	p.consume(token.LEFT_PAREN, "Expect '(' after 'if'.")
+	condition := p.expression()
+	p.consume(token.RIGHT_PAREN, "Expect ')' after if condition.")
+	thenBranch := p.statement()
+	var elseBranch ast.Stmt
+	if p.nextTokensMatchAny(token.ELSE) {
+		elseBranch = p.statement()
+	}
+	return &ast.IfStmt{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch, Line: line}
+}
+
+// jumpStatement parses a break or continue statement. The keyword has
+// already been consumed.
+func (p *Parser) jumpStatement() ast.Stmt {
+	keyword := p.previous()
+	if p.loopDepth == 0 {
+		// Report the error without unwinding, since the parser is not
+		// confused about where it is.
+		p.reporter.GloxError(keyword, fmt.Sprintf("Can't use '%s' outside of a loop.", keyword.Lexeme))
+		p.hadError = true
+	}
+	p.consume(token.SEMICOLON, fmt.Sprintf("Expect ';' after '%s'.", keyword.Lexeme))
+	if keyword.TokenType == token.BREAK {
+		return &ast.BreakStmt{Keyword: keyword, Line: keyword.Line}
+	}
+	return &ast.ContinueStmt{Keyword: keyword, Line: keyword.Line}
+}
+
+func (p *Parser) returnStatement() ast.Stmt {
+	keyword := p.previous()
+	if p.functionDepth == 0 {
+		p.reporter.GloxError(keyword, "Can't return from top-level code.")
+		p.hadError = true
+	}
+	var value ast.Expr
+	if !p.currentTokenMatches(token.SEMICOLON) {
+		value = p.expression()
+	}
+	p.consume(token.SEMICOLON, "Expect ';' after return value.")
+	return &ast.ReturnStmt{Keyword: keyword, Value: value, Line: keyword.Line}
+}
+
+func (p *Parser) throwStatement() ast.Stmt {
+	keyword := p.previous()
+	value := p.expression()
+	p.consume(token.SEMICOLON, "Expect ';' after thrown value.")
+	return &ast.ThrowStmt{Keyword: keyword, Value: value, Line: keyword.Line}
+}
+
+// tryStatement parses a try statement, which needs a catch clause, a
+// finally clause or both. The try keyword has already been consumed.
+func (p *Parser) tryStatement() ast.Stmt {
+	stmt := &ast.TryStmt{Line: p.previous().Line}
+	stmt.Body = p.braceBlock("Expect '{' after 'try'.")
+	if p.nextTokensMatchAny(token.CATCH) {
+		p.consume(token.LEFT_PAREN, "Expect '(' after 'catch'.")
+		stmt.CatchName = p.consume(token.IDENTIFIER, "Expect exception variable name.")
+		p.consume(token.RIGHT_PAREN, "Expect ')' after exception variable.")
+		stmt.Catch = p.braceBlock("Expect '{' after catch clause.")
+	}
+	if p.nextTokensMatchAny(token.FINALLY) {
+		stmt.Finally = p.braceBlock("Expect '{' after 'finally'.")
+	}
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.reporter.GloxError(p.peek(), "Expect 'catch' or 'finally' after try block.")
+		p.hadError = true
+	}
+	return stmt
+}
+
+// braceBlock parses a block that must be present, reporting message if the
+// current token does not open one.
+func (p *Parser) braceBlock(message string) ast.Stmt {
+	line := p.consume(token.LEFT_BRACE, message).Line
+	return &ast.BlockStmt{Statements: p.block(), Line: line}
+}
+
+// startsMapLiteral reports whether the '{' at the current token opens a map
+// literal rather than a block. A map is recognized by a first key of a
+// single token followed by a colon, as in {"a": 1}, which can't start a
+// block. Any other '{' at the start of a statement, including {}, opens a
+// block, so a map with a more complex first key must be parenthesized there.
+func (p *Parser) startsMapLiteral() bool {
+	if p.current+2 >= len(p.tokens) || p.tokens[p.current+2].TokenType != token.COLON {
+		return false
+	}
+	switch p.tokens[p.current+1].TokenType {
+	case token.STRING, token.NUMBER, token.IDENTIFIER, token.TRUE, token.FALSE, token.NIL:
+		return true
+	}
+	return false
+}
+
+// block parses the declarations of a block up to its closing brace. The
+// opening brace has already been consumed.
+func (p *Parser) block() []ast.Stmt {
+	var stmts []ast.Stmt
+	for !p.currentTokenMatches(token.RIGHT_BRACE) && !p.isAtEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+	}
+	p.consume(token.RIGHT_BRACE, "Expect '}' after block.")
+	return stmts
+}
+
 func (p *Parser) expressionStatement() ast.ExpressionStmt {
+	line := p.peek().Line
 	expr := p.expression()
 	p.consume(token.SEMICOLON, "Expect ';' after expression.")
-	return ast.ExpressionStmt{Expression: expr}
+	return ast.ExpressionStmt{Expression: expr, Line: line}
 }
 
 func (p *Parser) printStatement() ast.PrintStmt {
+	line := p.previous().Line
 	expr := p.expression()
 	p.consume(token.SEMICOLON, "Expect ';' after value.")
-	return ast.PrintStmt{Expression: expr}
+	return ast.PrintStmt{Expression: expr, Line: line}
 }
 
 /*
 	Grammar:
 	program        -> declaration* EOF ;
-    declaration    -> varDecl | statement;
+    declaration    -> varDecl | importDecl | statement;
 	varDecl 	   -> "var" IDENTIFIER ( "=" expression )? ";" ;
-	statement      -> exprStmt | printStmt;
-	expression     → equality ;
+	importDecl     -> "import" STRING "as" IDENTIFIER ";"
+				   | "import" "{" IDENTIFIER ( "," IDENTIFIER )* "}" "from" STRING ";" ;
+	statement      -> exprStmt | printStmt | forStmt | ifStmt | whileStmt
+				   | breakStmt | continueStmt | returnStmt | throwStmt
+				   | tryStmt | block;
+	forStmt        -> "for" "(" "var" IDENTIFIER "in" expression ")" statement
+				   | "for" "(" ( varDecl | exprStmt | ";" )
+				     expression? ";" expression? ")" statement ;
+	ifStmt         -> "if" "(" expression ")" statement ( "else" statement )? ;
+	whileStmt      -> "while" "(" expression ")" statement ;
+	breakStmt      -> "break" ";" ;
+	returnStmt     -> "return" expression? ";" ;
+	throwStmt      -> "throw" expression ";" ;
+	tryStmt        -> "try" block ( "catch" "(" IDENTIFIER ")" block )?
+				     ( "finally" block )? ;
+	continueStmt   -> "continue" ";" ;
+	block          -> "{" declaration* "}" ;
+	expression     → comma ;
+	comma          → assignment ( "," assignment )* ;
+	assignment     → ( IDENTIFIER | call "[" expression "]" ) "=" assignment
+				   | conditional ;
+	conditional    → equality ( "?" expression ":" conditional )? ;
 	equality       → comparison ( ( "!=" | "==" ) comparison )* ;
-	comparison     → term ( ( ">" | ">=" | "<" | "<=" ) term )* ;
+	comparison     → bitwiseOr ( ( ">" | ">=" | "<" | "<=" ) bitwiseOr )* ;
+	bitwiseOr      → bitwiseXor ( "|" bitwiseXor )* ;
+	bitwiseXor     → bitwiseAnd ( "^" bitwiseAnd )* ;
+	bitwiseAnd     → shift ( "&" shift )* ;
+	shift          → term ( ( "<<" | ">>" ) term )* ;
 	term           → factor ( ( "-" | "+" ) factor )* ;
-	factor         → unary ( ( "/" | "*" ) unary )* ;
-	unary          → ( "!" | "-" ) unary
-				   | primary ;
+	factor         → unary ( ( "/" | "*" | "%" | "~/" ) unary )* ;
+	unary          → ( "!" | "-" | "~" ) unary
+				   | exponent ;
+	exponent       → call ( "**" unary )? ;
+	call           → primary ( "(" arguments? ")" | "." IDENTIFIER
+				   | "[" ( expression | expression? ":" expression? ) "]" )* ;
+	arguments      → assignment ( "," assignment )* ;
 	primary        → NUMBER | STRING | "true" | "false" | "nil"
-				   | "(" expression ")" | IDENTIFIER ;
+				   | "(" expression ")" | IDENTIFIER
+				   | "[" ( assignment ( "," assignment )* )? "]"
+				   | "{" ( entry ( "," entry )* )? "}"
+				   | "fun" "(" parameters? ")" block
+				   | "(" parameters? ")" "=>" ( block | assignment )
+				   | binaryOperator expression ;
+	entry          → assignment ":" assignment ;
+	parameters     → IDENTIFIER ( "," IDENTIFIER )* ;
 */
 
 // expression parses and returns an expression.
-// It calls the equality method to handle equality operators.
-// If there are multiple equality operators, it iterates over them and
-// constructs a Binary expression.
+// It calls the comma method, the rule with the lowest precedence.
 // Returns the parsed expression.
 func (p *Parser) expression() ast.Expr {
-	return p.equality()
+	return p.comma()
+}
+
+// comma parses expressions separated by the comma operator, which
+// evaluates them in turn and takes the value of the last. Where commas
+// separate arguments, elements or entries, their expressions are parsed
+// with assignment instead, so a comma expression must be parenthesized
+// there.
+func (p *Parser) comma() ast.Expr {
+	expr := p.assignment()
+	for p.nextTokensMatchAny(token.COMMA) {
+		operator := p.previous()
+		right := p.assignment()
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right, Line: expr.Pos()}
+	}
+	return expr
+}
+
+// assignment parses an assignment to a list element, or a conditional
+// expression. The target is parsed as an ordinary expression first and
+// then checked, since it can't be told apart from one until the '='.
+func (p *Parser) assignment() ast.Expr {
+	expr := p.conditional()
+	if p.nextTokensMatchAny(token.EQUAL) {
+		equals := p.previous()
+		value := p.assignment()
+		if variable, ok := expr.(*ast.Variable); ok {
+			return &ast.Assign{Name: variable.Name, Value: value, Line: variable.Line}
+		}
+		if index, ok := expr.(*ast.Index); ok {
+			return &ast.IndexSet{Object: index.Object, Bracket: index.Bracket, Index: index.Index, Value: value, Line: index.Line}
+		}
+		// Report the error without unwinding, since the parser is not
+		// confused about where it is.
+		p.reporter.GloxError(equals, "Invalid assignment target.")
+		p.hadError = true
+	}
+	return expr
+}
+
+// conditional parses the ternary operator, which is right associative:
+// a ? b : c ? d : e groups as a ? b : (c ? d : e). As in C, the branch
+// between '?' and ':' may be any expression.
+func (p *Parser) conditional() ast.Expr {
+	expr := p.equality()
+	if p.nextTokensMatchAny(token.QUESTION) {
+		thenBranch := p.expression()
+		p.consume(token.COLON, "Expect ':' after then branch of conditional expression.")
+		elseBranch := p.conditional()
+		return &ast.Conditional{Condition: expr, ThenBranch: thenBranch, ElseBranch: elseBranch, Line: expr.Pos()}
+	}
+	return expr
 }
 
 // equality parses and returns an expression.
@@ -106,7 +471,7 @@ func (p *Parser) equality() ast.Expr {
 	for p.nextTokensMatchAny(token.BANG_EQUAL, token.EQUAL_EQUAL) {
 		operator := p.previous()
 		right := p.comparison()
-		expr = &ast.Binary{Left: expr, Operator: operator, Right: right}
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right, Line: expr.Pos()}
 	}
 	return expr
 }
@@ -117,11 +482,55 @@ func (p *Parser) equality() ast.Expr {
 // constructs a Binary expression.
 // Returns the parsed expression.
 func (p *Parser) comparison() ast.Expr {
-	expr := p.term()
+	expr := p.bitwiseOr()
 	for p.nextTokensMatchAny(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL) {
 		operator := p.previous()
-		term := p.term()
-		expr = &ast.Binary{Left: expr, Operator: operator, Right: term}
+		right := p.bitwiseOr()
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right, Line: expr.Pos()}
+	}
+	return expr
+}
+
+// bitwiseOr, bitwiseXor, bitwiseAnd and shift parse the bitwise operators.
+// As in Python, they bind more tightly than the comparisons, so that
+// x & 1 == 0 tests the low bit of x, and the shifts bind less tightly than
+// arithmetic.
+func (p *Parser) bitwiseOr() ast.Expr {
+	expr := p.bitwiseXor()
+	for p.nextTokensMatchAny(token.PIPE) {
+		operator := p.previous()
+		right := p.bitwiseXor()
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right, Line: expr.Pos()}
+	}
+	return expr
+}
+
+func (p *Parser) bitwiseXor() ast.Expr {
+	expr := p.bitwiseAnd()
+	for p.nextTokensMatchAny(token.CARET) {
+		operator := p.previous()
+		right := p.bitwiseAnd()
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right, Line: expr.Pos()}
+	}
+	return expr
+}
+
+func (p *Parser) bitwiseAnd() ast.Expr {
+	expr := p.shift()
+	for p.nextTokensMatchAny(token.AMPERSAND) {
+		operator := p.previous()
+		right := p.shift()
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right, Line: expr.Pos()}
+	}
+	return expr
+}
+
+func (p *Parser) shift() ast.Expr {
+	expr := p.term()
+	for p.nextTokensMatchAny(token.LESS_LESS, token.GREATER_GREATER) {
+		operator := p.previous()
+		right := p.term()
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right, Line: expr.Pos()}
 	}
 	return expr
 }
@@ -135,7 +544,7 @@ func (p *Parser) term() ast.Expr {
 	for p.nextTokensMatchAny(token.MINUS, token.PLUS) {
 		operator := p.previous()
 		factor := p.factor()
-		expr = &ast.Binary{Left: expr, Operator: operator, Right: factor}
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: factor, Line: expr.Pos()}
 	}
 	return expr
 }
@@ -146,58 +555,295 @@ func (p *Parser) term() ast.Expr {
 // Returns the parsed factor expression.
 func (p *Parser) factor() ast.Expr {
 	expr := p.unary()
-	for p.nextTokensMatchAny(token.SLASH, token.STAR) {
+	for p.nextTokensMatchAny(token.SLASH, token.STAR, token.PERCENT, token.TILDE_SLASH) {
 		operator := p.previous()
 		factor := p.unary()
-		expr = &ast.Binary{Left: expr, Operator: operator, Right: factor}
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: factor, Line: expr.Pos()}
 	}
 	return expr
 }
 
 // unary parses and returns a unary expression.
-// If the current token is a BANG or MINUS token, it consumes the token,
+// If the current token is a BANG, MINUS or TILDE token, it consumes the token,
 // recursively calls unary to parse the operand, and returns a Unary expression.
-// Otherwise, it calls primary method to parse the primary expression.
+// Otherwise, it calls exponent method to parse the operand.
 // Returns the parsed unary expression.
 func (p *Parser) unary() ast.Expr {
-	if p.nextTokensMatchAny(token.BANG, token.MINUS) {
+	if p.nextTokensMatchAny(token.BANG, token.MINUS, token.TILDE) {
+		operator := p.previous()
+		right := p.unary()
+		return &ast.Unary{Operator: operator, Right: right, Line: operator.Line}
+	}
+	return p.exponent()
+}
+
+// exponent parses the ** operator, which binds more tightly than a unary
+// operator on its left but not one on its right, so -2 ** 2 is -(2 ** 2)
+// and 2 ** -1 is 0.5. Parsing the right operand with unary, which comes
+// back here, makes the operator right associative: 2 ** 3 ** 2 groups as
+// 2 ** (3 ** 2).
+func (p *Parser) exponent() ast.Expr {
+	expr := p.call()
+	if p.nextTokensMatchAny(token.STAR_STAR) {
 		operator := p.previous()
 		right := p.unary()
-		return &ast.Unary{Operator: operator, Right: right}
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right, Line: expr.Pos()}
+	}
+	return expr
+}
+
+// maxArguments is the most arguments a call may pass, matching the one-byte
+// argument count of the vm's call instruction.
+const maxArguments = 255
+
+// call parses a primary expression followed by any number of argument lists,
+// property accesses and subscripts.
+func (p *Parser) call() ast.Expr {
+	expr := p.primary()
+	for {
+		if p.nextTokensMatchAny(token.LEFT_PAREN) {
+			expr = p.finishCall(expr)
+		} else if p.nextTokensMatchAny(token.DOT) {
+			name := p.consume(token.IDENTIFIER, "Expect property name after '.'.")
+			expr = &ast.Get{Object: expr, Name: name, Line: expr.Pos()}
+		} else if p.nextTokensMatchAny(token.LEFT_BRACKET) {
+			expr = p.finishIndex(expr)
+		} else {
+			return expr
+		}
+	}
+}
+
+func (p *Parser) finishCall(callee ast.Expr) ast.Expr {
+	var arguments []ast.Expr
+	if !p.currentTokenMatches(token.RIGHT_PAREN) {
+		for {
+			if len(arguments) >= maxArguments {
+				// Report the error without unwinding, since the parser
+				// is not confused about where it is.
+				p.reporter.GloxError(p.peek(), fmt.Sprintf("Can't have more than %d arguments.", maxArguments))
+				p.hadError = true
+			}
+			arguments = append(arguments, p.assignment())
+			if !p.nextTokensMatchAny(token.COMMA) {
+				break
+			}
+		}
 	}
-	return p.primary()
+	paren := p.consume(token.RIGHT_PAREN, "Expect ')' after arguments.")
+	return &ast.Call{Callee: callee, Paren: paren, Arguments: arguments, Line: callee.Pos()}
+}
+
+// finishIndex parses the rest of a subscript, either an index or a slice
+// with optional bounds.
+func (p *Parser) finishIndex(object ast.Expr) ast.Expr {
+	var low ast.Expr
+	if !p.currentTokenMatches(token.COLON) {
+		low = p.expression()
+	}
+	if !p.nextTokensMatchAny(token.COLON) {
+		bracket := p.consume(token.RIGHT_BRACKET, "Expect ']' after index.")
+		return &ast.Index{Object: object, Bracket: bracket, Index: low, Line: object.Pos()}
+	}
+	var high ast.Expr
+	if !p.currentTokenMatches(token.RIGHT_BRACKET) {
+		high = p.expression()
+	}
+	bracket := p.consume(token.RIGHT_BRACKET, "Expect ']' after slice.")
+	return &ast.Slice{Object: object, Bracket: bracket, Low: low, High: high, Line: object.Pos()}
 }
 
 func (p *Parser) primary() ast.Expr {
 	if p.nextTokensMatchAny(token.FALSE) {
-		return &ast.Literal{Value: false}
+		return &ast.Literal{Value: false, Line: p.previous().Line}
 	}
 	if p.nextTokensMatchAny(token.TRUE) {
-		return &ast.Literal{Value: true}
+		return &ast.Literal{Value: true, Line: p.previous().Line}
 	}
 	if p.nextTokensMatchAny(token.NIL) {
-		return &ast.Literal{Value: nil}
+		return &ast.Literal{Value: nil, Line: p.previous().Line}
 	}
 	if p.nextTokensMatchAny(token.NUMBER, token.STRING) {
-		return &ast.Literal{Value: p.previous().Literal}
+		return &ast.Literal{Value: p.previous().Literal, Line: p.previous().Line}
 	}
 	if p.nextTokensMatchAny(token.IDENTIFIER) {
-		return &ast.Variable{Name: p.previous()}
+		return &ast.Variable{Name: p.previous(), Line: p.previous().Line}
+	}
+	if p.nextTokensMatchAny(token.FUN) {
+		keyword := p.previous()
+		p.consume(token.LEFT_PAREN, "Expect '(' after 'fun'.")
+		params := p.parameters()
+		p.consume(token.LEFT_BRACE, "Expect '{' before function body.")
+		body := p.functionBody(p.block)
+		return &ast.Lambda{Keyword: keyword, Params: params, Body: body, Line: keyword.Line}
+	}
+	if p.currentTokenMatches(token.LEFT_PAREN) && p.startsArrow() {
+		return p.arrowFunction()
 	}
 	if p.nextTokensMatchAny(token.LEFT_PAREN) {
+		line := p.previous().Line
 		expr := p.expression()
 		p.consume(token.RIGHT_PAREN, "Expect ')' after expression.")
-		return &ast.Grouping{Expression: expr}
+		return &ast.Grouping{Expression: expr, Line: line}
+	}
+	if p.nextTokensMatchAny(token.LEFT_BRACKET) {
+		line := p.previous().Line
+		var elements []ast.Expr
+		if !p.currentTokenMatches(token.RIGHT_BRACKET) {
+			for {
+				elements = append(elements, p.assignment())
+				if !p.nextTokensMatchAny(token.COMMA) {
+					break
+				}
+			}
+		}
+		p.consume(token.RIGHT_BRACKET, "Expect ']' after list elements.")
+		return &ast.ListLiteral{Elements: elements, Line: line}
+	}
+	if p.nextTokensMatchAny(token.LEFT_BRACE) {
+		line := p.previous().Line
+		var keys, values []ast.Expr
+		if !p.currentTokenMatches(token.RIGHT_BRACE) {
+			for {
+				keys = append(keys, p.assignment())
+				p.consume(token.COLON, "Expect ':' after map key.")
+				values = append(values, p.assignment())
+				if !p.nextTokensMatchAny(token.COMMA) {
+					break
+				}
+			}
+		}
+		brace := p.consume(token.RIGHT_BRACE, "Expect '}' after map entries.")
+		return &ast.MapLiteral{Keys: keys, Values: values, Brace: brace, Line: line}
+	}
+
+	if operand := p.rightOperandRule(p.peek().TokenType); operand != nil {
+		return p.missingLeftOperand(operand)
 	}
 
 	p.logError(p.peek(), "Expect expression.")
 	panic("We shouldn't have gotten here...")
 }
 
+// rightOperandRule returns the rule parsing the right operand of a binary
+// operator that cannot start an expression, or nil for any other token.
+func (p *Parser) rightOperandRule(tokenType token.TokenType) func() ast.Expr {
+	switch tokenType {
+	case token.COMMA:
+		return p.assignment
+	case token.BANG_EQUAL, token.EQUAL_EQUAL:
+		return p.comparison
+	case token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL:
+		return p.bitwiseOr
+	case token.PIPE:
+		return p.bitwiseXor
+	case token.CARET:
+		return p.bitwiseAnd
+	case token.AMPERSAND:
+		return p.shift
+	case token.LESS_LESS, token.GREATER_GREATER:
+		return p.term
+	case token.PLUS:
+		return p.factor
+	case token.SLASH, token.STAR, token.PERCENT, token.TILDE_SLASH, token.STAR_STAR:
+		return p.unary
+	}
+	return nil
+}
+
+// missingLeftOperand reports a binary operator found where an expression
+// should start. It parses the right operand with operand and returns it in
+// place of the whole expression, so that parsing carries on without
+// further errors.
+func (p *Parser) missingLeftOperand(operand func() ast.Expr) ast.Expr {
+	operator := p.advance()
+	p.reporter.GloxError(operator, "Missing left-hand operand.")
+	p.hadError = true
+	return operand()
+}
+
+// startsArrow reports whether the '(' at the current token starts the
+// parameters of an arrow function, a possibly empty list of names followed
+// by ')' and '=>', rather than a grouping.
+func (p *Parser) startsArrow() bool {
+	idx := p.current + 1
+	if p.tokens[idx].TokenType != token.RIGHT_PAREN {
+		for p.tokens[idx].TokenType == token.IDENTIFIER && p.tokens[idx+1].TokenType == token.COMMA {
+			idx += 2
+		}
+		if p.tokens[idx].TokenType != token.IDENTIFIER {
+			return false
+		}
+		idx++
+	}
+	return p.tokens[idx].TokenType == token.RIGHT_PAREN && p.tokens[idx+1].TokenType == token.ARROW
+}
+
+// arrowFunction parses an arrow function, whose body is either a block or
+// an expression that it returns. As at the start of a statement, a '{'
+// after the arrow opens a block unless it begins a map literal.
+func (p *Parser) arrowFunction() ast.Expr {
+	line := p.advance().Line
+	params := p.parameters()
+	arrow := p.consume(token.ARROW, "Expect '=>' after parameters.")
+	var body []ast.Stmt
+	if p.currentTokenMatches(token.LEFT_BRACE) && !p.startsMapLiteral() {
+		p.advance()
+		body = p.functionBody(p.block)
+	} else {
+		body = p.functionBody(func() []ast.Stmt {
+			value := p.assignment()
+			return []ast.Stmt{&ast.ReturnStmt{Keyword: arrow, Value: value, Line: value.Pos()}}
+		})
+	}
+	return &ast.Lambda{Keyword: arrow, Params: params, Body: body, Line: line}
+}
+
+// parameters parses the parameter names of a function up to the closing
+// parenthesis. The opening parenthesis has already been consumed.
+func (p *Parser) parameters() []token.Token {
+	var params []token.Token
+	if !p.currentTokenMatches(token.RIGHT_PAREN) {
+		for {
+			if len(params) >= maxArguments {
+				p.reporter.GloxError(p.peek(), fmt.Sprintf("Can't have more than %d parameters.", maxArguments))
+				p.hadError = true
+			}
+			param := p.consume(token.IDENTIFIER, "Expect parameter name.")
+			for _, other := range params {
+				if other.Lexeme == param.Lexeme {
+					p.reporter.GloxError(param, "Already a parameter with this name.")
+					p.hadError = true
+				}
+			}
+			params = append(params, param)
+			if !p.nextTokensMatchAny(token.COMMA) {
+				break
+			}
+		}
+	}
+	p.consume(token.RIGHT_PAREN, "Expect ')' after parameters.")
+	return params
+}
+
+// functionBody parses the statements of a function body with parse. return
+// is allowed in the body, while break and continue are only allowed in
+// loops inside it.
+func (p *Parser) functionBody(parse func() []ast.Stmt) []ast.Stmt {
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	p.functionDepth++
+	defer func() {
+		p.loopDepth = loopDepth
+		p.functionDepth--
+	}()
+	return parse()
+}
+
 func (p *Parser) logError(token token.Token, message string) {
 	//todo call glox.error
-	err.GloxError(token, message)
-	panic("Parse error")
+	p.reporter.GloxError(token, message)
+	panic(ErrParse)
 }
 
 func (p *Parser) consume(tokenType token.TokenType, message string) token.Token {
@@ -205,7 +851,18 @@ func (p *Parser) consume(tokenType token.TokenType, message string) token.Token
 		return p.advance()
 	}
 	p.logError(p.peek(), message)
-	panic("Parse error")
+	panic(ErrParse)
+}
+
+// consumeWord consumes an identifier spelled word, such as a word that is
+// a keyword only in one place, reporting message if the current token is
+// anything else.
+func (p *Parser) consumeWord(word string, message string) token.Token {
+	if p.currentTokenMatches(token.IDENTIFIER) && p.peek().Lexeme == word {
+		return p.advance()
+	}
+	p.logError(p.peek(), message)
+	panic(ErrParse)
 }
 
 func (p *Parser) nextTokensMatchAny(tokenType ...token.TokenType) bool {
@@ -226,7 +883,9 @@ func (p *Parser) currentTokenMatches(tokenType token.TokenType) bool {
 }
 
 func (p *Parser) advance() token.Token {
-	p.current++
+	if !p.isAtEnd() {
+		p.current++
+	}
 	return p.previous()
 }
 
@@ -249,14 +908,7 @@ func (p *Parser) synchronize() {
 			return
 		}
 		switch p.peek().TokenType {
-		case token.CLASS:
-		case token.FUN:
-		case token.VAR:
-		case token.FOR:
-		case token.IF:
-		case token.WHILE:
-		case token.PRINT:
-		case token.RETURN:
+		case token.CLASS, token.FUN, token.VAR, token.IMPORT, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN:
 			return
 		}
 		p.advance()
@@ -1,12 +1,15 @@
 package parser
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/nicholasq/glox/ast"
 	"github.com/nicholasq/glox/scanner"
 	"github.com/nicholasq/glox/token"
+	"github.com/nicholasq/glox/util"
 )
 
 func TestParse(t *testing.T) {
@@ -77,6 +80,211 @@ func TestParse(t *testing.T) {
 	}
 }
 
+// TestParseSExpr states the expected trees as S-expressions, which are
+// read back into nodes to check that they are well formed.
+func TestParseSExpr(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Precedence", "print 1 + 2 * 3 - 4;", "(print (- (+ 1 (* 2 3)) 4))"},
+		{"Comparison and equality", "a < b == !c >= d;", "(; (== (< a b) (>= (! c) d)))"},
+		{"Unary chain", `var s = - -"x";`, `(var s = (- (- "x")))`},
+		{"Grouping", "print (1 + 2) / (3);", "(print (/ (group (+ 1 2)) (group 3)))"},
+		{"Several statements", "var a;\nprint a;", "(var a)\n(print a)"},
+		{"Call", `print f(1, "a" + b);`, `(print (call f 1 (+ "a" b)))`},
+		{"Call without arguments", "clock();", "(; (call clock))"},
+		{"Chained calls", "f(1)(2)();", "(; (call (call (call f 1) 2)))"},
+		{"Call binds tighter than unary", "print -f(x);", "(print (- (call f x)))"},
+		{"Property access", "print -math.sqrt(a.b).c;", "(print (- (. (call (. math sqrt) (. a b)) c)))"},
+		{"Index", `print s[i + 1][0].upper();`, "(print (call (. (index (index s (+ i 1)) 0) upper)))"},
+		{"List literal", `print [1, [], ["a", x]];`, `(print (list 1 (list) (list "a" x)))`},
+		{"Index assignment", "xs[0] = ys[1] = 2;", "(; (setindex xs 0 (setindex ys 1 2)))"},
+		{"Index assignment target", "a.b[c(d)] = e;", "(; (setindex (. a b) (call c d) e))"},
+		{"Slices", "print xs[1:2][:n][-1:][:];", "(print (slice (slice (slice (slice xs 1 : 2) : n) (- 1) :) :))"},
+		{"Slice bounds are expressions", "print xs[a + 1:b[0]];", "(print (slice xs (+ a 1) : (index b 0)))"},
+		{"Map literal", `print {"a": 1, b: {}, 2: [x]};`, `(print (map "a" 1 b (map) 2 (list x)))`},
+		{"Block", "{ var a = 1; { print a; } }", "(block (var a = 1) (block (print a)))"},
+		{"Empty block", "{}", "(block)"},
+		{"Map at the start of a statement", `{"a": 1}["a"] = 2;`, `(; (setindex (map "a" 1) "a" 2))`},
+		{"Block starting with an expression", "{ a; }", "(block (; a))"},
+		{"For-in", "for (var x in xs[1:]) print x;", "(for x in (slice xs 1 :) (print x))"},
+		{"Assignment", "a = b = xs[0] = 1;", "(; (= a (= b (setindex xs 0 1))))"},
+		{"If", "if (a) print 1;", "(if a (print 1))"},
+		{"Dangling else", "if (a) if (b) print 1; else print 2;", "(if a (if b (print 1) (print 2)))"},
+		{"While", "while (i < 3) i = i + 1;", "(while (< i 3) (; (= i (+ i 1))))"},
+		{"For", "for (var i = 0; i < 3; i = i + 1) print i;", "(block (var i = 0) (while (< i 3) (print i) (= i (+ i 1))))"},
+		{"For without clauses", "for (;;) break;", "(while true (break))"},
+		{"For with an expression initializer", "for (i = 0; ; ) continue;", "(block (; (= i 0)) (while true (continue)))"},
+		{"Break and continue in nested loops", "while (a) { for (var x in xs) continue; break; }", "(while a (block (for x in xs (continue)) (break)))"},
+		{"Throw", `throw "a" + b;`, `(throw (+ "a" b))`},
+		{"Try with catch", "try { f(); } catch (e) { print e; }", "(try (block (; (call f))) (catch e (block (print e))))"},
+		{"Try with finally", "try {} finally { x = 1; }", "(try (block) (finally (block (; (= x 1)))))"},
+		{"Try with catch and finally", "try {} catch (e) {} finally {}", "(try (block) (catch e (block)) (finally (block)))"},
+		{"Import as", `import "lib/util.lox" as util;`, `(import "lib/util.lox" as util)`},
+		{"Import from", `import { a, from, as } from "util.lox";`, `(import a from as from "util.lox")`},
+		{"Import in a block", `{ import { a } from "a.lox"; }`, `(block (import a from "a.lox"))`},
+		{"For-in with a block", "for (var k in {}) { for (var c in k) {} }", "(for k in (map) (block (for c in k (block))))"},
+		{"Conditional", "print a == b ? c : d;", "(print (?: (== a b) c d))"},
+		{"Conditional is right associative", "print a ? b : c ? d : e;", "(print (?: a b (?: c d e)))"},
+		{"Conditional branch between ? and :", "print a ? b = 1, c : d;", "(print (?: a (, (= b 1) c) d))"},
+		{"Assignment of a conditional", "x = a ? b : c;", "(; (= x (?: a b c)))"},
+		{"Comma", "a = 1, b = 2, c;", "(; (, (, (= a 1) (= b 2)) c))"},
+		{"Comma in a for clause", "for (i = 0, j = 9; i < j; i = i + 1, j = j - 1) {}", "(block (; (, (= i 0) (= j 9))) (while (< i j) (block) (, (= i (+ i 1)) (= j (- j 1)))))"},
+		{"Comma separates arguments, elements and entries", `f(a, (b, c)); print [a, b]; print {"a": 1, "b": 2};`, `(; (call f a (group (, b c)))) (print (list a b)) (print (map "a" 1 "b" 2))`},
+		{"Arrow function body stops at a comma", "f((a) => a, b);", "(; (call f (fun (a) (return a)) b))"},
+		{"Lambda", "var f = fun (a, b) { return a + b; };", "(var f = (fun (a b) (return (+ a b))))"},
+		{"Lambda called at once", "fun () { return; }();", "(; (call (fun () (return))))"},
+		{"Arrow function", "var f = (x) => x * 2;", "(var f = (fun (x) (return (* x 2))))"},
+		{"Arrow function without parameters", "print (() => 1)();", "(print (call (group (fun () (return 1)))))"},
+		{"Arrow function with a block", "f((a, b) => { print a; });", "(; (call f (fun (a b) (print a))))"},
+		{"Arrow function returning a map", `f(() => {"a": 1});`, `(; (call f (fun () (return (map "a" 1)))))`},
+		{"Arrow function returning an arrow function", "f((a) => (b) => a);", "(; (call f (fun (a) (return (fun (b) (return a))))))"},
+		{"Grouping is not an arrow function", "print (a) + (b);", "(print (+ (group a) (group b)))"},
+		{"Loop in a lambda", "while (a) f(fun () { while (b) break; });", "(while a (; (call f (fun () (while b (break))))))"},
+		{"Modulo and integer division", "print a % b ~/ c * d;", "(print (* (~/ (% a b) c) d))"},
+		{"Exponent is right associative", "print 2 ** 3 ** 2;", "(print (** 2 (** 3 2)))"},
+		{"Exponent binds tighter than unary on its left", "print -a ** -b;", "(print (- (** a (- b))))"},
+		{"Exponent binds tighter than factor", "print a * b ** c.d;", "(print (* a (** b (. c d))))"},
+		{"Bitwise precedence", "print a | b ^ c & d << e + f;", "(print (| a (^ b (& c (<< d (+ e f))))))"},
+		{"Bitwise binds tighter than comparison", "print x & 1 == y >> 2 < z;", "(print (== (& x 1) (< (>> y 2) z)))"},
+		{"Bitwise not", "print ~~a ^ ~1;", "(print (^ (~ (~ a)) (~ 1)))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := scanner.New(tt.input)
+			result, parseErr := New(scanner.ScanTokens()).Parse()
+			if parseErr != nil {
+				t.Fatalf("Error during parsing: %s", parseErr)
+			}
+			expected, readErr := util.ReadProgram(tt.expected)
+			if readErr != nil {
+				t.Fatalf("Malformed expectation %s: %s", tt.expected, readErr)
+			}
+			printer := util.AstPrinter{}
+			if actual, want := printer.PrintProgram(result), printer.PrintProgram(expected); actual != want {
+				t.Errorf("Expected\n%sgot\n%s", want, actual)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Assignment to a grouping", "(a) = 1;"},
+		{"Assignment to a call", "f() = 1;"},
+		{"Assignment to a slice", "xs[0:1] = 1;"},
+		{"Unclosed list", "print [1, 2;"},
+		{"Unclosed index", "print xs[1;"},
+		{"Empty index", "print xs[];"},
+		{"Trailing comma in list", "print [1,];"},
+		{"Map entry without a colon", `print {"a" 1};`},
+		{"Unclosed map", `print {"a": 1;`},
+		{"Unclosed block", "{ print 1;"},
+		{"For-in without var", "for (x in xs) print x;"},
+		{"For-in without in", "for (var x xs) print x;"},
+		{"For-in with a declaration as its body", "for (var x in xs) var y = x;"},
+		{"For-in with in as a variable", "for (var in in xs) print 1;"},
+		{"Try without catch or finally", "try {} print 1;"},
+		{"Try without a block", "try print 1; catch (e) {}"},
+		{"Catch without a variable", "try {} catch () {}"},
+		{"Catch without parentheses", "try {} catch e {}"},
+		{"Throw without a value", "throw;"},
+		{"Import without a path", "import util;"},
+		{"Import without a name", `import "util.lox";`},
+		{"Import with 'from' for 'as'", `import "util.lox" from util;`},
+		{"Import of no names", `import {} from "util.lox";`},
+		{"Import without 'from'", `import { a } "util.lox";`},
+		{"Import from a variable", `import { a } from path;`},
+		{"Break outside of a loop", "break;"},
+		{"Continue outside of a loop", "if (a) continue;"},
+		{"Break in a block after a loop", "while (a) print 1; { break; }"},
+		{"Break without a semicolon", "while (a) break"},
+		{"If without parentheses", "if a print 1;"},
+		{"For without a second semicolon", "for (var i = 0; i < 3) print i;"},
+		{"Conditional without a colon", "print a ? b;"},
+		{"Conditional without an else branch", "print a ? b : ;"},
+		{"Missing left operand of +", "print + 1;"},
+		{"Missing left operand of ==", "x = == 2;"},
+		{"Missing left operand of *", "f(* 2);"},
+		{"Missing left operand of a comma", "print , 1;"},
+		{"Missing left operand of <", "if (< 1) print 2;"},
+		{"Missing left operand of **", "print ** 2;"},
+		{"Missing left operand of |", "print | 2;"},
+		{"Exponent without a right operand", "print 2 ** ;"},
+		{"Return outside of a function", "return 1;"},
+		{"Return without a semicolon", "var f = fun () { return 1 };"},
+		{"Break in a lambda outside of a loop", "while (a) f(fun () { break; });"},
+		{"Duplicate parameter", "var f = (a, a) => a;"},
+		{"Lambda without a body", "var f = fun (a);"},
+		{"Lambda without parameters", "var f = fun { return 1; };"},
+		{"Arrow function without a body", "var f = () => ;"},
+		{"Lambda with a literal parameter", "var f = fun (1) {};"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := scanner.New(tt.input)
+			if _, parseErr := New(scanner.ScanTokens()).Parse(); parseErr != ErrParse {
+				t.Errorf("Expected ErrParse, got %v", parseErr)
+			}
+		})
+	}
+}
+
+func TestParseTooManyArguments(t *testing.T) {
+	args := strings.Repeat("1, ", maxArguments) + "1"
+	scanner := scanner.New("f(" + args + ");")
+	result, parseErr := New(scanner.ScanTokens()).Parse()
+	if parseErr != ErrParse {
+		t.Fatalf("Expected ErrParse, got %v", parseErr)
+	}
+	// The error doesn't stop the parser, which still builds the call.
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 statement, got %d", len(result))
+	}
+	call := result[0].(*ast.ExpressionStmt).Expression.(*ast.Call)
+	if len(call.Arguments) != maxArguments+1 {
+		t.Errorf("Expected %d arguments, got %d", maxArguments+1, len(call.Arguments))
+	}
+}
+
+func TestParseTooManyParameters(t *testing.T) {
+	params := make([]string, maxArguments+1)
+	for idx := range params {
+		params[idx] = fmt.Sprintf("p%d", idx)
+	}
+	scanner := scanner.New("var f = (" + strings.Join(params, ", ") + ") => 1;")
+	result, parseErr := New(scanner.ScanTokens()).Parse()
+	if parseErr != ErrParse {
+		t.Fatalf("Expected ErrParse, got %v", parseErr)
+	}
+	// As with arguments, the parser carries on with every parameter.
+	lambda := result[0].(*ast.VarStmt).Initializer.(*ast.Lambda)
+	if len(lambda.Params) != maxArguments+1 {
+		t.Errorf("Expected %d parameters, got %d", maxArguments+1, len(lambda.Params))
+	}
+}
+
+func TestParseMissingLeftOperand(t *testing.T) {
+	scanner := scanner.New("print == 1 < 2; print 3;")
+	result, parseErr := New(scanner.ScanTokens()).Parse()
+	if parseErr != ErrParse {
+		t.Fatalf("Expected ErrParse, got %v", parseErr)
+	}
+	// The right operand is parsed at the operator's precedence, and
+	// parsing carries on with the next statement.
+	printer := util.AstPrinter{}
+	if actual, expected := printer.PrintProgram(result), "(print (< 1 2))\n(print 3)\n"; actual != expected {
+		t.Errorf("Expected\n%sgot\n%s", expected, actual)
+	}
+}
+
 func compareAST(t *testing.T, expected, actual []ast.Stmt) {
 	if len(expected) != len(actual) {
 		t.Fatalf("Expected %d statements, got %d", len(expected), len(actual))
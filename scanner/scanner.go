@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"strconv"
+	"unicode/utf8"
 
 	"github.com/nicholasq/glox/error"
 	"github.com/nicholasq/glox/token"
@@ -13,6 +14,9 @@ type Scanner struct {
 	Start   uint
 	Current uint
 	Line    uint
+	// Reporter is where errors are reported, the error package's Writer if
+	// it is nil.
+	Reporter *error.Reporter
 }
 
 func New(source string) Scanner {
@@ -56,18 +60,45 @@ func (s *Scanner) scanToken() {
 		s.addToken(token.LEFT_BRACE)
 	case '}':
 		s.addToken(token.RIGHT_BRACE)
+	case '[':
+		s.addToken(token.LEFT_BRACKET)
+	case ']':
+		s.addToken(token.RIGHT_BRACKET)
 	case ',':
 		s.addToken(token.COMMA)
+	case ':':
+		s.addToken(token.COLON)
 	case '.':
 		s.addToken(token.DOT)
 	case '-':
 		s.addToken(token.MINUS)
 	case '+':
 		s.addToken(token.PLUS)
+	case '?':
+		s.addToken(token.QUESTION)
 	case ';':
 		s.addToken(token.SEMICOLON)
 	case '*':
-		s.addToken(token.STAR)
+		if s.nextRuneMatches('*') {
+			s.addToken(token.STAR_STAR)
+		} else {
+			s.addToken(token.STAR)
+		}
+	case '%':
+		s.addToken(token.PERCENT)
+	case '&':
+		s.addToken(token.AMPERSAND)
+	case '|':
+		s.addToken(token.PIPE)
+	case '^':
+		s.addToken(token.CARET)
+	case '~':
+		// Integer division can't be spelled // since that starts a comment.
+		if s.nextRuneMatches('/') {
+			s.addToken(token.TILDE_SLASH)
+		} else {
+			s.addToken(token.TILDE)
+		}
 	case '!':
 		if s.nextRuneMatches('=') {
 			s.addToken(token.BANG_EQUAL)
@@ -77,18 +108,24 @@ func (s *Scanner) scanToken() {
 	case '=':
 		if s.nextRuneMatches('=') {
 			s.addToken(token.EQUAL_EQUAL)
+		} else if s.nextRuneMatches('>') {
+			s.addToken(token.ARROW)
 		} else {
 			s.addToken(token.EQUAL)
 		}
 	case '<':
 		if s.nextRuneMatches('=') {
 			s.addToken(token.LESS_EQUAL)
+		} else if s.nextRuneMatches('<') {
+			s.addToken(token.LESS_LESS)
 		} else {
 			s.addToken(token.LESS)
 		}
 	case '>':
 		if s.nextRuneMatches('=') {
 			s.addToken(token.GREATER_EQUAL)
+		} else if s.nextRuneMatches('>') {
+			s.addToken(token.GREATER_GREATER)
 		} else {
 			s.addToken(token.GREATER)
 		}
@@ -115,7 +152,7 @@ func (s *Scanner) scanToken() {
 		} else if s.isAlpha(c) {
 			s.identifier()
 		} else {
-			error.Report(s.Line, "", "Unexpected character.")
+			s.Reporter.Report(s.Line, "", "Unexpected character.")
 		}
 	}
 }
@@ -161,7 +198,7 @@ func (s *Scanner) string() {
 	}
 	// Unterminated string.
 	if s.isAtEnd() {
-		error.Report(s.Line, "", "Unterminated string.")
+		s.Reporter.Report(s.Line, "", "Unterminated string.")
 		return
 	}
 	// Consumes the closing '"'.
@@ -175,10 +212,11 @@ func (s *Scanner) nextRuneMatches(char rune) bool {
 	if s.isAtEnd() {
 		return false
 	}
-	if []rune(s.Source)[s.Current] != char {
+	next, size := utf8.DecodeRuneInString(s.Source[s.Current:])
+	if next != char {
 		return false
 	}
-	s.Current++
+	s.Current += uint(size)
 	return true
 }
 
@@ -186,14 +224,20 @@ func (s *Scanner) peek() rune {
 	if s.isAtEnd() {
 		return '\000'
 	}
-	return []rune(s.Source)[s.Current]
+	curr, _ := utf8.DecodeRuneInString(s.Source[s.Current:])
+	return curr
 }
 
 func (s *Scanner) peekNext() rune {
 	if s.isAtEnd() {
 		return '\000'
 	}
-	return []rune(s.Source)[s.Current+1]
+	_, size := utf8.DecodeRuneInString(s.Source[s.Current:])
+	if s.Current+uint(size) >= uint(len(s.Source)) {
+		return '\000'
+	}
+	next, _ := utf8.DecodeRuneInString(s.Source[s.Current+uint(size):])
+	return next
 }
 
 func (s *Scanner) isAlpha(char rune) bool {
@@ -211,8 +255,8 @@ func (s *Scanner) isDigit(char rune) bool {
 func (s *Scanner) isAtEnd() bool { return s.Current >= uint(len(s.Source)) }
 
 func (s *Scanner) getRuneAndAdvance() rune {
-	curr := []rune(s.Source)[s.Current]
-	s.Current++
+	curr, size := utf8.DecodeRuneInString(s.Source[s.Current:])
+	s.Current += uint(size)
 	return curr
 }
 
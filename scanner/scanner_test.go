@@ -364,6 +364,27 @@ func TestScanTokens(t *testing.T) {
 	}
 }
 
+func TestScanTokensNonASCII(t *testing.T) {
+	scanner := New("print \"héllo\" + 1.5;")
+	tokens := scanner.ScanTokens()
+	expected := []token.Token{
+		{TokenType: token.PRINT, Lexeme: "print", Literal: "print", Line: 1},
+		{TokenType: token.STRING, Lexeme: "\"héllo\"", Literal: "héllo", Line: 1},
+		{TokenType: token.PLUS, Lexeme: "+", Literal: "+", Line: 1},
+		{TokenType: token.NUMBER, Lexeme: "1.5", Literal: 1.5, Line: 1},
+		{TokenType: token.SEMICOLON, Lexeme: ";", Literal: ";", Line: 1},
+		{TokenType: token.EOF, Lexeme: "", Literal: nil, Line: 1},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens. Got %d", len(expected), len(tokens))
+	}
+	for idx, token := range tokens {
+		if !deepEqual(expected[idx], token) {
+			t.Fatalf("\nExpected: %+v\n     Got: %+v", expected[idx], token)
+		}
+	}
+}
+
 func deepEqual(expected, actual token.Token) bool {
 	if expected.TokenType != actual.TokenType {
 		return false
@@ -383,3 +404,21 @@ func deepEqual(expected, actual token.Token) bool {
 
 	return true
 }
+
+func TestScanArithmeticOperators(t *testing.T) {
+	scanner := New("% ~/ ~ ** * & | ^ << <= >> >=")
+	expected := []token.TokenType{
+		token.PERCENT, token.TILDE_SLASH, token.TILDE, token.STAR_STAR, token.STAR,
+		token.AMPERSAND, token.PIPE, token.CARET, token.LESS_LESS, token.LESS_EQUAL,
+		token.GREATER_GREATER, token.GREATER_EQUAL, token.EOF,
+	}
+	tokens := scanner.ScanTokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens. Got %d", len(expected), len(tokens))
+	}
+	for idx, tok := range tokens {
+		if tok.TokenType != expected[idx] {
+			t.Fatalf("token %d: expected %s, got %s", idx, token.TokenNames[expected[idx]], token.TokenNames[tok.TokenType])
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type tokenJSON struct {
+	Type    string      `json:"type"`
+	Lexeme  string      `json:"lexeme"`
+	Literal interface{} `json:"literal,omitempty"`
+	Line    uint        `json:"line"`
+}
+
+// MarshalJSON encodes a token as an object with "type" (its name in
+// TokenNames), "lexeme", "line" and, if it has one, "literal" members.
+func (t Token) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tokenJSON{Type: TokenNames[t.TokenType], Lexeme: t.Lexeme, Literal: t.Literal, Line: t.Line})
+}
+
+// UnmarshalJSON decodes a token encoded by MarshalJSON.
+func (t *Token) UnmarshalJSON(data []byte) error {
+	var decoded tokenJSON
+	if decodeErr := json.Unmarshal(data, &decoded); decodeErr != nil {
+		return decodeErr
+	}
+	for tokenType, name := range TokenNames {
+		if name == decoded.Type {
+			*t = Token{TokenType: tokenType, Lexeme: decoded.Lexeme, Literal: decoded.Literal, Line: decoded.Line}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown token type %q", decoded.Type)
+}
@@ -10,15 +10,24 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
+	COLON
 	DOT
 	MINUS
 	PLUS
+	QUESTION
 	SEMICOLON
 	SLASH
 	STAR
+	AMPERSAND
+	CARET
+	PERCENT
+	PIPE
 
 	// One or two character Tokens.
+	ARROW
 	BANG
 	BANG_EQUAL
 	EQUAL
@@ -27,6 +36,11 @@ const (
 	GREATER_EQUAL
 	LESS
 	LESS_EQUAL
+	GREATER_GREATER
+	LESS_LESS
+	STAR_STAR
+	TILDE
+	TILDE_SLASH
 
 	// Literals.
 	IDENTIFIER
@@ -35,12 +49,18 @@ const (
 
 	// Keywords.
 	AND
+	BREAK
+	CATCH
 	CLASS
+	CONTINUE
 	ELSE
 	FALSE
+	FINALLY
 	FUN
 	FOR
 	IF
+	IMPORT
+	IN
 	NIL
 	OR
 
@@ -48,7 +68,9 @@ const (
 	RETURN
 	SUPER
 	THIS
+	THROW
 	TRUE
+	TRY
 	VAR
 	WHILE
 
@@ -56,45 +78,67 @@ const (
 )
 
 var TokenNames = map[TokenType]string{
-	LEFT_PAREN:    "LEFT_PAREN",
-	RIGHT_PAREN:   "RIGHT_PAREN",
-	LEFT_BRACE:    "LEFT_BRACE",
-	RIGHT_BRACE:   "RIGHT_BRACE",
-	COMMA:         "COMMA",
-	DOT:           "DOT",
-	MINUS:         "MINUS",
-	PLUS:          "PLUS",
-	SEMICOLON:     "SEMICOLON",
-	SLASH:         "SLASH",
-	STAR:          "STAR",
-	BANG:          "BANG",
-	BANG_EQUAL:    "BANG_EQUAL",
-	EQUAL:         "EQUAL",
-	EQUAL_EQUAL:   "EQUAL_EQUAL",
-	GREATER:       "GREATER",
-	GREATER_EQUAL: "GREATER_EQUAL",
-	LESS:          "LESS",
-	LESS_EQUAL:    "LESS_EQUAL",
-	IDENTIFIER:    "IDENTIFIER",
-	STRING:        "STRING",
-	NUMBER:        "NUMBER",
-	AND:           "AND",
-	CLASS:         "CLASS",
-	ELSE:          "ELSE",
-	FALSE:         "FALSE",
-	FUN:           "FUN",
-	FOR:           "FOR",
-	IF:            "IF",
-	NIL:           "NIL",
-	OR:            "OR",
-	PRINT:         "PRINT",
-	RETURN:        "RETURN",
-	SUPER:         "SUPER",
-	THIS:          "THIS",
-	TRUE:          "TRUE",
-	VAR:           "VAR",
-	WHILE:         "WHILE",
-	EOF:           "EOF",
+	LEFT_PAREN:      "LEFT_PAREN",
+	RIGHT_PAREN:     "RIGHT_PAREN",
+	LEFT_BRACE:      "LEFT_BRACE",
+	RIGHT_BRACE:     "RIGHT_BRACE",
+	LEFT_BRACKET:    "LEFT_BRACKET",
+	RIGHT_BRACKET:   "RIGHT_BRACKET",
+	COMMA:           "COMMA",
+	COLON:           "COLON",
+	DOT:             "DOT",
+	MINUS:           "MINUS",
+	PLUS:            "PLUS",
+	QUESTION:        "QUESTION",
+	SEMICOLON:       "SEMICOLON",
+	SLASH:           "SLASH",
+	STAR:            "STAR",
+	AMPERSAND:       "AMPERSAND",
+	CARET:           "CARET",
+	PERCENT:         "PERCENT",
+	PIPE:            "PIPE",
+	ARROW:           "ARROW",
+	BANG:            "BANG",
+	BANG_EQUAL:      "BANG_EQUAL",
+	EQUAL:           "EQUAL",
+	EQUAL_EQUAL:     "EQUAL_EQUAL",
+	GREATER:         "GREATER",
+	GREATER_EQUAL:   "GREATER_EQUAL",
+	LESS:            "LESS",
+	LESS_EQUAL:      "LESS_EQUAL",
+	GREATER_GREATER: "GREATER_GREATER",
+	LESS_LESS:       "LESS_LESS",
+	STAR_STAR:       "STAR_STAR",
+	TILDE:           "TILDE",
+	TILDE_SLASH:     "TILDE_SLASH",
+	IDENTIFIER:      "IDENTIFIER",
+	STRING:          "STRING",
+	NUMBER:          "NUMBER",
+	AND:             "AND",
+	BREAK:           "BREAK",
+	CATCH:           "CATCH",
+	CLASS:           "CLASS",
+	CONTINUE:        "CONTINUE",
+	ELSE:            "ELSE",
+	FALSE:           "FALSE",
+	FINALLY:         "FINALLY",
+	FUN:             "FUN",
+	FOR:             "FOR",
+	IF:              "IF",
+	IMPORT:          "IMPORT",
+	IN:              "IN",
+	NIL:             "NIL",
+	OR:              "OR",
+	PRINT:           "PRINT",
+	RETURN:          "RETURN",
+	SUPER:           "SUPER",
+	THIS:            "THIS",
+	THROW:           "THROW",
+	TRUE:            "TRUE",
+	TRY:             "TRY",
+	VAR:             "VAR",
+	WHILE:           "WHILE",
+	EOF:             "EOF",
 }
 
 type Token struct {
@@ -110,20 +154,28 @@ func (t Token) String() string {
 }
 
 var Keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":      AND,
+	"break":    BREAK,
+	"catch":    CATCH,
+	"class":    CLASS,
+	"continue": CONTINUE,
+	"else":     ELSE,
+	"false":    FALSE,
+	"finally":  FINALLY,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"import":   IMPORT,
+	"in":       IN,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"throw":    THROW,
+	"true":     TRUE,
+	"try":      TRY,
+	"var":      VAR,
+	"while":    WHILE,
 }
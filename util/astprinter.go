@@ -2,72 +2,268 @@ package util
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/nicholasq/glox/ast"
 	"github.com/nicholasq/glox/token"
 )
 
-type AstPrinter struct{}
+// AstPrinter formats syntax trees as S-expressions. Each node becomes a
+// parenthesized list headed by its operator or keyword, such as
+// (+ 1 (group (* 2 3))), (?: c a b), (, a b), (call f x), (. math PI),
+// (list 1 2), (map "a" 1 "b" 2), (= x 1), (var x = "a"),
+// (block (print x)), (if c (print 1) (print 2)), (for x in xs (print x)),
+// (throw e), (try (block) (catch e (block)) (finally (block))),
+// (fun (a b) (return a)) or (return). A while loop with an increment,
+// which a for loop becomes, ends with the increment, as in
+// (while (< i 3) (print i) (= i (+ i 1))). Strings are printed quoted, so
+// that they can be told apart from variables.
+type AstPrinter struct {
+	// Indent prints a list that contains other lists across several lines,
+	// one child per line, indented by two spaces per level.
+	Indent bool
+}
+
+var (
+	_ ast.ExprVisitor[string] = (*AstPrinter)(nil)
+	_ ast.StmtVisitor[string] = (*AstPrinter)(nil)
+)
+
+func (aP *AstPrinter) VisitAssignExpr(expr *ast.Assign) string {
+	return aP.parenthesize("=", &ast.Variable{Name: expr.Name}, expr.Value)
+}
 
-func (aP *AstPrinter) VisitBinaryExpr(expr *ast.Binary) interface{} {
+func (aP *AstPrinter) VisitBinaryExpr(expr *ast.Binary) string {
 	return aP.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
 }
 
-func (aP *AstPrinter) VisitGroupingExpr(expr *ast.Grouping) interface{} {
+func (aP *AstPrinter) VisitCallExpr(expr *ast.Call) string {
+	return aP.parenthesize("call", append([]ast.Expr{expr.Callee}, expr.Arguments...)...)
+}
+
+func (aP *AstPrinter) VisitConditionalExpr(expr *ast.Conditional) string {
+	return aP.parenthesize("?:", expr.Condition, expr.ThenBranch, expr.ElseBranch)
+}
+
+func (aP *AstPrinter) VisitGetExpr(expr *ast.Get) string {
+	return aP.parenthesize(".", expr.Object, &ast.Variable{Name: expr.Name})
+}
+
+func (aP *AstPrinter) VisitGroupingExpr(expr *ast.Grouping) string {
 	return aP.parenthesize("group", expr.Expression)
 }
 
-func (aP *AstPrinter) VisitLiteralExpr(expr *ast.Literal) interface{} {
-	if expr.Value == nil {
+func (aP *AstPrinter) VisitIndexExpr(expr *ast.Index) string {
+	return aP.parenthesize("index", expr.Object, expr.Index)
+}
+
+func (aP *AstPrinter) VisitIndexSetExpr(expr *ast.IndexSet) string {
+	return aP.parenthesize("setindex", expr.Object, expr.Index, expr.Value)
+}
+
+// VisitLambdaExpr prints a function's parameters followed by the
+// statements of its body, as in (fun (a b) (return (+ a b))). An arrow
+// function prints the same way as the fun form it stands for.
+func (aP *AstPrinter) VisitLambdaExpr(expr *ast.Lambda) string {
+	params := make([]string, 0, len(expr.Params))
+	for _, param := range expr.Params {
+		params = append(params, param.Lexeme)
+	}
+	children := []string{"(" + strings.Join(params, " ") + ")"}
+	for _, stmt := range expr.Body {
+		children = append(children, ast.AcceptStmt[string](stmt, aP))
+	}
+	return aP.list("fun", children)
+}
+
+func (aP *AstPrinter) VisitListLiteralExpr(expr *ast.ListLiteral) string {
+	return aP.parenthesize("list", expr.Elements...)
+}
+
+func (aP *AstPrinter) VisitLiteralExpr(expr *ast.Literal) string {
+	switch value := expr.Value.(type) {
+	case nil:
 		return "nil"
+	case string:
+		return strconv.Quote(value)
+	case float64:
+		// NaN is given a sign, like the infinities, so that it can't be
+		// read back as a variable.
+		if math.IsNaN(value) {
+			return "+NaN"
+		}
 	}
 	return fmt.Sprintf("%v", expr.Value)
 }
 
-func (aP *AstPrinter) VisitUnaryExpr(expr *ast.Unary) interface{} {
+// VisitSliceExpr prints a slice with a colon between its bounds, either of
+// which may be missing, as in (slice xs 1 :).
+// VisitMapLiteralExpr prints each entry's key followed by its value, as in
+// (map "a" 1 "b" 2).
+func (aP *AstPrinter) VisitMapLiteralExpr(expr *ast.MapLiteral) string {
+	entries := make([]ast.Expr, 0, 2*len(expr.Keys))
+	for idx, key := range expr.Keys {
+		entries = append(entries, key, expr.Values[idx])
+	}
+	return aP.parenthesize("map", entries...)
+}
+
+func (aP *AstPrinter) VisitSliceExpr(expr *ast.Slice) string {
+	operands := []ast.Expr{expr.Object}
+	if expr.Low != nil {
+		operands = append(operands, expr.Low)
+	}
+	operands = append(operands, &ast.Variable{Name: token.Token{TokenType: token.COLON, Lexeme: ":"}})
+	if expr.High != nil {
+		operands = append(operands, expr.High)
+	}
+	return aP.parenthesize("slice", operands...)
+}
+
+func (aP *AstPrinter) VisitUnaryExpr(expr *ast.Unary) string {
 	return aP.parenthesize(expr.Operator.Lexeme, expr.Right)
 }
 
-func (aP *AstPrinter) VisitVariableExpr(expr *ast.Variable) interface{} {
-	return expr
+func (aP *AstPrinter) VisitVariableExpr(expr *ast.Variable) string {
+	return expr.Name.Lexeme
 }
 
-func (aP *AstPrinter) parenthesize(name string, exprs ...ast.Expr) string {
-	result := fmt.Sprintf("(%s", name)
-	for _, expr := range exprs {
-		result += " "
-		result += expr.Accept(aP).(string)
+func (aP *AstPrinter) VisitBlockStmt(stmt *ast.BlockStmt) string {
+	children := make([]string, 0, len(stmt.Statements))
+	for _, inner := range stmt.Statements {
+		children = append(children, ast.AcceptStmt[string](inner, aP))
 	}
-	result += ")"
-	return result
+	return aP.list("block", children)
 }
 
-func (aP *AstPrinter) Print(expr ast.Expr) string {
-	return expr.Accept(aP).(string)
+func (aP *AstPrinter) VisitReturnStmt(stmt *ast.ReturnStmt) string {
+	if stmt.Value == nil {
+		return "(return)"
+	}
+	return aP.parenthesize("return", stmt.Value)
+}
+
+func (aP *AstPrinter) VisitThrowStmt(stmt *ast.ThrowStmt) string {
+	return aP.parenthesize("throw", stmt.Value)
+}
+
+func (aP *AstPrinter) VisitTryStmt(stmt *ast.TryStmt) string {
+	children := []string{ast.AcceptStmt[string](stmt.Body, aP)}
+	if stmt.Catch != nil {
+		children = append(children, aP.list("catch "+stmt.CatchName.Lexeme, []string{ast.AcceptStmt[string](stmt.Catch, aP)}))
+	}
+	if stmt.Finally != nil {
+		children = append(children, aP.list("finally", []string{ast.AcceptStmt[string](stmt.Finally, aP)}))
+	}
+	return aP.list("try", children)
+}
+
+func (aP *AstPrinter) VisitBreakStmt(stmt *ast.BreakStmt) string {
+	return "(break)"
+}
+
+func (aP *AstPrinter) VisitContinueStmt(stmt *ast.ContinueStmt) string {
+	return "(continue)"
+}
+
+func (aP *AstPrinter) VisitIfStmt(stmt *ast.IfStmt) string {
+	children := []string{ast.AcceptExpr[string](stmt.Condition, aP), ast.AcceptStmt[string](stmt.ThenBranch, aP)}
+	if stmt.ElseBranch != nil {
+		children = append(children, ast.AcceptStmt[string](stmt.ElseBranch, aP))
+	}
+	return aP.list("if", children)
+}
+
+func (aP *AstPrinter) VisitWhileStmt(stmt *ast.WhileStmt) string {
+	children := []string{ast.AcceptExpr[string](stmt.Condition, aP), ast.AcceptStmt[string](stmt.Body, aP)}
+	if stmt.Increment != nil {
+		children = append(children, ast.AcceptExpr[string](stmt.Increment, aP))
+	}
+	return aP.list("while", children)
+}
+
+func (aP *AstPrinter) VisitExpressionStmt(stmt *ast.ExpressionStmt) string {
+	return aP.parenthesize(";", stmt.Expression)
+}
+
+func (aP *AstPrinter) VisitForInStmt(stmt *ast.ForInStmt) string {
+	return aP.list("for "+stmt.Name.Lexeme+" in", []string{ast.AcceptExpr[string](stmt.Iterable, aP), ast.AcceptStmt[string](stmt.Body, aP)})
+}
+
+// VisitImportStmt prints an import of a whole module as
+// (import "util.lox" as u), and one of some of its members as
+// (import a b from "util.lox").
+func (aP *AstPrinter) VisitImportStmt(stmt *ast.ImportStmt) string {
+	path := strconv.Quote(stmt.Path.Literal.(string))
+	if stmt.Names == nil {
+		return aP.list("import", []string{path, "as", stmt.Alias.Lexeme})
+	}
+	children := make([]string, 0, len(stmt.Names)+2)
+	for _, name := range stmt.Names {
+		children = append(children, name.Lexeme)
+	}
+	return aP.list("import", append(children, "from", path))
 }
 
-func TestPrinter() {
-	unary := ast.Unary{
-		Operator: token.Token{Lexeme: "-", Literal: nil, TokenType: token.MINUS, Line: 1},
-		Right:    &ast.Literal{Value: 123},
+func (aP *AstPrinter) VisitPrintStmt(stmt *ast.PrintStmt) string {
+	return aP.parenthesize("print", stmt.Expression)
+}
+
+func (aP *AstPrinter) VisitVarStmt(stmt *ast.VarStmt) string {
+	if stmt.Initializer == nil {
+		return fmt.Sprintf("(var %s)", stmt.Name.Lexeme)
 	}
-	operator := token.Token{
-		Lexeme: "*", Literal: nil, TokenType: token.STAR, Line: 1,
+	return aP.parenthesize("var "+stmt.Name.Lexeme+" =", stmt.Initializer)
+}
+
+func (aP *AstPrinter) parenthesize(name string, exprs ...ast.Expr) string {
+	children := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		children = append(children, ast.AcceptExpr[string](expr, aP))
 	}
-	grouping := ast.Grouping{
-		Expression: &ast.Binary{
-			Left:     &ast.Literal{Value: 45.67},
-			Operator: token.Token{Lexeme: "+", Literal: nil, TokenType: token.PLUS, Line: 1},
-			Right:    &ast.Literal{Value: 78.9},
-		},
+	return aP.list(name, children)
+}
+
+// list formats a list headed by name, splitting it across lines if Indent
+// is set and any of its children is itself a list.
+func (aP *AstPrinter) list(name string, children []string) string {
+	nested := false
+	for _, child := range children {
+		nested = nested || strings.HasPrefix(child, "(")
 	}
 
-	expr := &ast.Binary{
-		Left:     &unary,
-		Operator: operator,
-		Right:    &grouping,
+	if !aP.Indent || !nested {
+		return "(" + strings.Join(append([]string{name}, children...), " ") + ")"
+	}
+	var result strings.Builder
+	result.WriteString("(" + name)
+	for _, child := range children {
+		result.WriteString("\n  ")
+		result.WriteString(strings.ReplaceAll(child, "\n", "\n  "))
 	}
+	result.WriteString(")")
+	return result.String()
+}
 
-	printer := AstPrinter{}
-	fmt.Println(printer.Print(expr))
+// Print returns the S-expression for an expression.
+func (aP *AstPrinter) Print(expr ast.Expr) string {
+	return ast.AcceptExpr[string](expr, aP)
+}
+
+// PrintStmt returns the S-expression for a statement.
+func (aP *AstPrinter) PrintStmt(stmt ast.Stmt) string {
+	return ast.AcceptStmt[string](stmt, aP)
+}
+
+// PrintProgram returns the S-expressions for a program, one statement per
+// line.
+func (aP *AstPrinter) PrintProgram(stmts []ast.Stmt) string {
+	var result strings.Builder
+	for _, stmt := range stmts {
+		result.WriteString(aP.PrintStmt(stmt))
+		result.WriteString("\n")
+	}
+	return result.String()
 }
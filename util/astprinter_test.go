@@ -0,0 +1,71 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+	"github.com/nicholasq/glox/token"
+)
+
+func TestPrint(t *testing.T) {
+	expr := &ast.Binary{
+		Left: &ast.Unary{
+			Operator: token.Token{TokenType: token.MINUS, Lexeme: "-", Line: 1},
+			Right:    &ast.Literal{Value: float64(123)},
+		},
+		Operator: token.Token{TokenType: token.STAR, Lexeme: "*", Line: 1},
+		Right:    &ast.Grouping{Expression: &ast.Literal{Value: 45.67}},
+	}
+	printer := AstPrinter{}
+	if actual := printer.Print(expr); actual != "(* (- 123) (group 45.67))" {
+		t.Errorf("Expected (* (- 123) (group 45.67)), got %s", actual)
+	}
+}
+
+func TestPrintProgram(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		indented string
+	}{
+		{
+			name:     "Literals",
+			input:    "print nil; print true; print 1.5; print \"a\nb\";",
+			expected: "(print nil)\n(print true)\n(print 1.5)\n(print \"a\\nb\")\n",
+			indented: "(print nil)\n(print true)\n(print 1.5)\n(print \"a\\nb\")\n",
+		},
+		{
+			name:     "Variables",
+			input:    "var a; var b = a; b;",
+			expected: "(var a)\n(var b = a)\n(; b)\n",
+			indented: "(var a)\n(var b = a)\n(; b)\n",
+		},
+		{
+			name:     "Nested expressions",
+			input:    "print -(1 + x) * !y;",
+			expected: "(print (* (- (group (+ 1 x))) (! y)))\n",
+			indented: "(print\n  (*\n    (-\n      (group\n        (+ 1 x)))\n    (! y)))\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := scanner.New(tt.input)
+			stmts, parseErr := parser.New(scanner.ScanTokens()).Parse()
+			if parseErr != nil {
+				t.Fatalf("Error during parsing: %s", parseErr)
+			}
+			printer := AstPrinter{}
+			if actual := printer.PrintProgram(stmts); actual != tt.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.expected, actual)
+			}
+			printer.Indent = true
+			if actual := printer.PrintProgram(stmts); actual != tt.indented {
+				t.Errorf("Expected indented\n%s\ngot\n%s", tt.indented, actual)
+			}
+		})
+	}
+}
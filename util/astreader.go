@@ -0,0 +1,506 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/scanner"
+	"github.com/nicholasq/glox/token"
+)
+
+// ReadExpr parses an expression written in the S-expression format of
+// AstPrinter, such as (+ 1 (group (* 2 x))). Whitespace between elements is
+// insignificant, so both the flat and the indented form are accepted.
+//
+// S-expressions carry no positions, so the nodes and tokens it returns have
+// a Line of 0.
+func ReadExpr(src string) (ast.Expr, error) {
+	elems, readErr := readSexprs(src)
+	if readErr != nil {
+		return nil, readErr
+	}
+	if len(elems) != 1 {
+		return nil, fmt.Errorf("expected one expression, found %d", len(elems))
+	}
+	return toExpr(elems[0])
+}
+
+// ReadProgram parses a sequence of statements written in the S-expression
+// format of AstPrinter, such as the output of PrintProgram.
+func ReadProgram(src string) ([]ast.Stmt, error) {
+	elems, readErr := readSexprs(src)
+	if readErr != nil {
+		return nil, readErr
+	}
+	stmts := make([]ast.Stmt, 0, len(elems))
+	for _, elem := range elems {
+		stmt, readErr := toStmt(elem)
+		if readErr != nil {
+			return nil, readErr
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// sexpr is an atom or, if list is set, a parenthesized list.
+type sexpr struct {
+	atom   string
+	quoted bool
+	list   bool
+	elems  []sexpr
+	offset int
+}
+
+func (s sexpr) String() string {
+	if s.list {
+		return fmt.Sprintf("list at offset %d", s.offset)
+	}
+	return fmt.Sprintf("%q at offset %d", s.atom, s.offset)
+}
+
+// readSexprs splits src into its top-level S-expressions.
+func readSexprs(src string) ([]sexpr, error) {
+	var stack [][]sexpr
+	var starts []int
+	var current []sexpr
+	for offset := 0; offset < len(src); {
+		c := src[offset]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			offset++
+		case c == '(':
+			stack = append(stack, current)
+			starts = append(starts, offset)
+			current = nil
+			offset++
+		case c == ')':
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected ')' at offset %d", offset)
+			}
+			list := sexpr{list: true, elems: current, offset: starts[len(starts)-1]}
+			current = append(stack[len(stack)-1], list)
+			stack, starts = stack[:len(stack)-1], starts[:len(starts)-1]
+			offset++
+		case c == '"':
+			quoted, unquoteErr := strconv.QuotedPrefix(src[offset:])
+			if unquoteErr != nil {
+				return nil, fmt.Errorf("unterminated string at offset %d", offset)
+			}
+			value, _ := strconv.Unquote(quoted)
+			current = append(current, sexpr{atom: value, quoted: true, offset: offset})
+			offset += len(quoted)
+		default:
+			end := offset
+			for end < len(src) && !strings.ContainsRune(" \t\n\r()\"", rune(src[end])) {
+				end++
+			}
+			current = append(current, sexpr{atom: src[offset:end], offset: offset})
+			offset = end
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unclosed '(' at offset %d", starts[len(starts)-1])
+	}
+	return current, nil
+}
+
+func toStmt(s sexpr) (ast.Stmt, error) {
+	if !s.list || len(s.elems) == 0 || s.elems[0].list || s.elems[0].quoted {
+		return nil, fmt.Errorf("expected a statement, found %s", s)
+	}
+	head := s.elems[0].atom
+	switch {
+	case head == ";" && len(s.elems) == 2:
+		expr, readErr := toExpr(s.elems[1])
+		return &ast.ExpressionStmt{Expression: expr}, readErr
+	case head == "print" && len(s.elems) == 2:
+		expr, readErr := toExpr(s.elems[1])
+		return &ast.PrintStmt{Expression: expr}, readErr
+	case head == "block":
+		var stmts []ast.Stmt
+		for _, elem := range s.elems[1:] {
+			stmt, readErr := toStmt(elem)
+			if readErr != nil {
+				return nil, readErr
+			}
+			stmts = append(stmts, stmt)
+		}
+		return &ast.BlockStmt{Statements: stmts}, nil
+	case (head == "break" || head == "continue") && len(s.elems) == 1:
+		keyword, readErr := toToken(s.elems[0])
+		if readErr != nil {
+			return nil, readErr
+		}
+		if keyword.TokenType == token.BREAK {
+			return &ast.BreakStmt{Keyword: keyword}, nil
+		}
+		return &ast.ContinueStmt{Keyword: keyword}, nil
+	case (head == "if" || head == "while") && (len(s.elems) == 3 || len(s.elems) == 4):
+		condition, readErr := toExpr(s.elems[1])
+		if readErr != nil {
+			return nil, readErr
+		}
+		body, readErr := toStmt(s.elems[2])
+		if readErr != nil {
+			return nil, readErr
+		}
+		if head == "while" {
+			loop := &ast.WhileStmt{Condition: condition, Body: body}
+			if len(s.elems) == 4 {
+				loop.Increment, readErr = toExpr(s.elems[3])
+			}
+			return loop, readErr
+		}
+		branch := &ast.IfStmt{Condition: condition, ThenBranch: body}
+		if len(s.elems) == 4 {
+			branch.ElseBranch, readErr = toStmt(s.elems[3])
+		}
+		return branch, readErr
+	case head == "return" && len(s.elems) <= 2:
+		keyword, readErr := toToken(s.elems[0])
+		if readErr != nil {
+			return nil, readErr
+		}
+		stmt := &ast.ReturnStmt{Keyword: keyword}
+		if len(s.elems) == 2 {
+			stmt.Value, readErr = toExpr(s.elems[1])
+		}
+		return stmt, readErr
+	case head == "throw" && len(s.elems) == 2:
+		keyword, readErr := toToken(s.elems[0])
+		if readErr != nil {
+			return nil, readErr
+		}
+		value, readErr := toExpr(s.elems[1])
+		return &ast.ThrowStmt{Keyword: keyword, Value: value}, readErr
+	case head == "try" && len(s.elems) >= 3 && len(s.elems) <= 4:
+		return toTry(s)
+	case head == "import" && len(s.elems) >= 4:
+		return toImport(s)
+	case head == "for" && len(s.elems) == 5:
+		name, readErr := toToken(s.elems[1])
+		if readErr != nil || name.TokenType != token.IDENTIFIER {
+			return nil, fmt.Errorf("expected a loop variable, found %s", s.elems[1])
+		}
+		in, readErr := toToken(s.elems[2])
+		if readErr != nil || in.TokenType != token.IN {
+			return nil, fmt.Errorf("expected 'in', found %s", s.elems[2])
+		}
+		iterable, readErr := toExpr(s.elems[3])
+		if readErr != nil {
+			return nil, readErr
+		}
+		body, readErr := toStmt(s.elems[4])
+		if readErr != nil {
+			return nil, readErr
+		}
+		return &ast.ForInStmt{Name: name, In: in, Iterable: iterable, Body: body}, nil
+	case head == "var" && (len(s.elems) == 2 || len(s.elems) == 4):
+		name, readErr := toToken(s.elems[1])
+		if readErr != nil || name.TokenType != token.IDENTIFIER {
+			return nil, fmt.Errorf("expected a variable name, found %s", s.elems[1])
+		}
+		if len(s.elems) == 2 {
+			return &ast.VarStmt{Name: name}, nil
+		}
+		if s.elems[2].list || s.elems[2].atom != "=" {
+			return nil, fmt.Errorf("expected '=', found %s", s.elems[2])
+		}
+		initializer, readErr := toExpr(s.elems[3])
+		return &ast.VarStmt{Name: name, Initializer: initializer}, readErr
+	}
+	return nil, fmt.Errorf("malformed statement %s", s)
+}
+
+// toTry converts (try block (catch name block)? (finally block)?).
+func toTry(s sexpr) (ast.Stmt, error) {
+	body, readErr := toStmt(s.elems[1])
+	if readErr != nil {
+		return nil, readErr
+	}
+	stmt := &ast.TryStmt{Body: body}
+	for _, clause := range s.elems[2:] {
+		if !clause.list || len(clause.elems) == 0 || clause.elems[0].list {
+			return nil, fmt.Errorf("expected a catch or finally clause, found %s", clause)
+		}
+		switch {
+		case clause.elems[0].atom == "catch" && len(clause.elems) == 3 && stmt.Catch == nil && stmt.Finally == nil:
+			name, readErr := toToken(clause.elems[1])
+			if readErr != nil || name.TokenType != token.IDENTIFIER {
+				return nil, fmt.Errorf("expected an exception variable, found %s", clause.elems[1])
+			}
+			stmt.CatchName = name
+			if stmt.Catch, readErr = toStmt(clause.elems[2]); readErr != nil {
+				return nil, readErr
+			}
+		case clause.elems[0].atom == "finally" && len(clause.elems) == 2 && stmt.Finally == nil:
+			if stmt.Finally, readErr = toStmt(clause.elems[1]); readErr != nil {
+				return nil, readErr
+			}
+		default:
+			return nil, fmt.Errorf("malformed try clause %s", clause)
+		}
+	}
+	return stmt, nil
+}
+
+// toImport converts (import "path" as name) and (import name... from "path").
+func toImport(s sexpr) (ast.Stmt, error) {
+	keyword, readErr := toToken(s.elems[0])
+	if readErr != nil {
+		return nil, readErr
+	}
+	stmt := &ast.ImportStmt{Keyword: keyword}
+	var path sexpr
+	var names []sexpr
+	switch {
+	case s.elems[1].quoted && len(s.elems) == 4 && s.elems[2].atom == "as" && !s.elems[2].quoted:
+		path, names = s.elems[1], s.elems[3:]
+	case s.elems[len(s.elems)-2].atom == "from" && !s.elems[len(s.elems)-2].quoted:
+		path, names = s.elems[len(s.elems)-1], s.elems[1:len(s.elems)-2]
+	default:
+		return nil, fmt.Errorf("malformed import %s", s)
+	}
+	if !path.quoted {
+		return nil, fmt.Errorf("expected a module path, found %s", path)
+	}
+	stmt.Path = token.Token{TokenType: token.STRING, Lexeme: `"` + path.atom + `"`, Literal: path.atom}
+	for _, elem := range names {
+		name, readErr := toToken(elem)
+		if readErr != nil || name.TokenType != token.IDENTIFIER {
+			return nil, fmt.Errorf("expected a name to import, found %s", elem)
+		}
+		stmt.Names = append(stmt.Names, name)
+	}
+	if s.elems[1].quoted {
+		stmt.Alias, stmt.Names = stmt.Names[0], nil
+	}
+	return stmt, nil
+}
+
+func toExpr(s sexpr) (ast.Expr, error) {
+	if !s.list {
+		return toAtom(s)
+	}
+	if len(s.elems) == 0 || s.elems[0].list || s.elems[0].quoted {
+		return nil, fmt.Errorf("expected an operator at the start of %s", s)
+	}
+
+	if s.elems[0].atom == "slice" {
+		return toSlice(s)
+	}
+	if s.elems[0].atom == "fun" {
+		return toLambda(s)
+	}
+
+	operands := make([]ast.Expr, 0, len(s.elems)-1)
+	for _, elem := range s.elems[1:] {
+		operand, readErr := toExpr(elem)
+		if readErr != nil {
+			return nil, readErr
+		}
+		operands = append(operands, operand)
+	}
+
+	head := s.elems[0].atom
+	if head == "?:" && len(operands) == 3 {
+		return &ast.Conditional{Condition: operands[0], ThenBranch: operands[1], ElseBranch: operands[2]}, nil
+	}
+	if head == "group" && len(operands) == 1 {
+		return &ast.Grouping{Expression: operands[0]}, nil
+	}
+	if head == "call" && len(operands) >= 1 {
+		paren := token.Token{TokenType: token.RIGHT_PAREN, Lexeme: ")", Literal: ")"}
+		var args []ast.Expr
+		if len(operands) > 1 {
+			args = operands[1:]
+		}
+		return &ast.Call{Callee: operands[0], Paren: paren, Arguments: args}, nil
+	}
+	if head == "list" {
+		var elements []ast.Expr
+		if len(operands) > 0 {
+			elements = operands
+		}
+		return &ast.ListLiteral{Elements: elements}, nil
+	}
+	if head == "map" && len(operands)%2 == 0 {
+		var keys, values []ast.Expr
+		for idx := 0; idx < len(operands); idx += 2 {
+			keys = append(keys, operands[idx])
+			values = append(values, operands[idx+1])
+		}
+		brace := token.Token{TokenType: token.RIGHT_BRACE, Lexeme: "}", Literal: "}"}
+		return &ast.MapLiteral{Keys: keys, Values: values, Brace: brace}, nil
+	}
+	if head == "=" && len(operands) == 2 {
+		if name, ok := operands[0].(*ast.Variable); ok {
+			return &ast.Assign{Name: name.Name, Value: operands[1]}, nil
+		}
+	}
+	if head == "setindex" && len(operands) == 3 {
+		bracket := token.Token{TokenType: token.RIGHT_BRACKET, Lexeme: "]", Literal: "]"}
+		return &ast.IndexSet{Object: operands[0], Bracket: bracket, Index: operands[1], Value: operands[2]}, nil
+	}
+	if head == "index" && len(operands) == 2 {
+		bracket := token.Token{TokenType: token.RIGHT_BRACKET, Lexeme: "]", Literal: "]"}
+		return &ast.Index{Object: operands[0], Bracket: bracket, Index: operands[1]}, nil
+	}
+	if head == "." && len(operands) == 2 {
+		if name, ok := operands[1].(*ast.Variable); ok {
+			return &ast.Get{Object: operands[0], Name: name.Name}, nil
+		}
+	}
+	operator, readErr := toToken(s.elems[0])
+	if readErr != nil {
+		return nil, readErr
+	}
+	switch {
+	case len(operands) == 1 && (operator.TokenType == token.MINUS || operator.TokenType == token.BANG || operator.TokenType == token.TILDE):
+		return &ast.Unary{Operator: operator, Right: operands[0]}, nil
+	case len(operands) == 2 && isBinaryOperator(operator.TokenType):
+		return &ast.Binary{Left: operands[0], Operator: operator, Right: operands[1]}, nil
+	}
+	return nil, fmt.Errorf("malformed expression %s", s)
+}
+
+// toLambda converts (fun (param...) stmt...).
+func toLambda(s sexpr) (ast.Expr, error) {
+	if len(s.elems) < 2 || !s.elems[1].list {
+		return nil, fmt.Errorf("malformed function %s", s)
+	}
+	keyword, readErr := toToken(s.elems[0])
+	if readErr != nil {
+		return nil, readErr
+	}
+	lambda := &ast.Lambda{Keyword: keyword}
+	for _, elem := range s.elems[1].elems {
+		param, readErr := toToken(elem)
+		if readErr != nil || param.TokenType != token.IDENTIFIER {
+			return nil, fmt.Errorf("expected a parameter name, found %s", elem)
+		}
+		lambda.Params = append(lambda.Params, param)
+	}
+	for _, elem := range s.elems[2:] {
+		stmt, readErr := toStmt(elem)
+		if readErr != nil {
+			return nil, readErr
+		}
+		lambda.Body = append(lambda.Body, stmt)
+	}
+	return lambda, nil
+}
+
+// toSlice converts (slice object low? : high?).
+func toSlice(s sexpr) (ast.Expr, error) {
+	colon := -1
+	for idx, elem := range s.elems {
+		if !elem.list && !elem.quoted && elem.atom == ":" {
+			colon = idx
+		}
+	}
+	if len(s.elems) < 3 || colon < 2 || colon > 3 || len(s.elems)-colon > 2 {
+		return nil, fmt.Errorf("malformed slice %s", s)
+	}
+	bounds := make([]ast.Expr, 0, 3)
+	for _, elem := range s.elems[1:colon] {
+		bound, readErr := toExpr(elem)
+		if readErr != nil {
+			return nil, readErr
+		}
+		bounds = append(bounds, bound)
+	}
+	slice := &ast.Slice{Object: bounds[0], Bracket: token.Token{TokenType: token.RIGHT_BRACKET, Lexeme: "]", Literal: "]"}}
+	if len(bounds) == 2 {
+		slice.Low = bounds[1]
+	}
+	if colon+1 < len(s.elems) {
+		high, readErr := toExpr(s.elems[colon+1])
+		if readErr != nil {
+			return nil, readErr
+		}
+		slice.High = high
+	}
+	return slice, nil
+}
+
+func toAtom(s sexpr) (ast.Expr, error) {
+	if s.quoted {
+		return &ast.Literal{Value: s.atom}, nil
+	}
+	switch s.atom {
+	case "nil":
+		return &ast.Literal{Value: nil}, nil
+	case "true":
+		return &ast.Literal{Value: true}, nil
+	case "false":
+		return &ast.Literal{Value: false}, nil
+	}
+	if s.atom == "+NaN" {
+		return &ast.Literal{Value: math.NaN()}, nil
+	}
+	if first := s.atom[0]; first >= '0' && first <= '9' || first == '-' || first == '+' {
+		if num, parseErr := strconv.ParseFloat(s.atom, 64); parseErr == nil {
+			return &ast.Literal{Value: num}, nil
+		}
+	}
+	name, readErr := toToken(s)
+	if readErr != nil || name.TokenType != token.IDENTIFIER {
+		return nil, fmt.Errorf("unexpected %s", s)
+	}
+	return &ast.Variable{Name: name}, nil
+}
+
+// toToken scans an atom holding an operator, keyword or identifier into the
+// token the Lox scanner would produce for it.
+func toToken(s sexpr) (token.Token, error) {
+	if s.list || s.quoted || !isLexeme(s.atom) {
+		return token.Token{}, fmt.Errorf("unexpected %s", s)
+	}
+	scanner := scanner.New(s.atom)
+	tokens := scanner.ScanTokens()
+	if len(tokens) != 2 {
+		return token.Token{}, fmt.Errorf("unexpected %s", s)
+	}
+	tok := tokens[0]
+	tok.Line = 0
+	return tok, nil
+}
+
+var operatorLexemes = map[string]bool{
+	"-": true, "+": true, "/": true, "*": true, "!": true, "!=": true,
+	"==": true, ">": true, ">=": true, "<": true, "<=": true, ",": true,
+	"%": true, "~/": true, "**": true, "&": true, "|": true, "^": true,
+	"~": true, "<<": true, ">>": true,
+}
+
+// isLexeme reports whether the scanner reads lexeme as a single operator,
+// keyword or identifier token without reporting an error.
+func isLexeme(lexeme string) bool {
+	if operatorLexemes[lexeme] {
+		return true
+	}
+	for idx, r := range lexeme {
+		alpha := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_'
+		if !alpha && (idx == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return lexeme != ""
+}
+
+func isBinaryOperator(tokenType token.TokenType) bool {
+	switch tokenType {
+	case token.MINUS, token.PLUS, token.SLASH, token.STAR,
+		token.BANG_EQUAL, token.EQUAL_EQUAL,
+		token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL,
+		token.COMMA, token.PERCENT, token.TILDE_SLASH, token.STAR_STAR,
+		token.AMPERSAND, token.PIPE, token.CARET, token.LESS_LESS, token.GREATER_GREATER:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package util
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/optimize"
+	"github.com/nicholasq/glox/parser"
+	"github.com/nicholasq/glox/scanner"
+	"github.com/nicholasq/glox/token"
+)
+
+func TestReadExpr(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Atoms", `(== (!= nil true) (== false "x"))`, `(== (!= nil true) (== false "x"))`},
+		{"Numbers", "(+ (- 1.5 -2) 1e+21)", "(+ (- 1.5 -2) 1e+21)"},
+		{"Non-finite numbers", "(+ +NaN (- +Inf -Inf))", "(+ +NaN (- +Inf -Inf))"},
+		{"Unary and grouping", "(- (group (! x)))", "(- (group (! x)))"},
+		{"Whitespace", "(*\n  (+ 1 x)\n\t2 )", "(* (+ 1 x) 2)"},
+		{"Escaped string", `(+ "a\n\"b\"" "")`, `(+ "a\n\"b\"" "")`},
+	}
+
+	printer := AstPrinter{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, readErr := ReadExpr(tt.input)
+			if readErr != nil {
+				t.Fatalf("Error reading %s: %s", tt.input, readErr)
+			}
+			if actual := printer.Print(expr); actual != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReadExprTokens(t *testing.T) {
+	expr, readErr := ReadExpr("(<= x 1)")
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	binary := expr.(*ast.Binary)
+	expected := token.Token{TokenType: token.LESS_EQUAL, Lexeme: "<=", Literal: "<="}
+	if binary.Operator != expected {
+		t.Errorf("Expected operator %v, got %v", expected, binary.Operator)
+	}
+	name := binary.Left.(*ast.Variable).Name
+	if name.TokenType != token.IDENTIFIER || name.Lexeme != "x" {
+		t.Errorf("Expected identifier x, got %v", name)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		program  bool
+		expected string
+	}{
+		{"Empty", "", false, "expected one expression, found 0"},
+		{"Two expressions", "1 2", false, "expected one expression, found 2"},
+		{"Unclosed list", "(+ 1 (- 2)", false, "unclosed '(' at offset 0"},
+		{"Extra paren", "(- 2))", false, "unexpected ')' at offset 5"},
+		{"Unterminated string", `(- "a)`, false, "unterminated string at offset 3"},
+		{"Empty list", "()", false, "expected an operator"},
+		{"Unknown operator", "(@ 1 2)", false, `unexpected "@" at offset 1`},
+		{"Wrong arity", "(* 1)", false, "malformed expression"},
+		{"Keyword as variable", "(+ print 1)", false, `unexpected "print" at offset 3`},
+		{"Statement as expression", "(print 1)", false, "malformed expression"},
+		{"Expression as statement", "(+ 1 2)", true, "malformed statement"},
+		{"Atom as statement", "x", true, "expected a statement"},
+		{"Bad variable name", "(var 1 = 2)", true, "expected a variable name"},
+		{"Missing equals", "(var x 2 3)", true, "expected '='"},
+		{"Import without a path", "(import a from b)", true, "expected a module path"},
+		{"Import without as", `(import "a.lox" to a)`, true, "malformed import"},
+		{"Function without parameters", "(fun return)", false, "malformed function"},
+		{"Function with a literal parameter", "(fun (a 1))", false, "expected a parameter name"},
+		{"Return with two values", "(return 1 2)", true, "malformed statement"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var readErr error
+			if tt.program {
+				_, readErr = ReadProgram(tt.input)
+			} else {
+				_, readErr = ReadExpr(tt.input)
+			}
+			if readErr == nil || !strings.Contains(readErr.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, readErr)
+			}
+		})
+	}
+}
+
+// checkRoundTrip checks that reading the printed form of stmts, in both the
+// flat and the indented layout, gives a program that prints the same way.
+func checkRoundTrip(t *testing.T, stmts []ast.Stmt) {
+	t.Helper()
+	flat := AstPrinter{}
+	expected := flat.PrintProgram(stmts)
+	for _, printer := range []AstPrinter{{}, {Indent: true}} {
+		printed := printer.PrintProgram(stmts)
+		read, readErr := ReadProgram(printed)
+		if readErr != nil {
+			t.Fatalf("Error reading\n%s\n%s", printed, readErr)
+		}
+		if actual := flat.PrintProgram(read); actual != expected {
+			t.Fatalf("Round trip changed\n%s\ninto\n%s", expected, actual)
+		}
+	}
+}
+
+func TestRoundTripScripts(t *testing.T) {
+	scripts, _ := filepath.Glob("../test/*.lox")
+	if len(scripts) == 0 {
+		t.Fatal("no test scripts found")
+	}
+	for _, script := range scripts {
+		t.Run(filepath.Base(script), func(t *testing.T) {
+			source, readErr := os.ReadFile(script)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			scanner := scanner.New(string(source))
+			stmts, parseErr := parser.New(scanner.ScanTokens()).Parse()
+			if parseErr != nil {
+				t.Fatalf("Error during parsing: %s", parseErr)
+			}
+			checkRoundTrip(t, stmts)
+		})
+	}
+}
+
+// TestRoundTripOptimized checks that the non-finite numbers constant folding
+// produces read back as numbers.
+func TestRoundTripOptimized(t *testing.T) {
+	scanner := scanner.New("print 0 / 0; print -1 / 0; var NaN = 1; print NaN;")
+	stmts, parseErr := parser.New(scanner.ScanTokens()).Parse()
+	if parseErr != nil {
+		t.Fatalf("Error during parsing: %s", parseErr)
+	}
+	stmts = optimize.Optimize(stmts)
+	checkRoundTrip(t, stmts)
+
+	printer := AstPrinter{}
+	read, readErr := ReadProgram(printer.PrintProgram(stmts))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if literal, ok := read[0].(*ast.PrintStmt).Expression.(*ast.Literal); !ok || !math.IsNaN(literal.Value.(float64)) {
+		t.Errorf("Expected a NaN literal, got %v", read[0].(*ast.PrintStmt).Expression)
+	}
+	if literal, ok := read[1].(*ast.PrintStmt).Expression.(*ast.Literal); !ok || !math.IsInf(literal.Value.(float64), -1) {
+		t.Errorf("Expected a -Inf literal, got %v", read[1].(*ast.PrintStmt).Expression)
+	}
+	if _, ok := read[3].(*ast.PrintStmt).Expression.(*ast.Variable); !ok {
+		t.Errorf("Expected the variable NaN, got %v", read[3].(*ast.PrintStmt).Expression)
+	}
+}
+
+func TestRoundTripRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for idx := 0; idx < 500; idx++ {
+		checkRoundTrip(t, []ast.Stmt{randomStmt(rng, 2)})
+	}
+}
+
+var (
+	unaryOperators  = []string{"-", "!", "~"}
+	binaryOperators = []string{"-", "+", "/", "*", "!=", "==", ">", ">=", "<", "<=", ",",
+		"%", "~/", "**", "&", "|", "^", "<<", ">>"}
+	randomNames  = []string{"a", "b_2", "_x", "Var", "nilly"}
+	randomValues = []interface{}{nil, true, false, 0.0, -1.0, 2.5, 1e21, 1e-7, math.Inf(1), math.NaN(),
+		"", "lox", "a \"quoted\"\nline", "tab\té"}
+)
+
+func randomStmt(rng *rand.Rand, depth int) ast.Stmt {
+	choice := rng.Intn(13)
+	if depth == 0 {
+		choice = rng.Intn(4)
+	}
+	switch choice {
+	case 0:
+		return &ast.ExpressionStmt{Expression: randomExpr(rng, 4)}
+	case 1:
+		return &ast.PrintStmt{Expression: randomExpr(rng, 4)}
+	case 2:
+		return &ast.VarStmt{Name: randomToken(randomNames[rng.Intn(len(randomNames))])}
+	case 4:
+		var stmts []ast.Stmt
+		for count := rng.Intn(3); count > 0; count-- {
+			stmts = append(stmts, randomStmt(rng, depth-1))
+		}
+		return &ast.BlockStmt{Statements: stmts}
+	case 5:
+		return &ast.ForInStmt{
+			Name:     randomToken(randomNames[rng.Intn(len(randomNames))]),
+			In:       randomToken("in"),
+			Iterable: randomExpr(rng, 3),
+			Body:     randomStmt(rng, depth-1),
+		}
+	case 6:
+		branch := &ast.IfStmt{Condition: randomExpr(rng, 3), ThenBranch: randomStmt(rng, depth-1)}
+		if rng.Intn(2) == 0 {
+			branch.ElseBranch = randomStmt(rng, depth-1)
+		}
+		return branch
+	case 7:
+		loop := &ast.WhileStmt{Condition: randomExpr(rng, 3), Body: randomStmt(rng, depth-1)}
+		if rng.Intn(2) == 0 {
+			loop.Increment = randomExpr(rng, 3)
+		}
+		return loop
+	case 8:
+		if rng.Intn(2) == 0 {
+			return &ast.BreakStmt{Keyword: randomToken("break")}
+		}
+		return &ast.ContinueStmt{Keyword: randomToken("continue")}
+	case 9:
+		return &ast.ThrowStmt{Keyword: randomToken("throw"), Value: randomExpr(rng, 3)}
+	case 10:
+		stmt := &ast.TryStmt{Body: randomBlock(rng, depth-1)}
+		if rng.Intn(2) == 0 {
+			stmt.CatchName = randomToken(randomNames[rng.Intn(len(randomNames))])
+			stmt.Catch = randomBlock(rng, depth-1)
+		}
+		if stmt.Catch == nil || rng.Intn(2) == 0 {
+			stmt.Finally = randomBlock(rng, depth-1)
+		}
+		return stmt
+	case 11:
+		stmt := &ast.ImportStmt{Keyword: randomToken("import"), Path: randomToken(`"lib/a b.lox"`)}
+		if rng.Intn(2) == 0 {
+			stmt.Alias = randomToken(randomNames[rng.Intn(len(randomNames))])
+			return stmt
+		}
+		for count := rng.Intn(3) + 1; count > 0; count-- {
+			stmt.Names = append(stmt.Names, randomToken(randomNames[rng.Intn(len(randomNames))]))
+		}
+		return stmt
+	case 12:
+		stmt := &ast.ReturnStmt{Keyword: randomToken("return")}
+		if rng.Intn(2) == 0 {
+			stmt.Value = randomExpr(rng, 3)
+		}
+		return stmt
+	}
+	return &ast.VarStmt{Name: randomToken(randomNames[rng.Intn(len(randomNames))]), Initializer: randomExpr(rng, 4)}
+}
+
+func randomBlock(rng *rand.Rand, depth int) *ast.BlockStmt {
+	block := &ast.BlockStmt{}
+	for count := rng.Intn(3); count > 0; count-- {
+		block.Statements = append(block.Statements, randomStmt(rng, depth))
+	}
+	return block
+}
+
+func randomExpr(rng *rand.Rand, depth int) ast.Expr {
+	choice := rng.Intn(15)
+	if depth == 0 {
+		choice = rng.Intn(2)
+	}
+	switch choice {
+	case 0:
+		return &ast.Literal{Value: randomValues[rng.Intn(len(randomValues))]}
+	case 1:
+		return &ast.Variable{Name: randomToken(randomNames[rng.Intn(len(randomNames))])}
+	case 2:
+		return &ast.Grouping{Expression: randomExpr(rng, depth-1)}
+	case 3:
+		return &ast.Unary{Operator: randomToken(unaryOperators[rng.Intn(len(unaryOperators))]), Right: randomExpr(rng, depth-1)}
+	case 4:
+		var arguments []ast.Expr
+		for count := rng.Intn(3); count > 0; count-- {
+			arguments = append(arguments, randomExpr(rng, depth-1))
+		}
+		return &ast.Call{Callee: randomExpr(rng, depth-1), Paren: randomToken(")"), Arguments: arguments}
+	case 5:
+		return &ast.Get{Object: randomExpr(rng, depth-1), Name: randomToken(randomNames[rng.Intn(len(randomNames))])}
+	case 6:
+		return &ast.Index{Object: randomExpr(rng, depth-1), Bracket: randomToken("]"), Index: randomExpr(rng, depth-1)}
+	case 7:
+		return &ast.IndexSet{Object: randomExpr(rng, depth-1), Bracket: randomToken("]"), Index: randomExpr(rng, depth-1), Value: randomExpr(rng, depth-1)}
+	case 8:
+		var elements []ast.Expr
+		for count := rng.Intn(3); count > 0; count-- {
+			elements = append(elements, randomExpr(rng, depth-1))
+		}
+		return &ast.ListLiteral{Elements: elements}
+	case 9:
+		slice := &ast.Slice{Object: randomExpr(rng, depth-1), Bracket: randomToken("]")}
+		if rng.Intn(2) == 0 {
+			slice.Low = randomExpr(rng, depth-1)
+		}
+		if rng.Intn(2) == 0 {
+			slice.High = randomExpr(rng, depth-1)
+		}
+		return slice
+	case 10:
+		var keys, values []ast.Expr
+		for count := rng.Intn(3); count > 0; count-- {
+			keys = append(keys, randomExpr(rng, depth-1))
+			values = append(values, randomExpr(rng, depth-1))
+		}
+		return &ast.MapLiteral{Keys: keys, Values: values, Brace: randomToken("}")}
+	case 11:
+		return &ast.Assign{Name: randomToken(randomNames[rng.Intn(len(randomNames))]), Value: randomExpr(rng, depth-1)}
+	case 12:
+		lambda := &ast.Lambda{Keyword: randomToken("fun")}
+		for count := rng.Intn(3); count > 0; count-- {
+			lambda.Params = append(lambda.Params, randomToken(randomNames[rng.Intn(len(randomNames))]))
+		}
+		for count := rng.Intn(3); count > 0; count-- {
+			lambda.Body = append(lambda.Body, randomStmt(rng, 0))
+		}
+		return lambda
+	case 13:
+		return &ast.Conditional{Condition: randomExpr(rng, depth-1), ThenBranch: randomExpr(rng, depth-1), ElseBranch: randomExpr(rng, depth-1)}
+	}
+	return &ast.Binary{
+		Left:     randomExpr(rng, depth-1),
+		Operator: randomToken(binaryOperators[rng.Intn(len(binaryOperators))]),
+		Right:    randomExpr(rng, depth-1),
+	}
+}
+
+func randomToken(lexeme string) token.Token {
+	scanner := scanner.New(lexeme)
+	return scanner.ScanTokens()[0]
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nicholasq/glox/token"
+)
+
+// PrintTokens writes one token per line in aligned columns: its line, its
+// type as named in token.TokenNames, its lexeme and its literal value.
+// Lexemes containing spaces or control characters, such as multi-line
+// strings, are quoted, as are string literals.
+func PrintTokens(w io.Writer, tokens []token.Token) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "LINE\tTYPE\tLEXEME\tLITERAL")
+	for _, tok := range tokens {
+		cells := []string{strconv.FormatUint(uint64(tok.Line), 10), token.TokenNames[tok.TokenType], formatLexeme(tok.Lexeme), formatLiteral(tok.Literal)}
+		// Leave out empty trailing cells, which tabwriter would pad with spaces.
+		for len(cells) > 0 && cells[len(cells)-1] == "" {
+			cells = cells[:len(cells)-1]
+		}
+		fmt.Fprintln(table, strings.Join(cells, "\t"))
+	}
+	return table.Flush()
+}
+
+// PrintTokensJSON writes the tokens as a JSON array, one token per line.
+func PrintTokensJSON(w io.Writer, tokens []token.Token) error {
+	out := bufio.NewWriter(w)
+	out.WriteString("[")
+	for idx, tok := range tokens {
+		data, marshalErr := json.Marshal(tok)
+		if marshalErr != nil {
+			return marshalErr
+		}
+		if idx > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n  ")
+		out.Write(data)
+	}
+	out.WriteString("\n]\n")
+	return out.Flush()
+}
+
+func formatLexeme(lexeme string) string {
+	for _, r := range lexeme {
+		if r == ' ' || !strconv.IsPrint(r) {
+			return strconv.Quote(lexeme)
+		}
+	}
+	return lexeme
+}
+
+func formatLiteral(literal interface{}) string {
+	switch value := literal.(type) {
+	case nil:
+		return ""
+	case string:
+		return strconv.Quote(value)
+	}
+	return fmt.Sprintf("%v", literal)
+}
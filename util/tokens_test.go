@@ -0,0 +1,64 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/nicholasq/glox/scanner"
+	"github.com/nicholasq/glox/token"
+)
+
+func TestPrintTokens(t *testing.T) {
+	scanner := scanner.New("var greeting = \"hi\nthere\";\nprint 1.5 >= \"x\";")
+	var out bytes.Buffer
+	if printErr := PrintTokens(&out, scanner.ScanTokens()); printErr != nil {
+		t.Fatal(printErr)
+	}
+	expected := `LINE  TYPE           LEXEME           LITERAL
+1     VAR            var              "var"
+1     IDENTIFIER     greeting         "greeting"
+1     EQUAL          =                "="
+2     STRING         "\"hi\nthere\""  "hi\nthere"
+2     SEMICOLON      ;                ";"
+3     PRINT          print            "print"
+3     NUMBER         1.5              1.5
+3     GREATER_EQUAL  >=               ">="
+3     STRING         "x"              "x"
+3     SEMICOLON      ;                ";"
+3     EOF
+`
+	if out.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestPrintTokensJSON(t *testing.T) {
+	scanner := scanner.New("print \"a\" + 2;")
+	tokens := scanner.ScanTokens()
+	var out bytes.Buffer
+	if printErr := PrintTokensJSON(&out, tokens); printErr != nil {
+		t.Fatal(printErr)
+	}
+	expected := `[
+  {"type":"PRINT","lexeme":"print","literal":"print","line":1},
+  {"type":"STRING","lexeme":"\"a\"","literal":"a","line":1},
+  {"type":"PLUS","lexeme":"+","literal":"+","line":1},
+  {"type":"NUMBER","lexeme":"2","literal":2,"line":1},
+  {"type":"SEMICOLON","lexeme":";","literal":";","line":1},
+  {"type":"EOF","lexeme":"","line":1}
+]
+`
+	if out.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	var decoded []token.Token
+	if decodeErr := json.Unmarshal(out.Bytes(), &decoded); decodeErr != nil {
+		t.Fatal(decodeErr)
+	}
+	if !reflect.DeepEqual(decoded, tokens) {
+		t.Errorf("Expected the tokens to round trip, got %v", decoded)
+	}
+}
@@ -0,0 +1,695 @@
+// Package vm executes bytecode produced by the compiler on a stack machine.
+package vm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/nicholasq/glox/ast"
+	"github.com/nicholasq/glox/chunk"
+	"github.com/nicholasq/glox/compiler"
+	err "github.com/nicholasq/glox/error"
+	"github.com/nicholasq/glox/module"
+	"github.com/nicholasq/glox/native"
+	"github.com/nicholasq/glox/token"
+)
+
+// framesMax bounds the call depth before the vm reports a stack overflow.
+const framesMax = 64
+
+// errStackOverflow is returned when a call would exceed framesMax.
+var errStackOverflow = errors.New("Stack overflow.")
+
+// frame is the activation record of a running function. slots is the index
+// of the function's first stack slot, and upvalues are the variables of
+// enclosing functions captured by the closure being run.
+type frame struct {
+	function *chunk.Function
+	upvalues []*upvalue
+	ip       int
+	slots    int
+}
+
+// closure is the runtime value of a lambda: its compiled function along
+// with the variables of enclosing functions that it captured. It can be
+// called by natives as well as by the vm it was created on.
+type closure struct {
+	function *chunk.Function
+	upvalues []*upvalue
+	vm       *VM
+}
+
+var _ native.Callable = (*closure)(nil)
+
+func (c *closure) Arity() int { return c.function.Arity }
+
+// Call runs the closure on its vm, above whatever is running there
+// already, until it returns. If a runtime error escapes the closure, the
+// call stack where it was raised is recorded and the vm is restored to
+// the state it was in before the call.
+func (c *closure) Call(ctx *native.Context, args []interface{}) (interface{}, error) {
+	vm := c.vm
+	if len(vm.frames) == framesMax {
+		return nil, errStackOverflow
+	}
+	base, stack, handlers := len(vm.frames), len(vm.stack), len(vm.handlers)
+	defer func() {
+		if r := recover(); r != nil {
+			if rtErr, ok := r.(*err.RuntimeError); ok && vm.unwinding != rtErr {
+				vm.unwinding, vm.unwound = rtErr, vm.stackTrace(rtErr.Token.Line)
+			}
+			vm.closeUpvalues(stack)
+			vm.frames = vm.frames[:base]
+			vm.stack = vm.stack[:stack]
+			vm.handlers = vm.handlers[:handlers]
+			panic(r)
+		}
+	}()
+	vm.push(c)
+	vm.stack = append(vm.stack, args...)
+	vm.frames = append(vm.frames, frame{function: c.function, upvalues: c.upvalues, slots: len(vm.stack) - len(args) - 1})
+	for !vm.runProtected(base) {
+	}
+	return vm.pop(), nil
+}
+
+func (c *closure) String() string { return c.function.String() }
+
+// upvalue is a local variable captured by a closure. While the variable is
+// in scope, the upvalue is open and refers to its stack slot; once the
+// variable goes out of scope, the upvalue is closed and holds its value.
+type upvalue struct {
+	slot   int
+	open   bool
+	closed chunk.Value
+}
+
+// handler is the handler of an active try statement: where execution goes
+// when a runtime error is raised inside it, and how many frames and stack
+// slots were in use when it began.
+type handler struct {
+	frames int
+	stack  int
+	ip     int
+}
+
+// caught is a runtime error that a handler pushed onto the stack, with the
+// call stack where it was raised, innermost first.
+type caught struct {
+	err   *err.RuntimeError
+	stack []string
+}
+
+func (c *caught) String() string {
+	return "<exception>"
+}
+
+type VM struct {
+	frames   []frame
+	handlers []handler
+	stack    []chunk.Value
+	// openUpvalues are the upvalues still referring to stack slots, in
+	// the order of their slots.
+	openUpvalues []*upvalue
+	// unwinding is the runtime error most recently raised through a
+	// closure called by a native or another vm, and unwound is the call
+	// stack where it was raised, recorded before the closure's frames
+	// were popped.
+	unwinding *err.RuntimeError
+	unwound   []string
+	globals   map[string]chunk.Value
+	out       io.Writer
+	trace     io.Writer
+	context   *native.Context
+	// file is the path of the script being run, which imports are
+	// resolved against, and loader loads the modules it imports.
+	file   string
+	loader *module.Loader
+}
+
+// New returns a vm whose globals hold the native functions.
+func New() *VM {
+	globals := make(map[string]chunk.Value, len(native.Globals))
+	for name, value := range native.Globals {
+		globals[name] = value
+	}
+	return &VM{
+		frames:  make([]frame, 0, framesMax),
+		stack:   make([]chunk.Value, 0, 256),
+		globals: globals,
+		out:     os.Stdout,
+		context: &native.Context{In: bufio.NewReader(os.Stdin)},
+		loader:  module.New(),
+	}
+}
+
+// SetOutput sets the writer that print statements write to.
+func (vm *VM) SetOutput(w io.Writer) {
+	vm.out = w
+}
+
+// SetInput sets the reader that input() and readLine() read from.
+func (vm *VM) SetInput(r io.Reader) {
+	vm.context.In = bufio.NewReader(r)
+}
+
+// SetFile sets the path of the script being run, which the paths of its
+// imports are relative to. Without one they are relative to the working
+// directory.
+func (vm *VM) SetFile(path string) {
+	vm.file = path
+}
+
+// SetLoader sets the loader that imports modules, replacing the one New
+// created.
+func (vm *VM) SetLoader(loader *module.Loader) {
+	vm.loader = loader
+}
+
+// SetTrace makes the vm write its stack and the instruction about to run
+// to w before executing each instruction. A nil writer turns tracing off.
+func (vm *VM) SetTrace(w io.Writer) {
+	vm.trace = w
+}
+
+// Interpret runs a compiled script. Globals persist between calls, so a
+// REPL can run successive lines on the same vm. It returns the first
+// runtime error as an *error.RuntimeError, or a call of exit() as a
+// *native.Exit.
+func (vm *VM) Interpret(script *chunk.Function) (result error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case *err.RuntimeError:
+				result = r
+			case *native.Exit:
+				result = r
+			default:
+				panic(r)
+			}
+			// Closures made by the script keep the values of the locals
+			// they captured.
+			vm.closeUpvalues(0)
+			vm.stack = vm.stack[:0]
+			vm.frames = vm.frames[:0]
+			vm.handlers = vm.handlers[:0]
+			vm.unwinding, vm.unwound = nil, nil
+		}
+	}()
+	vm.push(script)
+	vm.frames = append(vm.frames, frame{function: script, slots: 0})
+	for !vm.runProtected(0) {
+	}
+	vm.pop()
+	return nil
+}
+
+// runProtected runs the vm until the number of frames drops back to base,
+// and then reports true. If a runtime error is raised inside a try
+// statement begun above base, it unwinds to the try's handler and reports
+// false, so that the caller runs the vm again from there.
+func (vm *VM) runProtected(base int) (done bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			rtErr, ok := r.(*err.RuntimeError)
+			if !ok || len(vm.handlers) == 0 || vm.handlers[len(vm.handlers)-1].frames <= base {
+				panic(r)
+			}
+			h := vm.handlers[len(vm.handlers)-1]
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+			exception := &caught{err: rtErr, stack: vm.stackTrace(rtErr.Token.Line)}
+			if vm.unwinding == rtErr {
+				exception.stack = vm.unwound
+			}
+			vm.unwinding, vm.unwound = nil, nil
+			vm.closeUpvalues(h.stack)
+			vm.frames = vm.frames[:h.frames]
+			vm.stack = vm.stack[:h.stack]
+			vm.push(exception)
+			vm.frames[len(vm.frames)-1].ip = h.ip
+		}
+	}()
+	vm.run(base)
+	return true
+}
+
+// stackTrace describes the active frames, innermost first, taking line as
+// the line being run in the innermost one.
+func (vm *VM) stackTrace(line uint) []string {
+	stack := make([]string, 0, len(vm.frames))
+	for idx := len(vm.frames) - 1; idx >= 0; idx-- {
+		f := &vm.frames[idx]
+		name := f.function.Name
+		if name == "" {
+			name = "script"
+		}
+		frameLine := line
+		if idx < len(vm.frames)-1 {
+			frameLine = f.function.Chunk.Line(f.ip - 1)
+		}
+		stack = append(stack, native.StackEntry(name, frameLine))
+	}
+	return stack
+}
+
+// run executes instructions until the frame above base returns, leaving
+// its result on the stack.
+func (vm *VM) run(base int) {
+	f := &vm.frames[len(vm.frames)-1]
+	code := f.function.Chunk.Code
+
+	for {
+		if vm.trace != nil {
+			vm.traceInstruction(f)
+		}
+		op := chunk.OpCode(code[f.ip])
+		f.ip++
+
+		switch op {
+		case chunk.OpConstant:
+			vm.push(vm.readConstant(f))
+		case chunk.OpNil:
+			vm.push(nil)
+		case chunk.OpTrue:
+			vm.push(true)
+		case chunk.OpFalse:
+			vm.push(false)
+		case chunk.OpPop:
+			vm.pop()
+		case chunk.OpDefineGlobal:
+			name := vm.readConstant(f).(string)
+			vm.globals[name] = vm.pop()
+		case chunk.OpGetGlobal:
+			name := vm.readConstant(f).(string)
+			value, ok := vm.globals[name]
+			if !ok {
+				vm.runtimeError(fmt.Sprintf("undefined variable: %v", name))
+			}
+			vm.push(value)
+		case chunk.OpSetGlobal:
+			name := vm.readConstant(f).(string)
+			if _, ok := vm.globals[name]; !ok {
+				vm.runtimeError(fmt.Sprintf("undefined variable: %v", name))
+			}
+			vm.globals[name] = vm.peek(0)
+		case chunk.OpGetLocal:
+			slot := int(code[f.ip])
+			f.ip++
+			vm.push(vm.stack[f.slots+slot])
+		case chunk.OpSetLocal:
+			slot := int(code[f.ip])
+			f.ip++
+			vm.stack[f.slots+slot] = vm.peek(0)
+		case chunk.OpGetUpvalue:
+			up := f.upvalues[code[f.ip]]
+			f.ip++
+			if up.open {
+				vm.push(vm.stack[up.slot])
+			} else {
+				vm.push(up.closed)
+			}
+		case chunk.OpSetUpvalue:
+			up := f.upvalues[code[f.ip]]
+			f.ip++
+			if up.open {
+				vm.stack[up.slot] = vm.peek(0)
+			} else {
+				up.closed = vm.peek(0)
+			}
+		case chunk.OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case chunk.OpGetProperty:
+			name := vm.readConstant(f).(string)
+			value, getErr := native.Property(vm.peek(0), name)
+			if getErr != nil {
+				vm.runtimeError(getErr.Error())
+			}
+			vm.stack[len(vm.stack)-1] = value
+		case chunk.OpIndex:
+			value, indexErr := native.Index(vm.peek(1), vm.peek(0))
+			if indexErr != nil {
+				vm.runtimeError(indexErr.Error())
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2]
+			vm.push(value)
+		case chunk.OpSetIndex:
+			if setErr := native.SetIndex(vm.peek(2), vm.peek(1), vm.peek(0)); setErr != nil {
+				vm.runtimeError(setErr.Error())
+			}
+			value := vm.pop()
+			vm.stack = vm.stack[:len(vm.stack)-2]
+			vm.push(value)
+		case chunk.OpSlice:
+			value, sliceErr := native.Slice(vm.peek(2), vm.peek(1), vm.peek(0))
+			if sliceErr != nil {
+				vm.runtimeError(sliceErr.Error())
+			}
+			vm.stack = vm.stack[:len(vm.stack)-3]
+			vm.push(value)
+		case chunk.OpList:
+			count := int(f.function.Chunk.ReadShort(f.ip))
+			f.ip += 2
+			list := &native.List{Elements: make([]chunk.Value, count)}
+			copy(list.Elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(list)
+		case chunk.OpMap:
+			count := int(f.function.Chunk.ReadShort(f.ip))
+			f.ip += 2
+			m := native.NewMap()
+			entries := vm.stack[len(vm.stack)-2*count:]
+			for idx := 0; idx < len(entries); idx += 2 {
+				if storeErr := m.Store(entries[idx], entries[idx+1]); storeErr != nil {
+					vm.runtimeError(storeErr.Error())
+				}
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*count]
+			vm.push(m)
+		case chunk.OpEqual:
+			b, a := vm.pop(), vm.pop()
+			vm.push(isEqual(a, b))
+		case chunk.OpGreater:
+			a, b := vm.popNumbers()
+			vm.push(a > b)
+		case chunk.OpGreaterEqual:
+			a, b := vm.popNumbers()
+			vm.push(a >= b)
+		case chunk.OpLess:
+			a, b := vm.popNumbers()
+			vm.push(a < b)
+		case chunk.OpLessEqual:
+			a, b := vm.popNumbers()
+			vm.push(a <= b)
+		case chunk.OpAdd:
+			b, a := vm.pop(), vm.pop()
+			if x, ok := a.(float64); ok {
+				if y, ok := b.(float64); ok {
+					vm.push(x + y)
+					break
+				}
+			}
+			if x, ok := a.(string); ok {
+				if y, ok := b.(string); ok {
+					vm.push(x + y)
+					break
+				}
+			}
+			vm.runtimeError("Operands must be two numbers or two strings.")
+		case chunk.OpSubtract:
+			a, b := vm.popNumbers()
+			vm.push(a - b)
+		case chunk.OpMultiply:
+			a, b := vm.popNumbers()
+			vm.push(a * b)
+		case chunk.OpDivide:
+			a, b := vm.popNumbers()
+			vm.push(a / b)
+		case chunk.OpModulo:
+			a, b := vm.popNumbers()
+			vm.push(native.Modulo(a, b))
+		case chunk.OpFloorDivide:
+			a, b := vm.popNumbers()
+			vm.push(native.FloorDivide(a, b))
+		case chunk.OpPower:
+			a, b := vm.popNumbers()
+			vm.push(math.Pow(a, b))
+		case chunk.OpBitAnd, chunk.OpBitOr, chunk.OpBitXor, chunk.OpShiftLeft, chunk.OpShiftRight:
+			a, b := vm.popNumbers()
+			result, bitErr := native.Bitwise(bitwiseOperators[op], a, b)
+			if bitErr != nil {
+				vm.runtimeError(bitErr.Error())
+			}
+			vm.push(result)
+		case chunk.OpBitNot:
+			num, ok := vm.peek(0).(float64)
+			if !ok {
+				vm.runtimeError("Operand must be a number.")
+			}
+			result, bitErr := native.Complement(num)
+			if bitErr != nil {
+				vm.runtimeError(bitErr.Error())
+			}
+			vm.stack[len(vm.stack)-1] = result
+		case chunk.OpNot:
+			vm.push(!isTruthy(vm.pop()))
+		case chunk.OpNegate:
+			num, ok := vm.peek(0).(float64)
+			if !ok {
+				vm.runtimeError("Operand must be a number.")
+			}
+			vm.stack[len(vm.stack)-1] = -num
+		case chunk.OpIterate:
+			iterator, iterErr := native.Iterate(vm.context, vm.peek(0))
+			if iterErr != nil {
+				vm.iterationError(iterErr)
+			}
+			vm.stack[len(vm.stack)-1] = iterator
+		case chunk.OpForIter:
+			offset := int(f.function.Chunk.ReadShort(f.ip))
+			f.ip += 2
+			element, ok, nextErr := vm.peek(0).(*native.Iterator).Next()
+			if nextErr != nil {
+				vm.iterationError(nextErr)
+			}
+			if ok {
+				vm.push(element)
+			} else {
+				f.ip += offset
+			}
+		case chunk.OpLoop:
+			offset := int(f.function.Chunk.ReadShort(f.ip))
+			f.ip += 2 - offset
+		case chunk.OpJump:
+			offset := int(f.function.Chunk.ReadShort(f.ip))
+			f.ip += 2 + offset
+		case chunk.OpJumpIfFalse:
+			offset := int(f.function.Chunk.ReadShort(f.ip))
+			f.ip += 2
+			if !isTruthy(vm.peek(0)) {
+				f.ip += offset
+			}
+		case chunk.OpTry:
+			offset := int(f.function.Chunk.ReadShort(f.ip))
+			f.ip += 2
+			vm.handlers = append(vm.handlers, handler{frames: len(vm.frames), stack: len(vm.stack), ip: f.ip + offset})
+		case chunk.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case chunk.OpCatch:
+			exception := vm.peek(0).(*caught)
+			vm.stack[len(vm.stack)-1] = native.Caught(exception.err, exception.stack)
+		case chunk.OpThrow:
+			value := vm.pop()
+			if exception, ok := value.(*caught); ok {
+				panic(exception.err)
+			}
+			line := f.function.Chunk.Line(f.ip - 1)
+			panic(native.Throw(value, token.Token{TokenType: token.THROW, Lexeme: "throw", Line: line}))
+		case chunk.OpImport:
+			name := vm.readConstant(f).(string)
+			ns, loadErr := vm.loader.Load(vm.file, name, vm.runModule)
+			if loadErr != nil {
+				line := f.function.Chunk.Line(f.ip - 1)
+				panic(module.ImportError(loadErr, token.Token{TokenType: token.IMPORT, Lexeme: "import", Line: line}))
+			}
+			vm.push(ns)
+		case chunk.OpImportFrom:
+			name := vm.readConstant(f).(string)
+			member, memberErr := module.Member(vm.peek(0).(*native.Namespace), name)
+			if memberErr != nil {
+				vm.runtimeError(memberErr.Error())
+			}
+			vm.stack[len(vm.stack)-1] = member
+		case chunk.OpClosure:
+			fn := vm.readConstant(f).(*chunk.Function)
+			cl := &closure{function: fn, upvalues: make([]*upvalue, fn.UpvalueCount), vm: vm}
+			for idx := range cl.upvalues {
+				isLocal, index := code[f.ip], int(code[f.ip+1])
+				f.ip += 2
+				if isLocal == 1 {
+					cl.upvalues[idx] = vm.captureUpvalue(f.slots + index)
+				} else {
+					cl.upvalues[idx] = f.upvalues[index]
+				}
+			}
+			vm.push(cl)
+		case chunk.OpCall:
+			argCount := int(code[f.ip])
+			f.ip++
+			vm.callValue(argCount)
+			f = &vm.frames[len(vm.frames)-1]
+			code = f.function.Chunk.Code
+		case chunk.OpPrint:
+			fmt.Fprintf(vm.out, "%v\n", chunk.FormatValue(vm.pop()))
+		case chunk.OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(f.slots)
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:f.slots]
+			vm.push(result)
+			if len(vm.frames) == base {
+				return
+			}
+			f = &vm.frames[len(vm.frames)-1]
+			code = f.function.Chunk.Code
+		default:
+			vm.runtimeError(fmt.Sprintf("Unknown opcode %d.", op))
+		}
+	}
+}
+
+// runModule compiles an imported module and runs it on a vm of its own,
+// which shares this one's input, output, trace and loader.
+func (vm *VM) runModule(path string, stmts []ast.Stmt) (map[string]interface{}, error) {
+	script, compileErr := compiler.Compile(stmts)
+	if compileErr != nil {
+		return nil, fmt.Errorf("Could not compile module '%s'.", path)
+	}
+	child := New()
+	child.out, child.trace, child.context, child.loader, child.file = vm.out, vm.trace, vm.context, vm.loader, path
+	if runErr := child.Interpret(script); runErr != nil {
+		return nil, runErr
+	}
+	return child.globals, nil
+}
+
+// callValue calls the value below the top argCount stack slots, replacing
+// it and its arguments with the result. A closure made on this vm instead
+// gets a frame of its own, which the run loop continues in.
+func (vm *VM) callValue(argCount int) {
+	if cl, ok := vm.peek(argCount).(*closure); ok && cl.vm == vm {
+		if argCount != cl.function.Arity {
+			vm.runtimeError(fmt.Sprintf("Expected %d arguments but got %d.", cl.function.Arity, argCount))
+		}
+		if len(vm.frames) == framesMax {
+			vm.runtimeError(errStackOverflow.Error())
+		}
+		vm.frames = append(vm.frames, frame{function: cl.function, upvalues: cl.upvalues, slots: len(vm.stack) - argCount - 1})
+		return
+	}
+	function, ok := vm.peek(argCount).(native.Callable)
+	if !ok {
+		vm.runtimeError("Can only call functions.")
+	}
+	if arity := function.Arity(); arity >= 0 && argCount != arity {
+		vm.runtimeError(fmt.Sprintf("Expected %d arguments but got %d.", arity, argCount))
+	}
+	args := make([]chunk.Value, argCount)
+	copy(args, vm.stack[len(vm.stack)-argCount:])
+	result, callErr := function.Call(vm.context, args)
+	if callErr != nil {
+		if exit, ok := callErr.(*native.Exit); ok {
+			panic(exit)
+		}
+		vm.runtimeError(callErr.Error())
+	}
+	vm.stack = vm.stack[:len(vm.stack)-argCount-1]
+	vm.push(result)
+}
+
+// iterationError raises an error returned while starting or stepping
+// through a for-in loop, ending the program if it came from exit().
+func (vm *VM) iterationError(iterErr error) {
+	if exit, ok := iterErr.(*native.Exit); ok {
+		panic(exit)
+	}
+	vm.runtimeError(iterErr.Error())
+}
+
+// captureUpvalue returns the open upvalue for the stack slot, creating it
+// if no closure has captured the slot yet.
+func (vm *VM) captureUpvalue(slot int) *upvalue {
+	idx := len(vm.openUpvalues)
+	for idx > 0 && vm.openUpvalues[idx-1].slot >= slot {
+		if vm.openUpvalues[idx-1].slot == slot {
+			return vm.openUpvalues[idx-1]
+		}
+		idx--
+	}
+	up := &upvalue{slot: slot, open: true}
+	vm.openUpvalues = append(vm.openUpvalues, nil)
+	copy(vm.openUpvalues[idx+1:], vm.openUpvalues[idx:])
+	vm.openUpvalues[idx] = up
+	return up
+}
+
+// closeUpvalues closes the open upvalues for the stack slots from from
+// upwards, before those slots are popped.
+func (vm *VM) closeUpvalues(from int) {
+	idx := len(vm.openUpvalues)
+	for idx > 0 && vm.openUpvalues[idx-1].slot >= from {
+		up := vm.openUpvalues[idx-1]
+		up.closed, up.open = vm.stack[up.slot], false
+		idx--
+	}
+	vm.openUpvalues = vm.openUpvalues[:idx]
+}
+
+func (vm *VM) traceInstruction(f *frame) {
+	fmt.Fprint(vm.trace, "          ")
+	for _, value := range vm.stack {
+		fmt.Fprintf(vm.trace, "[ %s ]", chunk.FormatValue(value))
+	}
+	fmt.Fprintln(vm.trace)
+	chunk.DisassembleInstruction(vm.trace, f.function.Chunk, f.ip)
+}
+
+func (vm *VM) readConstant(f *frame) chunk.Value {
+	idx := f.function.Chunk.ReadShort(f.ip)
+	f.ip += 2
+	return f.function.Chunk.Constants[idx]
+}
+
+func (vm *VM) push(value chunk.Value) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() chunk.Value {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) peek(distance int) chunk.Value {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+// bitwiseOperators maps each binary bitwise instruction to the operator
+// native.Bitwise applies for it.
+var bitwiseOperators = map[chunk.OpCode]token.TokenType{
+	chunk.OpBitAnd:     token.AMPERSAND,
+	chunk.OpBitOr:      token.PIPE,
+	chunk.OpBitXor:     token.CARET,
+	chunk.OpShiftLeft:  token.LESS_LESS,
+	chunk.OpShiftRight: token.GREATER_GREATER,
+}
+
+// popNumbers pops the two operands of a binary numeric instruction.
+func (vm *VM) popNumbers() (float64, float64) {
+	b, bOk := vm.peek(0).(float64)
+	a, aOk := vm.peek(1).(float64)
+	if !aOk || !bOk {
+		vm.runtimeError("Operands must be numbers.")
+	}
+	vm.stack = vm.stack[:len(vm.stack)-2]
+	return a, b
+}
+
+// runtimeError aborts execution with an error at the current instruction's line.
+func (vm *VM) runtimeError(message string) {
+	f := &vm.frames[len(vm.frames)-1]
+	line := f.function.Chunk.Line(f.ip - 1)
+	panic(&err.RuntimeError{Token: token.Token{Line: line}, Message: message})
+}
+
+func isTruthy(v chunk.Value) bool {
+	return native.IsTruthy(v)
+}
+
+func isEqual(a, b chunk.Value) bool {
+	return a == b
+}